@@ -0,0 +1,339 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements inferring the structure of a pulse train which no
+	codec recognises. The pulse train is split into frames on long spaces,
+	and for each frame the header, the bit encoding and the number of bits
+	are inferred by clustering the pulse and space durations:
+
+	  Pulse-distance: pulses are the same, spaces are short or long
+	  Pulse-width:    spaces are the same, pulses are short or long
+	  Biphase:        pulses and spaces are one or two half-bits long
+
+	Frames after the first are compared with the first frame to determine
+	the repeat frame
+*/
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Encoding is the way in which bits are encoded in a frame
+type Encoding uint
+
+// Frame is the inferred structure of a frame of pulses and spaces
+type Frame struct {
+	Pulses      []uint32 // Pulses and spaces, starting and ending with a pulse
+	Gap         uint32   // Space after the frame, or zero for the last frame
+	HeaderPulse uint32   // Header pulse, or zero if there is no header
+	HeaderSpace uint32   // Header space, or zero if there is no header space
+	Encoding    Encoding // Bit encoding
+	Mark        uint32   // Value which is the same for every bit
+	Zero, One   uint32   // Values which encode zero and one, or one and two half-bits for biphase
+	Trail       uint32   // Trailing pulse, or zero
+	Bits        []bool   // Bits in the order received, or nil for biphase and unknown encodings
+	Length      uint     // Number of bits, which is approximate for biphase encoding
+}
+
+// Analysis is the inferred structure of a pulse train
+type Analysis struct {
+	Frames []*Frame
+	Repeat *Frame // The repeat frame, or nil if there are no repeats
+}
+
+type cluster struct {
+	value uint32
+	count uint
+}
+
+/////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	ENCODING_UNKNOWN        Encoding = iota
+	ENCODING_PULSE_DISTANCE          // Bits are encoded in the length of the space
+	ENCODING_PULSE_WIDTH             // Bits are encoded in the length of the pulse
+	ENCODING_BIPHASE                 // Bits are encoded in the order of pulse and space
+)
+
+const (
+	ANALYZE_FRAME_GAP = 10000 // A space of 10ms or more ends a frame
+	ANALYZE_TOLERANCE = 30    // Values within 30% are in the same cluster
+	ANALYZE_HEADER    = 1.5   // A header is at least 1.5 times longer than bit values
+)
+
+/////////////////////////////////////////////////////////////////////
+// ANALYZE
+
+// AnalyzePulses infers the structure of alternating pulses and spaces,
+// starting with a pulse
+func AnalyzePulses(pulses []uint32) *Analysis {
+	this := &Analysis{
+		Frames: make([]*Frame, 0, 1),
+	}
+
+	// Split into frames on long spaces
+	start := 0
+	for i := 1; i <= len(pulses); i += 2 {
+		if i == len(pulses) || pulses[i] >= ANALYZE_FRAME_GAP {
+			if i > start {
+				frame := analyzeFrame(pulses[start:i])
+				if i < len(pulses) {
+					frame.Gap = pulses[i]
+				}
+				this.Frames = append(this.Frames, frame)
+			}
+			start = i + 1
+		}
+	}
+
+	// The repeat frame is the second frame, which can be the same as
+	// the first frame
+	if len(this.Frames) > 1 {
+		this.Repeat = this.Frames[1]
+	}
+
+	return this
+}
+
+// TimingDeviation returns the mean and maximum deviation of received values
+// from nominal values, as a percentage. Only values up to the first frame
+// gap are compared
+func TimingDeviation(received, nominal []uint32) (float64, float64) {
+	total, max, count := 0.0, 0.0, 0
+	for i := 0; i < len(received) && i < len(nominal); i++ {
+		if received[i] >= ANALYZE_FRAME_GAP || nominal[i] >= ANALYZE_FRAME_GAP || nominal[i] == 0 {
+			break
+		}
+		deviation := math.Abs(float64(received[i])-float64(nominal[i])) * 100 / float64(nominal[i])
+		total += deviation
+		max = math.Max(max, deviation)
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	} else {
+		return total / float64(count), max
+	}
+}
+
+// Same returns true if a frame has the same structure and bits as
+// another frame
+func (this *Frame) Same(other *Frame) bool {
+	if other == nil || len(this.Pulses) != len(other.Pulses) {
+		return false
+	}
+	for i := range this.Pulses {
+		if similar(this.Pulses[i], other.Pulses[i]) == false {
+			return false
+		}
+	}
+	return true
+}
+
+// Value returns the bits as hexadecimal, with the first bit received as
+// the most significant bit when msb is true, or the least significant bit
+// otherwise
+func (this *Frame) Value(msb bool) string {
+	if len(this.Bits) == 0 {
+		return ""
+	}
+	digits := make([]string, 0, (len(this.Bits)+3)/4)
+	for end := len(this.Bits); end > 0; end -= 4 {
+		digit := 0
+		for i := end - 1; i >= 0 && i >= end-4; i-- {
+			bit := this.Bits[i]
+			if msb == false {
+				bit = this.Bits[len(this.Bits)-1-i]
+			}
+			if bit {
+				digit |= 1 << uint(end-1-i)
+			}
+		}
+		digits = append([]string{fmt.Sprintf("%X", digit)}, digits...)
+	}
+	return "0x" + strings.Join(digits, "")
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (e Encoding) String() string {
+	switch e {
+	case ENCODING_UNKNOWN:
+		return "ENCODING_UNKNOWN"
+	case ENCODING_PULSE_DISTANCE:
+		return "ENCODING_PULSE_DISTANCE"
+	case ENCODING_PULSE_WIDTH:
+		return "ENCODING_PULSE_WIDTH"
+	case ENCODING_BIPHASE:
+		return "ENCODING_BIPHASE"
+	default:
+		return "[?? Invalid Encoding value]"
+	}
+}
+
+func (this *Frame) String() string {
+	return fmt.Sprintf("remotes.Frame{ values=%v header=%v,%v encoding=%v bits=%v gap=%v }", len(this.Pulses), this.HeaderPulse, this.HeaderSpace, this.Encoding, this.Length, this.Gap)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func analyzeFrame(pulses []uint32) *Frame {
+	this := &Frame{
+		Pulses: pulses,
+	}
+
+	// A header pulse is longer than the other pulses, and a header space
+	// is one which is not the same as any other space
+	body := pulses
+	if len(body) > 2 {
+		if rest := clusters(body[2:], 0); pulses[0] > uint32(float64(maxCluster(rest))*ANALYZE_HEADER) {
+			this.HeaderPulse = pulses[0]
+			body = body[1:]
+			if rest := clusters(body[1:], 1); matchCluster(rest, body[0]) == false {
+				this.HeaderSpace = body[0]
+				body = body[1:]
+			}
+		}
+	}
+
+	// Cluster the pulses and the spaces
+	first := 0
+	if this.HeaderPulse != 0 && this.HeaderSpace == 0 {
+		first = 1
+	}
+	marks, spaces := clusters(body, first), clusters(body, 1-first)
+
+	// Infer the encoding
+	switch {
+	case len(marks) == 2 && len(spaces) == 2 && isDouble(marks) && isDouble(spaces) && inCluster(marks[0].value, spaces[0].value):
+		this.Encoding = ENCODING_BIPHASE
+		this.Zero = (marks[0].value + spaces[0].value) / 2
+		this.One = (marks[1].value + spaces[1].value) / 2
+		total := uint32(0)
+		for _, value := range body {
+			total += value
+		}
+		this.Length = uint(math.Round(float64(total) / float64(this.Zero) / 2))
+	case len(marks) == 1 && len(spaces) == 2:
+		this.Encoding = ENCODING_PULSE_DISTANCE
+		this.Mark, this.Zero, this.One = marks[0].value, spaces[0].value, spaces[1].value
+		this.Bits = bitsForValues(body, 1-first, this.Zero, this.One)
+		if len(body)%2 == 1 {
+			this.Trail = body[len(body)-1]
+		}
+	case len(marks) == 2 && len(spaces) == 1:
+		this.Encoding = ENCODING_PULSE_WIDTH
+		this.Mark, this.Zero, this.One = spaces[0].value, marks[0].value, marks[1].value
+		this.Bits = bitsForValues(body, first, this.Zero, this.One)
+	case len(marks) == 2 && len(spaces) == 2 && len(body)%2 == 1 && this.HeaderSpace != 0:
+		// Pulse-distance with a trailing pulse which is a different length
+		// to the bit pulses
+		if last := body[len(body)-1]; marks[0].count == 1 || marks[1].count == 1 {
+			this.Encoding = ENCODING_PULSE_DISTANCE
+			this.Mark, this.Zero, this.One, this.Trail = marks[0].value, spaces[0].value, spaces[1].value, last
+			if marks[0].count == 1 {
+				this.Mark = marks[1].value
+			}
+			this.Bits = bitsForValues(body, 1, this.Zero, this.One)
+		}
+	}
+
+	// Set the number of bits
+	if this.Bits != nil {
+		this.Length = uint(len(this.Bits))
+	}
+
+	return this
+}
+
+// clusters returns clusters for every second value from an offset, in
+// ascending order of value
+func clusters(values []uint32, offset int) []cluster {
+	sorted := make([]uint32, 0, len(values)/2+1)
+	for i := offset; i < len(values); i += 2 {
+		sorted = append(sorted, values[i])
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	result := make([]cluster, 0, 2)
+	start, total := 0, uint64(0)
+	for i, value := range sorted {
+		if i > start && inCluster(sorted[start], value) == false {
+			result = append(result, cluster{uint32(total / uint64(i-start)), uint(i - start)})
+			start, total = i, 0
+		}
+		total += uint64(value)
+	}
+	if len(sorted) > start {
+		result = append(result, cluster{uint32(total / uint64(len(sorted)-start)), uint(len(sorted) - start)})
+	}
+	return result
+}
+
+// inCluster returns true if a value is within tolerance of the smallest
+// value in a cluster
+func inCluster(min, value uint32) bool {
+	return float64(value) <= float64(min)*(100+ANALYZE_TOLERANCE)/100
+}
+
+// similar returns true if two values are within tolerance of each other
+func similar(a, b uint32) bool {
+	if a < b {
+		return inCluster(a, b)
+	} else {
+		return inCluster(b, a)
+	}
+}
+
+func matchCluster(clusters []cluster, value uint32) bool {
+	for _, c := range clusters {
+		if similar(c.value, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func maxCluster(clusters []cluster) uint32 {
+	max := uint32(0)
+	for _, c := range clusters {
+		if c.value > max {
+			max = c.value
+		}
+	}
+	return max
+}
+
+// isDouble returns true if the long cluster is about twice the short cluster
+func isDouble(clusters []cluster) bool {
+	ratio := float64(clusters[1].value) / float64(clusters[0].value)
+	return ratio > 1.6 && ratio < 2.4
+}
+
+// bitsForValues returns bits for every second value from an offset, where
+// values nearer to one are true
+func bitsForValues(values []uint32, offset int, zero, one uint32) []bool {
+	bits := make([]bool, 0, len(values)/2)
+	threshold := (zero + one) / 2
+	for i := offset; i < len(values); i += 2 {
+		bits = append(bits, values[i] > threshold)
+	}
+	return bits
+}
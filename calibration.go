@@ -0,0 +1,137 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements calibration of a remote, where the pulses and
+	spaces are consistently longer or shorter than the nominal values for
+	the codec. The calibration is measured while learning and stored in the
+	keymap:
+
+	  <remote>
+	    <codec>4</codec>
+	    <calibration pulse="1.08" space="0.95" samples="12"></calibration>
+	    ...
+	  </remote>
+
+	When the keymaps are loaded, the codec also recognises frames with the
+	calibrated values, but only when the frame is decoded as the device
+	for the keymap. Other remotes decoded by the codec are not affected
+*/
+
+import (
+	"fmt"
+	"math"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Calibration is the ratio of received to nominal pulses and spaces
+type Calibration struct {
+	Pulse   float64 `xml:"pulse,attr"`
+	Space   float64 `xml:"space,attr"`
+	Samples uint    `xml:"samples,attr"` // Number of frames measured
+}
+
+/////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CALIBRATION_MAX_DEVIATION = 50 // Values which deviate more than 50% are not measured
+)
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// MeasureCalibration returns the calibration for received values against
+// nominal values for the same scancode, or nil if the values cannot be
+// compared. Only values up to the first frame gap are measured, and the
+// frames must be the same length
+func MeasureCalibration(received, nominal []uint32) *Calibration {
+	length := frameLength(nominal)
+	if length == 0 || frameLength(received) != length {
+		return nil
+	}
+	var total, count [2]float64
+	for i := 0; i < length; i++ {
+		if nominal[i] == 0 {
+			return nil
+		}
+		ratio := float64(received[i]) / float64(nominal[i])
+		if math.Abs(ratio-1)*100 > CALIBRATION_MAX_DEVIATION {
+			return nil
+		}
+		// Pulses are at even indexes and spaces at odd indexes
+		total[i%2] += ratio
+		count[i%2]++
+	}
+	if count[0] == 0 || count[1] == 0 {
+		return nil
+	}
+	return &Calibration{
+		Pulse:   round(total[0] / count[0]),
+		Space:   round(total[1] / count[1]),
+		Samples: 1,
+	}
+}
+
+// Add returns a calibration which is the average of two calibrations,
+// weighted by the number of samples in each
+func (this *Calibration) Add(other *Calibration) *Calibration {
+	if this == nil || this.Samples == 0 {
+		return other
+	} else if other == nil || other.Samples == 0 {
+		return this
+	}
+	samples := float64(this.Samples + other.Samples)
+	return &Calibration{
+		Pulse:   round((this.Pulse*float64(this.Samples) + other.Pulse*float64(other.Samples)) / samples),
+		Space:   round((this.Space*float64(this.Samples) + other.Space*float64(other.Samples)) / samples),
+		Samples: this.Samples + other.Samples,
+	}
+}
+
+// SetCalibrations registers the calibrations in the keymaps for a codec
+// with the codec, so remotes with slower or faster timings are recognised
+func SetCalibrations(codec CalibratedCodec, keymaps KeyMaps) error {
+	for _, keymap := range keymaps.KeyMaps(codec.Type(), DEVICE_UNKNOWN, "") {
+		if keymap.Calibration == nil {
+			continue
+		} else if err := codec.Calibrate(keymap.Device, keymap.Calibration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *Calibration) String() string {
+	return fmt.Sprintf("remotes.Calibration{ pulse=%.3f space=%.3f samples=%v }", this.Pulse, this.Space, this.Samples)
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func round(value float64) float64 {
+	return math.Round(value*1000) / 1000
+}
+
+// frameLength returns the number of values before the first space which
+// is a frame gap
+func frameLength(values []uint32) int {
+	for i := 1; i < len(values); i += 2 {
+		if values[i] >= ANALYZE_FRAME_GAP {
+			return i
+		}
+	}
+	return len(values)
+}
@@ -0,0 +1,148 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements the carrier frequency and duty cycle which are
+	set on the LIRC device before sending. Each codec declares a carrier,
+	which can be overridden for a device in the keymap:
+
+	  <remote id="12">
+	    <codec>4</codec>
+	    <carrier frequency="56000" duty="50"></carrier>
+	    ...
+	  </remote>
+
+	Where the duty cycle is omitted the codec duty cycle is used. LIRC
+	devices accept duty cycles between 1% and 99%, and devices which can't
+	set the carrier frequency or duty cycle send with their own
+*/
+
+import (
+	"fmt"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Carrier is the carrier frequency in Hz and duty cycle as a percentage
+type Carrier struct {
+	Frequency uint32 `xml:"frequency,attr"`
+	DutyCycle uint32 `xml:"duty,attr,omitempty"`
+}
+
+// Carriers is the carrier for a codec, with overrides for devices
+type Carriers struct {
+	sync.RWMutex
+	carrier Carrier
+	devices map[uint32]Carrier
+}
+
+/////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	DEFAULT_DUTY_CYCLE = 33 // Duty cycle when not known
+	MAX_DUTY_CYCLE     = 99 // Maximum duty cycle accepted by LIRC devices
+)
+
+/////////////////////////////////////////////////////////////////////
+// CARRIERS
+
+// NewCarriers returns the carrier for a codec, with the default frequency
+// and duty cycle used for any value which is zero
+func NewCarriers(frequency, duty_cycle uint32) *Carriers {
+	if frequency == 0 {
+		frequency = DEFAULT_FREQUENCY
+	}
+	if duty_cycle == 0 {
+		duty_cycle = DEFAULT_DUTY_CYCLE
+	}
+	return &Carriers{
+		carrier: Carrier{frequency, duty_cycle},
+		devices: make(map[uint32]Carrier),
+	}
+}
+
+// Carrier returns the carrier for sending to a device
+func (this *Carriers) Carrier(device uint32) Carrier {
+	this.RLock()
+	defer this.RUnlock()
+	if carrier, exists := this.devices[device]; exists {
+		return carrier
+	} else {
+		return this.carrier
+	}
+}
+
+// SetCarrier overrides the carrier for a device, or removes the override
+// when the carrier is nil
+func (this *Carriers) SetCarrier(device uint32, carrier *Carrier) error {
+	this.Lock()
+	defer this.Unlock()
+	if carrier == nil {
+		delete(this.devices, device)
+		return nil
+	} else if carrier.Frequency == 0 || carrier.DutyCycle > MAX_DUTY_CYCLE {
+		return gopi.ErrBadParameter
+	}
+	override := *carrier
+	if override.DutyCycle == 0 {
+		override.DutyCycle = this.carrier.DutyCycle
+	}
+	this.devices[device] = override
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// SendPulses sets the carrier frequency and duty cycle and then sends
+// pulses and spaces. Where the device can't set the carrier frequency or
+// duty cycle, the pulses and spaces are sent anyway
+func SendPulses(lirc gopi.LIRC, carrier Carrier, pulses []uint32) error {
+	if lirc == nil || len(pulses) == 0 {
+		return gopi.ErrBadParameter
+	} else if err := lirc.SetSendCarrierHz(carrier.Frequency); err != nil && err != gopi.ErrNotImplemented {
+		return err
+	} else if err := lirc.SetSendDutyCycle(carrier.DutyCycle); err != nil && err != gopi.ErrNotImplemented {
+		return err
+	} else {
+		return lirc.PulseSend(pulses)
+	}
+}
+
+// SetCarriers registers the carrier overrides in the keymaps with a
+// codec, for each device which the codec sends to
+func SetCarriers(codec Codec, keymaps KeyMaps) error {
+	for entry, keymap := range keymaps.LookupKeyMapEntry(codec.Type(), DEVICE_UNKNOWN, SCANCODE_UNKNOWN) {
+		if keymap.Carrier == nil {
+			continue
+		}
+		device := keymap.Device
+		if entry.Device != 0 {
+			device = entry.Device
+		}
+		if err := codec.SetCarrier(device, keymap.Carrier); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this Carrier) String() string {
+	return fmt.Sprintf("remotes.Carrier{ frequency=%vHz duty_cycle=%v%% }", this.Frequency, this.DutyCycle)
+}
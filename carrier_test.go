@@ -0,0 +1,85 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/sys/loopback"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// fixedCarrier is a LIRC device which can't set the carrier or duty cycle
+type fixedCarrier struct {
+	loopback.LIRC
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestSendPulsesFixedCarrier(t *testing.T) {
+	lirc := openLoopback(t)
+	defer lirc.Close()
+
+	// Pulses are sent when the device can't set the carrier
+	if err := remotes.SendPulses(fixedCarrier{lirc}, remotes.Carrier{Frequency: 38000, DutyCycle: 33}, []uint32{100, 200, 300}); err != nil {
+		t.Error(err)
+	}
+	if err := remotes.SendPronto(fixedCarrier{lirc}, &remotes.ProntoCode{Frequency: 38000, Intro: []uint32{100, 200}}, 0); err != nil {
+		t.Error(err)
+	}
+
+	// Other errors are returned
+	if err := remotes.SendPulses(lirc, remotes.Carrier{Frequency: 38000, DutyCycle: 100}, []uint32{100, 200, 300}); err != gopi.ErrBadParameter {
+		t.Errorf("Expected ErrBadParameter, got %v", err)
+	}
+}
+
+func TestSetCarrier(t *testing.T) {
+	carriers := remotes.NewCarriers(38000, 33)
+	if err := carriers.SetCarrier(0x01, &remotes.Carrier{Frequency: 56000}); err != nil {
+		t.Fatal(err)
+	} else if carrier := carriers.Carrier(0x01); carrier.Frequency != 56000 || carrier.DutyCycle != 33 {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+	if err := carriers.SetCarrier(0x02, &remotes.Carrier{Frequency: 56000, DutyCycle: 100}); err != gopi.ErrBadParameter {
+		t.Errorf("Expected ErrBadParameter, got %v", err)
+	} else if carrier := carriers.Carrier(0x02); carrier.Frequency != 38000 {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (fixedCarrier) SetSendCarrierHz(value uint32) error {
+	return gopi.ErrNotImplemented
+}
+
+func (fixedCarrier) SetSendDutyCycle(value uint32) error {
+	return gopi.ErrNotImplemented
+}
+
+func openLoopback(t *testing.T) loopback.LIRC {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(loopback.Loopback{}, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(loopback.LIRC)
+	}
+	return nil
+}
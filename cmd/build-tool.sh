@@ -27,6 +27,9 @@ COMMANDS=(
   tool/ir_rcv.go
   tool/ir_learn.go
   tool/ir_send.go
+  tool/ir_analyze.go
+  tool/ir_replay.go
+  tool/ir_uinput.go
 )
 
 for COMMAND in ${COMMANDS[@]}; do
@@ -28,9 +28,15 @@ import (
 	_ "github.com/djthorpe/remotes/rpc/grpc/remotes"
 
 	// Remote Codecs
+	_ "github.com/djthorpe/remotes/codec/irp"
+	_ "github.com/djthorpe/remotes/codec/jvc"
+	_ "github.com/djthorpe/remotes/codec/kaseikyo"
 	_ "github.com/djthorpe/remotes/codec/nec"
-	_ "github.com/djthorpe/remotes/codec/panasonic"
+	_ "github.com/djthorpe/remotes/codec/raw"
 	_ "github.com/djthorpe/remotes/codec/rc5"
+	_ "github.com/djthorpe/remotes/codec/rc6"
+	_ "github.com/djthorpe/remotes/codec/sanyo"
+	_ "github.com/djthorpe/remotes/codec/sharp"
 	_ "github.com/djthorpe/remotes/codec/sony"
 )
 
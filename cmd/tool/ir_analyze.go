@@ -0,0 +1,370 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// ir_analyze is used to identify the protocol of a pulse train
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/hw/linux"
+	_ "github.com/djthorpe/gopi/sys/logger"
+
+	// Remotes
+	_ "github.com/djthorpe/remotes/codec/irp"
+	_ "github.com/djthorpe/remotes/codec/jvc"
+	_ "github.com/djthorpe/remotes/codec/kaseikyo"
+	_ "github.com/djthorpe/remotes/codec/nec"
+	_ "github.com/djthorpe/remotes/codec/raw"
+	_ "github.com/djthorpe/remotes/codec/rc5"
+	_ "github.com/djthorpe/remotes/codec/rc6"
+	_ "github.com/djthorpe/remotes/codec/sanyo"
+	_ "github.com/djthorpe/remotes/codec/sharp"
+	_ "github.com/djthorpe/remotes/codec/sony"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+var (
+	captured chan []uint32
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func DisplayMatchHeader() {
+	fmt.Printf("%-17s %-10s %-10s %-22s %-9s %-9s\n", "CODEC", "DEVICE", "SCANCODE", "EVENT", "MEAN DEV", "MAX DEV")
+	fmt.Printf("%-17s %-10s %-10s %-22s %-9s %-9s\n", strings.Repeat("-", 17), strings.Repeat("-", 10), strings.Repeat("-", 10), strings.Repeat("-", 22), strings.Repeat("-", 9), strings.Repeat("-", 9))
+}
+
+func DisplayMatches(pulses []uint32, app *gopi.AppInstance) uint {
+	matches := uint(0)
+	for _, codec := range remotes.ModuleCodecs(app) {
+		if codec.Type() == remotes.CODEC_RAW {
+			// The raw codec matches any pulse train so is ignored
+			continue
+		}
+		events, err := codec.Decode(remotes.PulseEvents(pulses, 0))
+		if err != nil {
+			app.Logger.Warn("%v: %v", codec.Type(), err)
+			continue
+		}
+		for _, evt := range events {
+			if matches == 0 {
+				DisplayMatchHeader()
+			}
+			matches++
+			// Compare the timings with those of the first frame when encoded
+			mean, max := "-", "-"
+			if nominal, err := codec.Encode(evt.Device(), evt.ScanCode(), 0); err == nil && sameLength(pulses, nominal) {
+				mean_deviation, max_deviation := remotes.TimingDeviation(pulses, nominal)
+				mean, max = fmt.Sprintf("%.1f%%", mean_deviation), fmt.Sprintf("%.1f%%", max_deviation)
+			}
+			fmt.Printf("%-17v 0x%08X 0x%08X %-22v %-9s %-9s\n", codec.Type(), evt.Device(), evt.ScanCode(), evt.EventType(), mean, max)
+		}
+	}
+	return matches
+}
+
+func DisplayFrame(title string, frame *remotes.Frame) {
+	fmt.Printf("%v: %v values", title, len(frame.Pulses))
+	if frame.Gap != 0 {
+		fmt.Printf(", followed by a %vµs space", frame.Gap)
+	}
+	fmt.Println("")
+
+	if frame.HeaderPulse != 0 {
+		if frame.HeaderSpace != 0 {
+			fmt.Printf("  Header:   pulse %vµs space %vµs\n", frame.HeaderPulse, frame.HeaderSpace)
+		} else {
+			fmt.Printf("  Header:   pulse %vµs\n", frame.HeaderPulse)
+		}
+	} else {
+		fmt.Printf("  Header:   none\n")
+	}
+
+	switch frame.Encoding {
+	case remotes.ENCODING_PULSE_DISTANCE:
+		fmt.Printf("  Encoding: pulse-distance, pulse %vµs then space %vµs (0) or %vµs (1)\n", frame.Mark, frame.Zero, frame.One)
+	case remotes.ENCODING_PULSE_WIDTH:
+		fmt.Printf("  Encoding: pulse-width, space %vµs then pulse %vµs (0) or %vµs (1)\n", frame.Mark, frame.Zero, frame.One)
+	case remotes.ENCODING_BIPHASE:
+		fmt.Printf("  Encoding: biphase, half-bit %vµs\n", frame.Zero)
+	default:
+		fmt.Printf("  Encoding: unknown, values %v\n", frame.Pulses)
+	}
+
+	if frame.Bits != nil {
+		fmt.Printf("  Bits:     %v bits, %v (first bit is MSB) or %v (first bit is LSB)\n", frame.Length, frame.Value(true), frame.Value(false))
+	} else if frame.Encoding == remotes.ENCODING_BIPHASE {
+		fmt.Printf("  Bits:     about %v bits\n", frame.Length)
+	}
+	if frame.Trail != 0 {
+		fmt.Printf("  Trailer:  pulse %vµs\n", frame.Trail)
+	}
+}
+
+func DisplayAnalysis(pulses []uint32) {
+	analysis := remotes.AnalyzePulses(pulses)
+	if len(analysis.Frames) == 0 {
+		fmt.Println("No frames")
+		return
+	}
+
+	// Display the first frame
+	DisplayFrame(fmt.Sprintf("Frame 1 of %v", len(analysis.Frames)), analysis.Frames[0])
+
+	// Display the repeat frame
+	switch {
+	case analysis.Repeat == nil:
+		fmt.Println("Repeat:     none (hold the button down to capture repeat frames)")
+	case analysis.Repeat.Same(analysis.Frames[0]):
+		fmt.Println("Repeat:     the first frame is repeated")
+	case len(analysis.Repeat.Pulses) == len(analysis.Frames[0].Pulses) && analysis.Repeat.Encoding == analysis.Frames[0].Encoding:
+		DisplayFrame("Repeat: the first frame is repeated with different bits", analysis.Repeat)
+	default:
+		DisplayFrame("Repeat: a different frame is repeated", analysis.Repeat)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ReadPulses reads values in microseconds from text, in mode2 format
+// (lines of "pulse N" and "space N"), as alternating values starting with a
+// pulse optionally prefixed with + and -, or as a Pronto code
+func ReadPulses(r io.Reader) ([]uint32, error) {
+	fields := make([]string, 0, 100)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields = append(fields, strings.Fields(line)...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Pronto codes start with 0000
+	if len(fields) > 0 && fields[0] == "0000" {
+		if code, err := remotes.ParsePronto(strings.Join(fields, " ")); err != nil {
+			return nil, err
+		} else {
+			return code.Pulses(1), nil
+		}
+	}
+
+	// Read values
+	pulses := make([]uint32, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		is_pulse, is_timeout := len(pulses)%2 == 0, false
+		switch fields[i] {
+		case "pulse", "space", "timeout":
+			if i+1 >= len(fields) {
+				return nil, fmt.Errorf("Missing value after '%v'", fields[i])
+			}
+			is_pulse, is_timeout = fields[i] == "pulse", fields[i] == "timeout"
+			i++
+		}
+		if value, err := strconv.ParseUint(strings.TrimLeft(fields[i], "+-"), 10, 32); err != nil {
+			return nil, fmt.Errorf("Invalid value: %v", fields[i])
+		} else if is_timeout && value < remotes.ANALYZE_FRAME_GAP {
+			// A timeout always ends a frame
+			pulses = appendPulse(pulses, false, remotes.ANALYZE_FRAME_GAP)
+		} else {
+			pulses = appendPulse(pulses, is_pulse, uint32(value))
+		}
+	}
+
+	// Return the pulses
+	return trimPulses(pulses), nil
+}
+
+// Capture receives pulses and spaces until no values are received
+// for the gap duration, or a timeout is received, and then sends
+// a signal to end
+func Capture(app *gopi.AppInstance, done <-chan struct{}) error {
+	// Pulses are read from a file or arguments
+	if len(app.AppFlags.Args()) > 0 {
+		<-done
+		return nil
+	} else if _, exists := app.AppFlags.GetString("file"); exists {
+		<-done
+		return nil
+	} else if app.LIRC == nil {
+		<-done
+		return gopi.ErrAppError
+	}
+
+	// Capture pulses
+	gap, _ := app.AppFlags.GetDuration("gap")
+	events := app.LIRC.Subscribe()
+	pulses := make([]uint32, 0, 100)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+
+FOR_LOOP:
+	for {
+		select {
+		case <-done:
+			break FOR_LOOP
+		case evt := <-events:
+			if lirc_evt, ok := evt.(gopi.LIRCEvent); ok {
+				switch lirc_evt.Type() {
+				case gopi.LIRC_TYPE_PULSE, gopi.LIRC_TYPE_SPACE:
+					if pulses = appendPulse(pulses, lirc_evt.Type() == gopi.LIRC_TYPE_PULSE, lirc_evt.Value()); len(pulses) > 0 {
+						timer.Reset(gap)
+					}
+				case gopi.LIRC_TYPE_TIMEOUT:
+					if len(pulses) > 0 {
+						timer.Reset(0)
+					}
+				}
+			}
+		case <-timer.C:
+			captured <- trimPulses(pulses)
+			app.SendSignal()
+			<-done
+			break FOR_LOOP
+		}
+	}
+
+	// Unsubscribe
+	timer.Stop()
+	app.LIRC.Unsubscribe(events)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Main(app *gopi.AppInstance, done chan<- struct{}) error {
+	var pulses []uint32
+
+	if args := app.AppFlags.Args(); len(args) > 0 {
+		// Read pulses from the command line
+		if values, err := ReadPulses(strings.NewReader(strings.Join(args, " "))); err != nil {
+			done <- gopi.DONE
+			return err
+		} else {
+			pulses = values
+		}
+	} else if filename, exists := app.AppFlags.GetString("file"); exists {
+		// Read pulses from a file
+		if fh, err := os.Open(filename); err != nil {
+			done <- gopi.DONE
+			return err
+		} else {
+			defer fh.Close()
+			if values, err := ReadPulses(fh); err != nil {
+				done <- gopi.DONE
+				return fmt.Errorf("%v: %v", filename, err)
+			} else {
+				pulses = values
+			}
+		}
+	} else {
+		// Capture pulses
+		app.Logger.Info("Press a button on the remote, or CTRL+C to end")
+		app.WaitForSignal()
+		select {
+		case values := <-captured:
+			pulses = values
+		default:
+			break
+		}
+	}
+
+	// Check for pulses
+	if len(pulses) == 0 {
+		done <- gopi.DONE
+		return fmt.Errorf("No pulses to analyze")
+	}
+
+	// Display matching codecs, or else infer the structure
+	if matches := DisplayMatches(pulses, app); matches == 0 {
+		fmt.Printf("No codec matches %v values\n\n", len(pulses))
+		DisplayAnalysis(pulses)
+	}
+
+	// Finish gracefully
+	done <- gopi.DONE
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// appendPulse appends a pulse or space, adding to the last value when it is
+// the same type. Spaces before the first pulse are ignored
+func appendPulse(pulses []uint32, is_pulse bool, value uint32) []uint32 {
+	last_is_pulse := len(pulses)%2 == 1
+	switch {
+	case len(pulses) == 0 && is_pulse == false:
+		return pulses
+	case is_pulse == last_is_pulse:
+		pulses[len(pulses)-1] += value
+		return pulses
+	default:
+		return append(pulses, value)
+	}
+}
+
+// trimPulses removes a trailing space
+func trimPulses(pulses []uint32) []uint32 {
+	if len(pulses)%2 == 0 && len(pulses) > 0 {
+		return pulses[:len(pulses)-1]
+	} else {
+		return pulses
+	}
+}
+
+// sameLength returns true if the first frame of received pulses has the
+// same number of values as nominal pulses
+func sameLength(received, nominal []uint32) bool {
+	for i := range received {
+		if received[i] >= remotes.ANALYZE_FRAME_GAP {
+			return i == len(nominal)
+		}
+	}
+	return len(received) == len(nominal)
+}
+
+func codecs() []string {
+	codecs := make([]string, 0)
+	// Obtain all the codecs
+	for _, module := range gopi.ModulesByType(gopi.MODULE_TYPE_OTHER) {
+		if strings.HasPrefix(module.Name, "remotes/") {
+			codecs = append(codecs, module.Name)
+		}
+	}
+	return codecs
+}
+
+func main() {
+	// Configuration
+	config := gopi.NewAppConfig(codecs()...)
+	config.AppFlags.FlagString("file", "", "Read pulses from a file rather than capturing them")
+	config.AppFlags.FlagDuration("gap", 250*time.Millisecond, "End capture when no pulses are received for this duration")
+
+	// Make the capture channel
+	captured = make(chan []uint32, 1)
+
+	// Run the command line tool
+	os.Exit(gopi.CommandLineTool(config, Main, Capture))
+}
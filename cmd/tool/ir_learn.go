@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	// Frameworks
@@ -24,9 +25,15 @@ import (
 	_ "github.com/djthorpe/remotes/keymap"
 
 	// Remotes
+	_ "github.com/djthorpe/remotes/codec/irp"
+	_ "github.com/djthorpe/remotes/codec/jvc"
+	_ "github.com/djthorpe/remotes/codec/kaseikyo"
 	_ "github.com/djthorpe/remotes/codec/nec"
-	_ "github.com/djthorpe/remotes/codec/panasonic"
+	_ "github.com/djthorpe/remotes/codec/raw"
 	_ "github.com/djthorpe/remotes/codec/rc5"
+	_ "github.com/djthorpe/remotes/codec/rc6"
+	_ "github.com/djthorpe/remotes/codec/sanyo"
+	_ "github.com/djthorpe/remotes/codec/sharp"
 	_ "github.com/djthorpe/remotes/codec/sony"
 )
 
@@ -34,10 +41,20 @@ import (
 // TYPES
 
 type App struct {
-	app    *gopi.AppInstance
-	keymap *remotes.KeyMap      // Currently learned keymap
-	key    *remotes.KeyMapEntry // Currently learned key
-	db     remotes.KeyMaps
+	app      *gopi.AppInstance
+	keymap   *remotes.KeyMap      // Currently learned keymap
+	key      *remotes.KeyMapEntry // Currently learned key
+	db       remotes.KeyMaps
+	raw      remotes.RawCodec    // Codec for remotes no other codec understands
+	pending  remotes.RemoteEvent // Raw event for the currently learned key
+	recorded bool                // Currently learned key was recorded by another codec
+
+	// Calibration
+	calibrate bool
+	measured  remotes.RemoteEvent // Event to measure the timings for the currently learned key
+	frame     []uint32            // Pulses and spaces for the frame being received
+	frames    [][]uint32          // Frames received for the currently learned key
+	sync.Mutex
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -57,6 +74,11 @@ func NewApp(app *gopi.AppInstance) *App {
 	this.key = nil
 	this.keymap = nil
 	this.db = app.ModuleInstance("keymap").(remotes.KeyMaps)
+	if raw, ok := app.ModuleInstance("remotes/raw").(remotes.RawCodec); ok {
+		this.raw = raw
+		this.raw.SetLearning(true)
+	}
+	this.calibrate, _ = app.AppFlags.GetBool("calibrate")
 
 	// Load in the existing keymaps from root
 	if err := this.db.LoadKeyMaps(func(filename string, keymap *remotes.KeyMap) {
@@ -76,21 +98,136 @@ func NewApp(app *gopi.AppInstance) *App {
 func (this *App) SetKey(keymap *remotes.KeyMap, key *remotes.KeyMapEntry) {
 	this.keymap = keymap
 	this.key = key
+	this.pending = nil
+	this.recorded = false
+
+	this.Lock()
+	defer this.Unlock()
+	this.measured = nil
+	this.frames = nil
 }
 
 func (this *App) HandleEvent(evt remotes.RemoteEvent) error {
 	if this.keymap == nil && this.key == nil && evt == nil {
 		return nil
+	} else if evt.Codec() == remotes.CODEC_RAW {
+		// Raw events are only recorded when no other codec recognises the key
+		this.pending = evt
 	} else if err := this.db.SetKeyMapEntry(this.keymap, evt.Codec(), evt.Device(), this.key.Keycode, evt.ScanCode()); err != nil {
 		fmt.Printf("\n  %v\n", err)
 	} else {
+		this.recorded = true
 		fmt.Printf("\n  Recorded key %v for device 0x%08X and scancode 0x%08X\n", this.key.Keycode, evt.Device(), evt.ScanCode())
+
+		// Measure the timings of the first event for the key
+		this.Lock()
+		if this.calibrate && this.measured == nil {
+			this.measured = evt
+		}
+		this.Unlock()
+	}
+
+	// Success
+	return nil
+}
+
+// Record the raw event and learned pulses for the current key, if no
+// other codec recognised the key
+func (this *App) HandleRawEvent() error {
+	if this.keymap == nil || this.key == nil || this.raw == nil || this.pending == nil || this.recorded {
+		return nil
+	}
+	evt := this.pending
+	if pulses := this.raw.Pulses(evt.Device(), evt.ScanCode()); len(pulses) == 0 {
+		return remotes.ErrNotFound
+	} else if err := this.db.SetKeyMapEntry(this.keymap, evt.Codec(), evt.Device(), this.key.Keycode, evt.ScanCode()); err != nil {
+		return err
+	} else if err := this.db.SetKeyMapPulses(this.keymap, this.key.Keycode, pulses); err != nil {
+		return err
+	} else {
+		fmt.Printf("\n  Recorded key %v for device 0x%08X with %v learned pulses\n", this.key.Keycode, evt.Device(), len(pulses))
+	}
+
+	// Success
+	return nil
+}
+
+// Record pulses and spaces into frames, when calibrating. A frame ends
+// with a long space or a timeout
+func (this *App) HandleLIRCEvent(evt gopi.LIRCEvent) {
+	this.Lock()
+	defer this.Unlock()
+
+	switch evt.Type() {
+	case gopi.LIRC_TYPE_PULSE:
+		this.frame = append(this.frame, evt.Value())
+	case gopi.LIRC_TYPE_SPACE:
+		if len(this.frame) == 0 {
+			break
+		} else if evt.Value() < remotes.ANALYZE_FRAME_GAP {
+			this.frame = append(this.frame, evt.Value())
+			break
+		}
+		fallthrough
+	case gopi.LIRC_TYPE_TIMEOUT:
+		if len(this.frame) > 0 && this.key != nil {
+			this.frames = append(this.frames, this.frame)
+		}
+		this.frame = nil
+	}
+}
+
+// Measure the timings for the current key against the nominal timings
+// for the codec which recognised it, and update the keymap calibration
+func (this *App) HandleCalibration() error {
+	this.Lock()
+	evt, frames := this.measured, this.frames
+	this.Unlock()
+
+	if this.keymap == nil || this.key == nil || evt == nil || evt.Codec() != this.keymap.Type {
+		return nil
+	}
+	codec, ok := this.Codec(evt.Codec()).(remotes.CalibratedCodec)
+	if ok == false || codec == nil {
+		return nil
+	}
+	nominal, err := codec.Encode(evt.Device(), evt.ScanCode(), 0)
+	if err != nil {
+		return err
+	}
+
+	// Measure each frame which is comparable with the nominal frame
+	var calibration *remotes.Calibration
+	for _, frame := range frames {
+		calibration = calibration.Add(remotes.MeasureCalibration(frame, nominal))
+	}
+	// The keymap is a copy, so add to the calibration in the database
+	current := this.keymap.Calibration
+	if keymaps := this.db.KeyMaps(this.keymap.Type, this.keymap.Device, ""); len(keymaps) == 1 {
+		current = keymaps[0].Calibration
+	}
+	if calibration == nil {
+		return nil
+	} else if err := this.db.SetCalibration(this.keymap, current.Add(calibration)); err != nil {
+		return err
+	} else {
+		fmt.Printf("  Calibrated pulses x%.3f and spaces x%.3f from %v frames\n", calibration.Pulse, calibration.Space, calibration.Samples)
 	}
 
 	// Success
 	return nil
 }
 
+// Return the codec for a codec type
+func (this *App) Codec(codec_type remotes.CodecType) remotes.Codec {
+	for _, codec := range remotes.ModuleCodecs(this.app) {
+		if codec.Type() == codec_type {
+			return codec
+		}
+	}
+	return nil
+}
+
 // Return a keymap
 func (this *App) KeyMapWithName(name string) *remotes.KeyMap {
 	// Find keymaps with the name specified
@@ -184,6 +321,16 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 				return nil
 			}
 
+			// Record the key from the raw codec if not otherwise recognised
+			if err := theApp.HandleRawEvent(); err != nil {
+				fmt.Printf("\n  %v\n", err)
+			}
+
+			// Measure the timings for the key
+			if err := theApp.HandleCalibration(); err != nil {
+				fmt.Printf("\n  %v\n", err)
+			}
+
 			// Reset the key we're currently learning
 			theApp.SetKey(keymap, nil)
 			fmt.Println("")
@@ -212,10 +359,14 @@ func EventLoop(app *gopi.AppInstance, done <-chan struct{}) error {
 	remote_events := events.Subscribe()
 
 	// Subscribe to codecs
-	for _, name := range codecs() {
-		if instance, ok := app.ModuleInstance(name).(remotes.Codec); ok && instance != nil {
-			events.Add(instance.Subscribe())
-		}
+	for _, codec := range remotes.ModuleCodecs(app) {
+		events.Add(codec.Subscribe())
+	}
+
+	// Subscribe to pulses and spaces when calibrating
+	var lirc_events <-chan gopi.Event
+	if theApp.calibrate {
+		lirc_events = app.LIRC.Subscribe()
 	}
 
 	// Wait for either terminate signal or incoming remote event
@@ -228,10 +379,17 @@ FOR_LOOP:
 			if err := theApp.HandleEvent(remote_event.(remotes.RemoteEvent)); err != nil {
 				app.Logger.Warn("EventLoop: %v", err)
 			}
+		case lirc_event := <-lirc_events:
+			if lirc_event != nil {
+				theApp.HandleLIRCEvent(lirc_event.(gopi.LIRCEvent))
+			}
 		}
 	}
 
 	// Close merged events
+	if lirc_events != nil {
+		app.LIRC.Unsubscribe(lirc_events)
+	}
 	events.Unsubscribe(remote_events)
 	events.Close()
 	return nil
@@ -257,6 +415,7 @@ func main() {
 	config.AppFlags.FlagString("device", "", "Name of device to learn")
 	config.AppFlags.FlagUint("repeats", 0, "Key send repeat value")
 	config.AppFlags.FlagBool("multicodec", false, "Remote sends various encodings")
+	config.AppFlags.FlagBool("calibrate", false, "Measure the timings of the remote")
 
 	// Set the start signal
 	startSignal = make(chan struct{})
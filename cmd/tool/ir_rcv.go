@@ -17,48 +17,55 @@ import (
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
-	"github.com/djthorpe/gopi/util/event"
 	"github.com/djthorpe/remotes"
 
 	// Modules
 	_ "github.com/djthorpe/gopi/sys/hw/linux"
 	_ "github.com/djthorpe/gopi/sys/logger"
 	_ "github.com/djthorpe/remotes/keymap"
+	_ "github.com/djthorpe/remotes/lifecycle"
 
 	// Remotes
+	_ "github.com/djthorpe/remotes/codec/irp"
+	_ "github.com/djthorpe/remotes/codec/jvc"
+	_ "github.com/djthorpe/remotes/codec/kaseikyo"
 	_ "github.com/djthorpe/remotes/codec/nec"
-	_ "github.com/djthorpe/remotes/codec/panasonic"
+	_ "github.com/djthorpe/remotes/codec/raw"
 	_ "github.com/djthorpe/remotes/codec/rc5"
+	_ "github.com/djthorpe/remotes/codec/rc6"
+	_ "github.com/djthorpe/remotes/codec/sanyo"
+	_ "github.com/djthorpe/remotes/codec/sharp"
 	_ "github.com/djthorpe/remotes/codec/sony"
 )
 
 var (
-	start chan struct{}
-	once  sync.Once
+	start    chan struct{}
+	once     sync.Once
+	recorder *remotes.Recorder
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 
 func PrintHeader() {
-	fmt.Printf("%-31s %-25v %-10s %-10s %-15s %-22s %s\n", "Name", "Key", "Scancode", "Device", "Codec", "Event", "Timestamp")
-	fmt.Printf("%-31s %-25v %-10s %-10s %-15s %-22s %s\n",
+	fmt.Printf("%-31s %-25v %-10s %-10s %-15s %-22s %-10s %-7s %s\n", "Name", "Key", "Scancode", "Device", "Codec", "Event", "Held", "Repeats", "Timestamp")
+	fmt.Printf("%-31s %-25v %-10s %-10s %-15s %-22s %-10s %-7s %s\n",
 		strings.Repeat("-", 31), strings.Repeat("-", 25), strings.Repeat("-", 10), strings.Repeat("-", 10),
-		strings.Repeat("-", 15), strings.Repeat("-", 22), strings.Repeat("-", 10))
+		strings.Repeat("-", 15), strings.Repeat("-", 22), strings.Repeat("-", 10), strings.Repeat("-", 7), strings.Repeat("-", 10))
 }
 
-func PrintEntry(keymap *remotes.KeyMap, entry *remotes.KeyMapEntry, evt_type gopi.InputEventType, ts time.Duration) {
+func PrintEntry(keymap *remotes.KeyMap, entry *remotes.KeyMapEntry, evt remotes.LifecycleEvent) {
 	once.Do(PrintHeader)
-	ts = ts.Truncate(time.Millisecond)
-	fmt.Printf("%+15s: %-15s %-25v 0x%08X 0x%08X %-15s %-22s %v\n", keymap.Name, entry.Name, entry.Keycode, entry.Scancode, entry.Device, entry.Type, evt_type, ts)
+	ts, held := evt.Timestamp().Truncate(time.Millisecond), evt.Held().Truncate(time.Millisecond)
+	fmt.Printf("%+15s: %-15s %-25v 0x%08X 0x%08X %-15s %-22s %-10v %-7v %v\n", keymap.Name, entry.Name, entry.Keycode, entry.Scancode, entry.Device, entry.Type, evt.EventType(), held, evt.Repeats(), ts)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func HandleEvent(keymaps remotes.KeyMaps, evt remotes.RemoteEvent) error {
+func HandleEvent(keymaps remotes.KeyMaps, evt remotes.LifecycleEvent) error {
 	// Lookup entry
 	if entries := keymaps.LookupKeyMapEntry(evt.Codec(), evt.Device(), evt.ScanCode()); entries != nil {
 		for entry, keymap := range entries {
-			PrintEntry(keymap, entry, evt.EventType(), evt.Timestamp())
+			PrintEntry(keymap, entry, evt)
 		}
 	}
 	return nil
@@ -73,19 +80,34 @@ func EventLoop(app *gopi.AppInstance, done <-chan struct{}) error {
 	// Output header
 	once.Do(PrintHeader)
 
-	// Create a merged event channel
-	events := event.NewEventMerger()
-	remote_events := events.Subscribe()
+	// Subscribe to key press, repeat and release events from the codecs
+	lifecycle := app.ModuleInstance("remotes/lifecycle").(remotes.Lifecycle)
+	remote_events := lifecycle.Subscribe()
 
-	// Subscribe to codecs
-	for _, name := range codecs() {
-		if instance, ok := app.ModuleInstance(name).(remotes.Codec); ok && instance != nil {
-			events.Add(instance.Subscribe())
+	// Obtain keymaps
+	keymaps := app.ModuleInstance("keymap").(remotes.KeyMaps)
+
+	// Register learned pulses with the raw codec
+	if raw, ok := app.ModuleInstance("remotes/raw").(remotes.RawCodec); ok {
+		if err := remotes.SetRawPulses(raw, keymaps); err != nil {
+			app.Logger.Warn("EventLoop: %v", err)
 		}
 	}
 
-	// Obtain keymaps
-	keymaps := app.ModuleInstance("keymap").(remotes.KeyMaps)
+	// Register calibrated timings with the codecs
+	for _, codec := range remotes.ModuleCodecs(app) {
+		if codec, ok := codec.(remotes.CalibratedCodec); ok {
+			if err := remotes.SetCalibrations(codec, keymaps); err != nil {
+				app.Logger.Warn("EventLoop: %v", err)
+			}
+		}
+	}
+
+	// Subscribe to LIRC events when recording
+	var lirc_events <-chan gopi.Event
+	if recorder != nil {
+		lirc_events = app.LIRC.Subscribe()
+	}
 
 	// Wait for either terminate signal or incoming remote event
 FOR_LOOP:
@@ -94,15 +116,23 @@ FOR_LOOP:
 		case <-done:
 			break FOR_LOOP
 		case remote_event := <-remote_events:
-			if err := HandleEvent(keymaps, remote_event.(remotes.RemoteEvent)); err != nil {
+			if err := HandleEvent(keymaps, remote_event.(remotes.LifecycleEvent)); err != nil {
+				app.Logger.Warn("EventLoop: %v", err)
+			}
+		case lirc_event := <-lirc_events:
+			if err := recorder.Record(lirc_event.(gopi.LIRCEvent)); err != nil {
 				app.Logger.Warn("EventLoop: %v", err)
 			}
 		}
 	}
 
-	// Close merged events
-	events.Unsubscribe(remote_events)
-	events.Close()
+	// Stop recording
+	if lirc_events != nil {
+		app.LIRC.Unsubscribe(lirc_events)
+	}
+
+	// Unsubscribe from events
+	lifecycle.Unsubscribe(remote_events)
 	return nil
 }
 
@@ -122,6 +152,23 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 		return err
 	}
 
+	// Record LIRC events to a file
+	if filename, exists := app.AppFlags.GetString("record"); exists {
+		if fh, err := os.Create(filename); err != nil {
+			start <- gopi.DONE
+			done <- gopi.DONE
+			return err
+		} else if recorder, err = remotes.NewRecorder(fh); err != nil {
+			fh.Close()
+			start <- gopi.DONE
+			done <- gopi.DONE
+			return err
+		} else {
+			defer fh.Close()
+			app.Logger.Info("Recording to %v", filename)
+		}
+	}
+
 	// Start signal
 	start <- gopi.DONE
 
@@ -151,6 +198,7 @@ func main() {
 	// Configuration
 	codecs := append(codecs(), "remotes/keymap")
 	config := gopi.NewAppConfig(codecs...)
+	config.AppFlags.FlagString("record", "", "Record received pulses and spaces to a file")
 
 	// start signal
 	start = make(chan struct{})
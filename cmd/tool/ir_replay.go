@@ -0,0 +1,208 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// ir_replay is used to replay recordings made with ir_rcv -record
+// into the codecs, without any IR hardware
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/sys/loopback"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+	_ "github.com/djthorpe/remotes/keymap"
+
+	// Remotes
+	_ "github.com/djthorpe/remotes/codec/irp"
+	_ "github.com/djthorpe/remotes/codec/jvc"
+	_ "github.com/djthorpe/remotes/codec/kaseikyo"
+	_ "github.com/djthorpe/remotes/codec/nec"
+	_ "github.com/djthorpe/remotes/codec/raw"
+	_ "github.com/djthorpe/remotes/codec/rc5"
+	_ "github.com/djthorpe/remotes/codec/rc6"
+	_ "github.com/djthorpe/remotes/codec/sanyo"
+	_ "github.com/djthorpe/remotes/codec/sharp"
+	_ "github.com/djthorpe/remotes/codec/sony"
+)
+
+var (
+	start chan struct{}
+	ready chan struct{}
+	once  sync.Once
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func PrintHeader() {
+	fmt.Printf("%-31s %-25v %-10s %-10s %-15s %-22s %s\n", "Name", "Key", "Scancode", "Device", "Codec", "Event", "Timestamp")
+	fmt.Printf("%-31s %-25v %-10s %-10s %-15s %-22s %s\n",
+		strings.Repeat("-", 31), strings.Repeat("-", 25), strings.Repeat("-", 10), strings.Repeat("-", 10),
+		strings.Repeat("-", 15), strings.Repeat("-", 22), strings.Repeat("-", 10))
+}
+
+func PrintEvent(name, key string, evt remotes.RemoteEvent) {
+	once.Do(PrintHeader)
+	ts := evt.Timestamp().Truncate(time.Millisecond)
+	fmt.Printf("%-31s %-25v 0x%08X 0x%08X %-15s %-22s %v\n", name, key, evt.ScanCode(), evt.Device(), evt.Codec(), evt.EventType(), ts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func HandleEvent(keymaps remotes.KeyMaps, evt remotes.RemoteEvent) {
+	// Lookup entry, and print the event even when there is no entry
+	if entries := keymaps.LookupKeyMapEntry(evt.Codec(), evt.Device(), evt.ScanCode()); len(entries) > 0 {
+		for entry, keymap := range entries {
+			PrintEvent(keymap.Name+": "+entry.Name, fmt.Sprint(entry.Keycode), evt)
+		}
+	} else {
+		PrintEvent("<unmapped>", "<unmapped>", evt)
+	}
+}
+
+func EventLoop(app *gopi.AppInstance, done <-chan struct{}) error {
+	<-start
+
+	// Create a merged event channel
+	events := event.NewEventMerger()
+	remote_events := events.Subscribe()
+
+	// Subscribe to codecs
+	for _, codec := range remotes.ModuleCodecs(app) {
+		events.Add(codec.Subscribe())
+	}
+
+	// Register learned pulses with the raw codec
+	keymaps := app.ModuleInstance("keymap").(remotes.KeyMaps)
+	if raw, ok := app.ModuleInstance("remotes/raw").(remotes.RawCodec); ok {
+		if err := remotes.SetRawPulses(raw, keymaps); err != nil {
+			app.Logger.Warn("EventLoop: %v", err)
+		}
+	}
+
+	// Register calibrated timings with the codecs
+	for _, codec := range remotes.ModuleCodecs(app) {
+		if codec, ok := codec.(remotes.CalibratedCodec); ok {
+			if err := remotes.SetCalibrations(codec, keymaps); err != nil {
+				app.Logger.Warn("EventLoop: %v", err)
+			}
+		}
+	}
+
+	// Signal that the replay can start
+	ready <- gopi.DONE
+
+	// Wait for either terminate signal or incoming remote event
+FOR_LOOP:
+	for {
+		select {
+		case <-done:
+			break FOR_LOOP
+		case remote_event := <-remote_events:
+			HandleEvent(keymaps, remote_event.(remotes.RemoteEvent))
+		}
+	}
+
+	// Close merged events
+	events.Unsubscribe(remote_events)
+	events.Close()
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Main(app *gopi.AppInstance, done chan<- struct{}) error {
+	// Check arguments and LIRC driver
+	args := app.AppFlags.Args()
+	lirc, ok := app.LIRC.(loopback.LIRC)
+	if len(args) == 0 {
+		start <- gopi.DONE
+		done <- gopi.DONE
+		return gopi.ErrHelp
+	} else if ok == false || lirc == nil {
+		start <- gopi.DONE
+		done <- gopi.DONE
+		return errors.New("Missing loopback driver")
+	}
+
+	// Load keymaps, which are optional when replaying
+	keymaps := app.ModuleInstance("keymap").(remotes.KeyMaps)
+	if err := keymaps.LoadKeyMaps(func(filename string, keymap *remotes.KeyMap) {
+		app.Logger.Info("Loading: %v (%v)", filename, keymap.Name)
+	}); err != nil {
+		app.Logger.Warn("Keymaps: %v", err)
+	}
+
+	// Wait until the event loop has subscribed to the codecs
+	start <- gopi.DONE
+	<-ready
+
+	// Replay each recording in turn
+	speed, _ := app.AppFlags.GetFloat64("loopback.speed")
+	for _, filename := range args {
+		if fh, err := os.Open(filename); err != nil {
+			done <- gopi.DONE
+			return err
+		} else if recording, err := remotes.ReadRecording(fh); err != nil {
+			fh.Close()
+			done <- gopi.DONE
+			return fmt.Errorf("%v: %v", filename, err)
+		} else {
+			fh.Close()
+			app.Logger.Info("Replaying: %v (%v events)", filename, len(recording))
+			if err := lirc.Replay(recording, speed); err != nil {
+				done <- gopi.DONE
+				return err
+			}
+		}
+	}
+
+	// Allow time for the codecs to emit the last events
+	app.WaitForSignalOrTimeout(100 * time.Millisecond)
+
+	// Finish gracefully
+	done <- gopi.DONE
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func codecs() []string {
+	codecs := make([]string, 0)
+	// Obtain all the codecs
+	for _, module := range gopi.ModulesByType(gopi.MODULE_TYPE_OTHER) {
+		if strings.HasPrefix(module.Name, "remotes/") {
+			codecs = append(codecs, module.Name)
+		}
+	}
+	return codecs
+}
+
+func main() {
+	// Configuration
+	codecs := append(codecs(), "remotes/keymap")
+	config := gopi.NewAppConfig(codecs...)
+
+	// start and ready signals
+	start = make(chan struct{})
+	ready = make(chan struct{}, 1)
+
+	// Run the command line tool
+	os.Exit(gopi.CommandLineTool(config, Main, EventLoop))
+}
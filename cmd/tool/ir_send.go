@@ -27,9 +27,15 @@ import (
 	_ "github.com/djthorpe/remotes/keymap"
 
 	// Remotes
+	_ "github.com/djthorpe/remotes/codec/irp"
+	_ "github.com/djthorpe/remotes/codec/jvc"
+	_ "github.com/djthorpe/remotes/codec/kaseikyo"
 	_ "github.com/djthorpe/remotes/codec/nec"
-	_ "github.com/djthorpe/remotes/codec/panasonic"
+	_ "github.com/djthorpe/remotes/codec/raw"
 	_ "github.com/djthorpe/remotes/codec/rc5"
+	_ "github.com/djthorpe/remotes/codec/rc6"
+	_ "github.com/djthorpe/remotes/codec/sanyo"
+	_ "github.com/djthorpe/remotes/codec/sharp"
 	_ "github.com/djthorpe/remotes/codec/sony"
 )
 
@@ -95,6 +101,26 @@ func DisplayDeviceEntries(device string, keymaps remotes.KeyMaps, app *gopi.AppI
 	return err
 }
 
+// LookupEntry returns a single entry in a keymap for a key argument, or
+// an error if the key is unknown or ambiguous
+func LookupEntry(keymap *remotes.KeyMap, keymaps remotes.KeyMaps, arg string) (*remotes.KeyMapEntry, error) {
+	entries := make([]*remotes.KeyMapEntry, 0, 1)
+	for _, key := range keymaps.LookupKeyCode(arg) {
+		entries = append(entries, keymaps.GetKeyMapEntry(keymap, remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, key.Keycode, remotes.SCANCODE_UNKNOWN)...)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("Unknown key: %v", arg)
+	} else if len(entries) > 1 {
+		ambigious := ""
+		for _, entry := range entries {
+			ambigious += fmt.Sprint("'" + entry.Name + "',")
+		}
+		return nil, fmt.Errorf("Ambiguous key: %v (It could mean one of %v)", arg, strings.TrimSuffix(ambigious, ","))
+	} else {
+		return entries[0], nil
+	}
+}
+
 func Delete(device string, keymaps remotes.KeyMaps, args []string) error {
 	var once sync.Once
 
@@ -105,23 +131,13 @@ func Delete(device string, keymaps remotes.KeyMaps, args []string) error {
 	}
 	allkeys := strings.Split(strings.Join(args, ","), ",")
 	for _, arg := range allkeys {
-		entries := make([]*remotes.KeyMapEntry, 0, 1)
-		for _, key := range keymaps.LookupKeyCode(arg) {
-			entries = append(entries, keymaps.GetKeyMapEntry(allkeymaps[0], remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, key.Keycode, remotes.SCANCODE_UNKNOWN)...)
-		}
-		if len(entries) == 0 {
-			return fmt.Errorf("Unknown key: %v", arg)
-		} else if len(entries) > 1 {
-			ambigious := ""
-			for _, entry := range entries {
-				ambigious += fmt.Sprint("'" + entry.Name + "',")
-			}
-			return fmt.Errorf("Ambiguous key: %v (It could mean one of %v)", arg, strings.TrimSuffix(ambigious, ","))
+		if entry, err := LookupEntry(allkeymaps[0], keymaps, arg); err != nil {
+			return err
 		} else {
 			// Perform the delete
 			once.Do(DisplayEntryHeader)
-			DisplayEntry(entries[0], "Deleted ")
-			if err := keymaps.DeleteKeyMapEntry(allkeymaps[0], entries[0]); err != nil {
+			DisplayEntry(entry, "Deleted ")
+			if err := keymaps.DeleteKeyMapEntry(allkeymaps[0], entry); err != nil {
 				return err
 			}
 		}
@@ -142,30 +158,79 @@ func Send(device string, keymaps remotes.KeyMaps, args []string, repeats uint, r
 	// to a single entry or else the argument is ambiguous
 	allkeys := strings.Split(strings.Join(args, ","), ",")
 	for _, arg := range allkeys {
-		entries := make([]*remotes.KeyMapEntry, 0, 1)
-		for _, key := range keymaps.LookupKeyCode(arg) {
-			entries = append(entries, keymaps.GetKeyMapEntry(allkeymaps[0], remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, key.Keycode, remotes.SCANCODE_UNKNOWN)...)
-		}
-		if len(entries) == 0 {
-			return fmt.Errorf("Unknown key: %v", arg)
-		} else if len(entries) > 1 {
-			ambigious := ""
-			for _, entry := range entries {
-				ambigious += fmt.Sprint("'" + entry.Name + "',")
-			}
-			return fmt.Errorf("Ambiguous key: %v (It could mean one of %v)", arg, strings.TrimSuffix(ambigious, ","))
+		if entry, err := LookupEntry(allkeymaps[0], keymaps, arg); err != nil {
+			return err
 		} else {
 			// Override the repeats value
 			if repeats_override {
-				entries[0].Repeats = repeats
+				entry.Repeats = repeats
 			}
 			// Perform the send
 			once.Do(DisplayEntryHeader)
-			DisplayEntry(entries[0], "Sent ")
-			send <- entries[0]
+			DisplayEntry(entry, "Sent ")
+			send <- entry
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+func Export(device string, keymaps remotes.KeyMaps, args []string, app *gopi.AppInstance) error {
+	// Get keymap for device
+	allkeymaps := keymaps.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, device)
+	if len(allkeymaps) != 1 {
+		return fmt.Errorf("Invalid -device flag")
+	}
+
+	// Export all keys when there are no arguments
+	entries := make([]*remotes.KeyMapEntry, 0)
+	if len(args) == 0 {
+		entries = keymaps.GetKeyMapEntry(allkeymaps[0], remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, remotes.KEYCODE_NONE, remotes.SCANCODE_UNKNOWN)
+	} else {
+		for _, arg := range strings.Split(strings.Join(args, ","), ",") {
+			if entry, err := LookupEntry(allkeymaps[0], keymaps, arg); err != nil {
+				return err
+			} else {
+				entries = append(entries, entry)
+			}
 		}
 	}
 
+	// Output the Pronto code for each entry
+	codec_map := codecMap(app)
+	for _, entry := range entries {
+		if codec, exists := codec_map[entry.Type]; exists == false || codec == nil {
+			return fmt.Errorf("Codec not registered: %v", entry.Type)
+		} else if len(entry.Pulses) > 0 {
+			// Learned pulses are exported as the intro sequence
+			code := &remotes.ProntoCode{
+				Frequency: codec.Carrier(entry.Device).Frequency,
+				Intro:     append(append([]uint32{}, entry.Pulses...), remotes.PRONTO_LEADOUT),
+			}
+			fmt.Printf("%-20s %v\n", entry.Name, code)
+		} else if code, err := remotes.EncodePronto(codec, entry.Device, entry.Scancode); err != nil {
+			return fmt.Errorf("%v: %v", entry.Name, err)
+		} else {
+			fmt.Printf("%-20s %v\n", entry.Name, code)
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+func SendPronto(value string, repeats uint, app *gopi.AppInstance) error {
+	if code, err := remotes.ParsePronto(value); err != nil {
+		return err
+	} else if lirc, ok := app.ModuleInstance("lirc").(gopi.LIRC); ok == false || lirc == nil {
+		return fmt.Errorf("Missing LIRC module")
+	} else if err := remotes.SendPronto(lirc, code, repeats); err != nil {
+		return err
+	} else {
+		fmt.Printf("Sent Pronto code with carrier %vHz\n", code.Frequency)
+	}
+
 	// Return success
 	return nil
 }
@@ -180,17 +245,28 @@ func SetRepeats(device string, keymaps remotes.KeyMaps, repeats uint) error {
 	return keymaps.SetRepeats(allkeymaps[0], repeats)
 }
 
+func Rename(device string, keymaps remotes.KeyMaps, name string, app *gopi.AppInstance) error {
+	// Get keymap for device
+	allkeymaps := keymaps.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, device)
+	if len(allkeymaps) != 1 {
+		return fmt.Errorf("Invalid -device flag")
+	}
+	// Set the name
+	if err := keymaps.SetName(allkeymaps[0], name); err == remotes.ErrDuplicateName {
+		return fmt.Errorf("Invalid -rename flag: A keymap named '%v' already exists", strings.TrimSpace(name))
+	} else if err != nil {
+		return err
+	}
+	// Display the keymaps
+	return DisplayKeymaps(keymaps, app)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 func SendLoop(app *gopi.AppInstance, done <-chan struct{}) error {
 
 	// Make a map of the codecs registered
-	codec_map := make(map[remotes.CodecType]remotes.Codec, 10)
-	for _, name := range codecs() {
-		if instance, ok := app.ModuleInstance(name).(remotes.Codec); ok && instance != nil {
-			codec_map[instance.Type()] = instance
-		}
-	}
+	codec_map := codecMap(app)
 
 FOR_LOOP:
 	for {
@@ -201,6 +277,13 @@ FOR_LOOP:
 			if entry != nil {
 				if codec, exists := codec_map[entry.Type]; exists == false || codec == nil {
 					return fmt.Errorf("Codec not registered: %v", entry.Type)
+				} else if raw, ok := codec.(remotes.RawCodec); ok && len(entry.Pulses) > 0 {
+					// Set the learned pulses before sending
+					if err := raw.SetPulses(entry.Device, entry.Scancode, entry.Pulses); err != nil {
+						return err
+					} else if err := raw.Send(entry.Device, entry.Scancode, entry.Repeats); err != nil {
+						return err
+					}
 				} else if err := codec.Send(entry.Device, entry.Scancode, entry.Repeats); err != nil {
 					return err
 				}
@@ -223,15 +306,41 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 		return err
 	}
 
+	// Register carrier overrides with the codecs
+	for _, codec := range codecMap(app) {
+		if err := remotes.SetCarriers(codec, keymaps); err != nil {
+			done <- gopi.DONE
+			return err
+		}
+	}
+
 	// Repeats override
 	repeats, repeats_override := app.AppFlags.GetUint("repeats")
 
-	if device, exists := app.AppFlags.GetString("device"); exists == false {
+	if pronto, exists := app.AppFlags.GetString("pronto"); exists {
+		// Send a Pronto code
+		if err := SendPronto(pronto, repeats, app); err != nil {
+			done <- gopi.DONE
+			return err
+		}
+	} else if device, exists := app.AppFlags.GetString("device"); exists == false {
 		// No -device flag so display devices
 		if err := DisplayKeymaps(keymaps, app); err != nil {
 			done <- gopi.DONE
 			return err
 		}
+	} else if name, exists := app.AppFlags.GetString("rename"); exists {
+		// Rename the keymap
+		if err := Rename(device, keymaps, name, app); err != nil {
+			done <- gopi.DONE
+			return err
+		}
+	} else if export, _ := app.AppFlags.GetBool("export"); export {
+		// Export keys as Pronto codes
+		if err := Export(device, keymaps, app.AppFlags.Args(), app); err != nil {
+			done <- gopi.DONE
+			return err
+		}
 	} else if args := app.AppFlags.Args(); len(args) > 0 {
 		if delete, _ := app.AppFlags.GetBool("delete"); delete {
 			// Delete keymappings
@@ -277,6 +386,14 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 
 ////////////////////////////////////////////////////////////////////////////////
 
+func codecMap(app *gopi.AppInstance) map[remotes.CodecType]remotes.Codec {
+	codec_map := make(map[remotes.CodecType]remotes.Codec, 10)
+	for _, codec := range remotes.ModuleCodecs(app) {
+		codec_map[codec.Type()] = codec
+	}
+	return codec_map
+}
+
 func codecs() []string {
 	codecs := make([]string, 0)
 	// Obtain all the codecs
@@ -295,6 +412,9 @@ func main() {
 	config.AppFlags.FlagString("device", "", "Name of device to send codes to")
 	config.AppFlags.FlagUint("repeats", 0, "Number of code repeats (overrides default)")
 	config.AppFlags.FlagBool("delete", false, "Delete key mapping(s)")
+	config.AppFlags.FlagString("rename", "", "Rename the keymap for the device")
+	config.AppFlags.FlagBool("export", false, "Output key mapping(s) as Pronto codes")
+	config.AppFlags.FlagString("pronto", "", "Send a Pronto code")
 
 	// Make the send channel
 	send = make(chan *remotes.KeyMapEntry)
@@ -0,0 +1,100 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// ir_uinput injects the keys pressed on remotes into a Linux virtual
+// keyboard, so that any application can be controlled by a remote
+package main
+
+import (
+	"os"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/hw/linux"
+	_ "github.com/djthorpe/gopi/sys/logger"
+	_ "github.com/djthorpe/remotes/keymap"
+	_ "github.com/djthorpe/remotes/lifecycle"
+	_ "github.com/djthorpe/remotes/mapper"
+	_ "github.com/djthorpe/remotes/sys/uinput"
+
+	// Remotes
+	_ "github.com/djthorpe/remotes/codec/irp"
+	_ "github.com/djthorpe/remotes/codec/jvc"
+	_ "github.com/djthorpe/remotes/codec/kaseikyo"
+	_ "github.com/djthorpe/remotes/codec/nec"
+	_ "github.com/djthorpe/remotes/codec/raw"
+	_ "github.com/djthorpe/remotes/codec/rc5"
+	_ "github.com/djthorpe/remotes/codec/rc6"
+	_ "github.com/djthorpe/remotes/codec/sanyo"
+	_ "github.com/djthorpe/remotes/codec/sharp"
+	_ "github.com/djthorpe/remotes/codec/sony"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Main(app *gopi.AppInstance, done chan<- struct{}) error {
+	// Load keymaps
+	keymaps := app.ModuleInstance("keymap").(remotes.KeyMaps)
+	if err := keymaps.LoadKeyMaps(func(filename string, keymap *remotes.KeyMap) {
+		app.Logger.Info("Loading: %v (%v)", filename, keymap.Name)
+	}); err != nil {
+		done <- gopi.DONE
+		return err
+	}
+
+	// Register learned pulses with the raw codec
+	if raw, ok := app.ModuleInstance("remotes/raw").(remotes.RawCodec); ok {
+		if err := remotes.SetRawPulses(raw, keymaps); err != nil {
+			app.Logger.Warn("Main: %v", err)
+		}
+	}
+
+	// Register calibrated timings with the codecs
+	for _, codec := range remotes.ModuleCodecs(app) {
+		if codec, ok := codec.(remotes.CalibratedCodec); ok {
+			if err := remotes.SetCalibrations(codec, keymaps); err != nil {
+				app.Logger.Warn("Main: %v", err)
+			}
+		}
+	}
+
+	// Wait for interrupt
+	app.Logger.Info("Waiting for CTRL+C or SIGTERM to end")
+	app.WaitForSignal()
+
+	// Finish gracefully
+	done <- gopi.DONE
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func codecs() []string {
+	codecs := make([]string, 0)
+	// Obtain all the codecs
+	for _, module := range gopi.ModulesByType(gopi.MODULE_TYPE_OTHER) {
+		if strings.HasPrefix(module.Name, "remotes/") {
+			codecs = append(codecs, module.Name)
+		}
+	}
+	return codecs
+}
+
+func main() {
+	// Configuration
+	modules := append(codecs(), "remotes/keymap", "uinput/remotes")
+	config := gopi.NewAppConfig(modules...)
+
+	// Run the command line tool
+	os.Exit(gopi.CommandLineTool(config, Main))
+}
@@ -0,0 +1,181 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Protocols Configuration, which creates a codec for each protocol
+// in the protocol files in a folder
+type Protocols struct {
+	LIRC      gopi.LIRC
+	Path      string // Folder containing the protocol files
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type protocols struct {
+	log    gopi.Logger
+	path   string
+	codecs []remotes.Codec
+	errors map[string]error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Protocols) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.irp.Protocols>Open{ path=\"%v\" }", config.Path)
+
+	this := new(protocols)
+	this.log = log
+	this.path = config.Path
+	this.codecs = make([]remotes.Codec, 0)
+	this.errors = make(map[string]error)
+
+	// There are no protocols when the folder does not exist
+	if this.path == "" {
+		return this, nil
+	}
+	files, err := ioutil.ReadDir(this.path)
+	if os.IsNotExist(err) {
+		log.Debug("<remotes.codec.irp.Protocols>Open: %v", err)
+		return this, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Create codecs for each file. A file which can't be read is reported
+	// and the protocols in other files are still used
+	for _, file := range files {
+		if file.Mode().IsRegular() == false || filepath.Ext(file.Name()) != EXT_PROTOCOLS {
+			continue
+		}
+		path := filepath.Join(this.path, file.Name())
+		if codecs, err := this.openFile(config, path); err != nil {
+			log.Warn("<remotes.codec.irp.Protocols>Open: %v: %v", path, err)
+			this.errors[path] = err
+		} else {
+			this.codecs = append(this.codecs, codecs...)
+		}
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *protocols) Close() error {
+	this.log.Debug("<remotes.codec.irp.Protocols>Close{ path=\"%v\" }", this.path)
+
+	// Close the codecs
+	for _, codec := range this.codecs {
+		if err := codec.Close(); err != nil {
+			return err
+		}
+	}
+
+	// Release resources
+	this.codecs = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *protocols) String() string {
+	return fmt.Sprintf("<remotes.codec.irp.Protocols>{ path=\"%v\" codecs=%v }", this.path, this.codecs)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODECSET INTERFACE
+
+// Codecs returns a codec for each protocol
+func (this *protocols) Codecs() []remotes.Codec {
+	return this.codecs
+}
+
+// LoadErrors returns files which could not be read, and the reason
+func (this *protocols) LoadErrors() map[string]error {
+	return this.errors
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// openFile returns codecs for all the protocols in a file, or an error
+// if any protocol can't be used
+func (this *protocols) openFile(config Protocols, path string) ([]remotes.Codec, error) {
+	definitions, err := readProtocolFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Obtain the codec types
+	types := make([]remotes.CodecType, len(definitions))
+	for i, definition := range definitions {
+		if codec_type, err := remotes.NamedCodecType(definition.Name); err != nil {
+			return nil, fmt.Errorf("%v: %v", definition.Name, err)
+		} else if this.codecForType(codec_type) != nil || containsType(types[:i], codec_type) {
+			return nil, fmt.Errorf("%v: %v", definition.Name, remotes.ErrDuplicateCodec)
+		} else {
+			types[i] = codec_type
+		}
+	}
+
+	// Open the codecs
+	codecs := make([]remotes.Codec, 0, len(definitions))
+	for i, definition := range definitions {
+		if driver, err := gopi.Open(Codec{
+			LIRC:      config.LIRC,
+			Type:      types[i],
+			Protocol:  definition.Protocol,
+			Tolerance: config.Tolerance,
+		}, this.log); err != nil {
+			for _, codec := range codecs {
+				codec.Close()
+			}
+			return nil, fmt.Errorf("%v: %v", definition.Name, err)
+		} else {
+			codecs = append(codecs, driver.(remotes.Codec))
+		}
+	}
+
+	// Return success
+	return codecs, nil
+}
+
+func (this *protocols) codecForType(codec_type remotes.CodecType) remotes.Codec {
+	for _, codec := range this.codecs {
+		if codec.Type() == codec_type {
+			return codec
+		}
+	}
+	return nil
+}
+
+func containsType(types []remotes.CodecType, codec_type remotes.CodecType) bool {
+	for _, t := range types {
+		if t == codec_type {
+			return true
+		}
+	}
+	return false
+}
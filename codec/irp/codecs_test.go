@@ -0,0 +1,120 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/irp"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestProtocolFiles(t *testing.T) {
+	// Open the example protocols
+	protocols := openProtocols(t, filepath.Join("..", "..", "etc", "irp"))
+	defer protocols.Close()
+
+	names := make(map[string]remotes.Codec)
+	for _, codec := range protocols.Codecs() {
+		names[codec.Type().String()] = codec
+	}
+	if len(names) != 2 {
+		t.Errorf("Expected two codecs, got %v", protocols.Codecs())
+	}
+	for _, name := range []string{"CODEC_MITSUBISHI", "CODEC_NOKIA32"} {
+		if codec, exists := names[name]; exists == false {
+			t.Errorf("Missing codec %v", name)
+		} else if codec.Type()&remotes.CODEC_NAMED == 0 {
+			t.Errorf("%v: Unexpected codec type 0x%X", name, uint(codec.Type()))
+		} else if codec_type, err := remotes.NamedCodecType(name); err != nil {
+			t.Error(err)
+		} else if codec_type != codec.Type() {
+			t.Errorf("%v: Expected the same codec type for the same name", name)
+		}
+	}
+}
+
+func TestProtocolFileErrors(t *testing.T) {
+	path, err := ioutil.TempDir("", "irp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	// One file is valid, the others are not, and other extensions are ignored
+	files := map[string]string{
+		"valid.irp":     "# Comment\nTEST_VALID {38k,500}<1,-1|1,-3>(16,-8,D:8,F:8,1,-50)*[D:0..255,F:0..255]\n",
+		"invalid.irp":   "TEST_INVALID {38k,500}<1,-1|1,-3>(16,-8,D:8,F:8,1,-50\n",
+		"duplicate.irp": "NEC32 {38k,564}<1,-1|1,-3>(16,-8,D:8,F:8,1,-78)*[D:0..255,F:0..255]\n",
+		"ignored.txt":   "Not a protocol",
+	}
+	for name, contents := range files {
+		if err := ioutil.WriteFile(filepath.Join(path, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	protocols := openProtocols(t, path)
+	defer protocols.Close()
+	if codecs := protocols.Codecs(); len(codecs) != 1 || codecs[0].Type().String() != "CODEC_TEST_VALID" {
+		t.Errorf("Expected one codec, got %v", codecs)
+	}
+	errors := protocols.(interface {
+		LoadErrors() map[string]error
+	}).LoadErrors()
+	if len(errors) != 2 {
+		t.Errorf("Expected two errors, got %v", errors)
+	}
+	for _, name := range []string{"invalid.irp", "duplicate.irp"} {
+		if _, exists := errors[filepath.Join(path, name)]; exists == false {
+			t.Errorf("%v: Expected error", name)
+		}
+	}
+}
+
+func TestMissingFolder(t *testing.T) {
+	protocols := openProtocols(t, filepath.Join(os.TempDir(), "irp_missing_folder"))
+	defer protocols.Close()
+	if codecs := protocols.Codecs(); len(codecs) != 0 {
+		t.Errorf("Expected no codecs, got %v", codecs)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func newLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if driver, err := gopi.Open(logger.Config{Level: logger.LOG_ERROR}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.Logger)
+	}
+}
+
+func openProtocols(t *testing.T, path string) remotes.CodecSet {
+	t.Helper()
+	if driver, err := gopi.Open(irp.Protocols{Path: path}, newLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(remotes.CodecSet)
+	}
+}
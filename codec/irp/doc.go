@@ -0,0 +1,49 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+/*
+
+The IRP codec builds both a decoder and an encoder from a protocol
+described in IRP notation, as used by IrScrutinizer and the irdb project:
+http://www.harctoolbox.org/IrpTransmogrifier.html#The+IRP+notation
+
+  * General spec: {38.4k,564,msb,33%} is the carrier frequency, unit in
+  *   microseconds (or with a m, u or p suffix), bit order and duty cycle
+  * Bit spec: <1,-1|1,-3> are the pulses and spaces for each symbol, where
+  *   the number of symbols is 2, 4, 8 or 16
+  * Stream: (16,-8,D:8,S:8,F:8,~F:8,1,^108m,(16,-4,1,^108m)*) contains
+  *   pulses, spaces, extents, bitfields and nested streams
+  *   with a *, +, n or n+ repeat marker
+  * Bitfields: D:8 or ~F:8 or D:4:4 or (255-D):8
+  * Parameter spec: [D:0..255,S:0..255=255-D,F:0..255,T@:0..1=0]
+
+The parameter F is the scancode. The other parameters make up the device,
+packed in the order they are declared with the first parameter in the most
+significant bits, except for persistent parameters (marked with @) which
+are toggled on each send. Assignments within streams are not supported.
+
+Where the whole stream repeats, frames with the same parameters received
+in quick succession are reported as repeats. Otherwise the repeating
+part of the stream is reported as a repeat of the previous frame.
+
+Protocols are read from files with the .irp extension in the folder set
+by the -irp.path flag (/var/local/remotes/irp by default), one per line,
+with the protocol name followed by the IRP notation:
+
+  MITSUBISHI {32.6k,300}<1,-3|1,-7>(D:8,F:8,1,-80)*[D:0..255,F:0..255]
+
+The remotes/irp module creates a codec for each protocol. The codec type
+is derived from the name (see remotes.NamedCodecType) so no new constant
+is needed, and is reported as CODEC_<name>. A file which can't be read,
+or which contains a protocol with the same name as another codec, is
+reported and skipped. Example protocol files are in etc/irp.
+
+*/
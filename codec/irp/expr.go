@@ -0,0 +1,190 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+import (
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// expr is an expression in a bitfield or parameter default, which is
+// evaluated with the values of the parameters
+type expr interface {
+	eval(env map[string]uint64) (uint64, error)
+	names() []string
+}
+
+type numberExpr uint64
+
+type nameExpr string
+
+type unaryExpr struct {
+	op      rune
+	operand expr
+}
+
+type binaryExpr struct {
+	op          rune
+	left, right expr
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	// Binary operators in order of increasing precedence
+	precedence = []string{"|", "^", "&", "+-", "*/%"}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSE
+
+func (this *parser) parseExpr() (expr, error) {
+	return this.parseBinary(0)
+}
+
+func (this *parser) parseBinary(level int) (expr, error) {
+	if level == len(precedence) {
+		return this.parseUnary()
+	}
+	left, err := this.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for this.pos < len(this.tokens) && strings.ContainsRune(precedence[level], this.tokens[this.pos].kind) {
+		op := this.next().kind
+		if right, err := this.parseBinary(level + 1); err != nil {
+			return nil, err
+		} else {
+			left = &binaryExpr{op, left, right}
+		}
+	}
+	return left, nil
+}
+
+func (this *parser) parseUnary() (expr, error) {
+	if this.peek('-') || this.peek('~') {
+		op := this.next().kind
+		if operand, err := this.parseUnary(); err != nil {
+			return nil, err
+		} else {
+			return &unaryExpr{op, operand}, nil
+		}
+	}
+	return this.parsePrimary()
+}
+
+func (this *parser) parsePrimary() (expr, error) {
+	switch {
+	case this.peek('n'):
+		if value, err := this.parseNumber(); err != nil {
+			return nil, err
+		} else {
+			return numberExpr(value), nil
+		}
+	case this.peek('a'):
+		return nameExpr(this.next().value), nil
+	case this.peek('('):
+		this.next()
+		if value, err := this.parseExpr(); err != nil {
+			return nil, err
+		} else if err := this.expect(')'); err != nil {
+			return nil, err
+		} else {
+			return value, nil
+		}
+	default:
+		return nil, this.errorf("Expected expression")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVALUATE
+
+func (this numberExpr) eval(env map[string]uint64) (uint64, error) {
+	return uint64(this), nil
+}
+
+func (this numberExpr) names() []string {
+	return nil
+}
+
+func (this nameExpr) eval(env map[string]uint64) (uint64, error) {
+	if value, exists := env[string(this)]; exists == false {
+		return 0, fmt.Errorf("IRP: Missing value for parameter %v", string(this))
+	} else {
+		return value, nil
+	}
+}
+
+func (this nameExpr) names() []string {
+	return []string{string(this)}
+}
+
+func (this *unaryExpr) eval(env map[string]uint64) (uint64, error) {
+	value, err := this.operand.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch this.op {
+	case '-':
+		return -value, nil
+	case '~':
+		return ^value, nil
+	default:
+		return 0, fmt.Errorf("IRP: Invalid operator '%c'", this.op)
+	}
+}
+
+func (this *unaryExpr) names() []string {
+	return this.operand.names()
+}
+
+func (this *binaryExpr) eval(env map[string]uint64) (uint64, error) {
+	left, err := this.left.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	right, err := this.right.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch this.op {
+	case '|':
+		return left | right, nil
+	case '^':
+		return left ^ right, nil
+	case '&':
+		return left & right, nil
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/', '%':
+		if right == 0 {
+			return 0, fmt.Errorf("IRP: Division by zero")
+		} else if this.op == '/' {
+			return left / right, nil
+		} else {
+			return left % right, nil
+		}
+	default:
+		return 0, fmt.Errorf("IRP: Invalid operator '%c'", this.op)
+	}
+}
+
+func (this *binaryExpr) names() []string {
+	return append(this.left.names(), this.right.names()...)
+}
@@ -0,0 +1,449 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// atom is an element of a flattened frame, which is a *duration, *extent
+// or *bitrun
+type atom interface{}
+
+// bitrun is a run of consecutive bitfields, which are sent as one sequence
+// of symbols
+type bitrun struct {
+	fields []*bitfield
+	length uint
+}
+
+// level is a received pulse or space, where consecutive values
+// with the same level are merged
+type level struct {
+	pulse bool
+	value float64
+}
+
+// candidate is a symbol which matches the received levels, with the
+// total deviation from the expected durations
+type candidate struct {
+	symbol    uint
+	c         cursor
+	elapsed   float64
+	deviation float64
+}
+
+// cursor is the position within received levels, where used is the
+// part of the current level which has already been matched
+type cursor struct {
+	index int
+	used  float64
+}
+
+type status uint
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	STATUS_OK status = iota
+	STATUS_FAIL
+	STATUS_INCOMPLETE
+)
+
+const (
+	PARAM_SCANCODE = "F"
+	TOLERANCE      = 35 // 35% tolerance on values
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE
+
+// Encode returns the pulses and spaces in microseconds for a set of
+// parameter values. The repeating part of the protocol is sent
+// the number of times indicated by repeats
+func (this *Protocol) Encode(params map[string]uint64, repeats uint) ([]uint32, error) {
+	values := make([]float64, 0, 100)
+	if err := this.encodeStream(this.stream, params, repeats, true, &values); err != nil {
+		return nil, err
+	}
+
+	// Merge durations with the same sign, and remove leading and trailing spaces
+	pulses := make([]uint32, 0, len(values))
+	sign := false
+	for _, value := range values {
+		if value == 0 {
+			continue
+		} else if len(pulses) == 0 && value < 0 {
+			continue
+		} else if len(pulses) > 0 && (value > 0) == sign {
+			pulses[len(pulses)-1] += uint32(math.Round(math.Abs(value)))
+		} else {
+			pulses = append(pulses, uint32(math.Round(math.Abs(value))))
+			sign = value > 0
+		}
+	}
+	if len(pulses) > 0 && len(pulses)%2 == 0 {
+		pulses = pulses[:len(pulses)-1]
+	}
+
+	// Return success
+	return pulses, nil
+}
+
+func (this *Protocol) encodeStream(s *stream, params map[string]uint64, repeats uint, top bool, values *[]float64) error {
+	// Determine how many times to send the stream
+	count := s.min
+	if s.max == REPEAT_INFINITE {
+		if top {
+			count = 1 + repeats
+		} else if repeats > count {
+			count = repeats
+		}
+	}
+
+	for i := uint(0); i < count; i++ {
+		start := len(*values)
+		bits := make([]bool, 0, 64)
+		for _, item := range s.items {
+			if _, ok := item.(*bitfield); ok == false {
+				if err := this.encodeBits(bits, values); err != nil {
+					return err
+				}
+				bits = bits[:0]
+			}
+			switch item := item.(type) {
+			case *duration:
+				*values = append(*values, item.value)
+			case *extent:
+				elapsed := 0.0
+				for _, value := range (*values)[start:] {
+					elapsed += math.Abs(value)
+				}
+				if item.value > elapsed {
+					*values = append(*values, elapsed-item.value)
+				}
+				start = len(*values)
+			case *bitfield:
+				if value, err := item.value.eval(params); err != nil {
+					return err
+				} else {
+					if item.complement {
+						value = ^value
+					}
+					value = value >> item.offset
+					for j := uint(0); j < item.width; j++ {
+						if this.MSB {
+							bits = append(bits, value&(1<<(item.width-j-1)) != 0)
+						} else {
+							bits = append(bits, value&(1<<j) != 0)
+						}
+					}
+				}
+			case *stream:
+				if err := this.encodeStream(item, params, repeats, false, values); err != nil {
+					return err
+				}
+			}
+		}
+		if err := this.encodeBits(bits, values); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// encodeBits appends the durations for a sequence of bits
+func (this *Protocol) encodeBits(bits []bool, values *[]float64) error {
+	width := this.symbolWidth()
+	if uint(len(bits))%width != 0 {
+		return fmt.Errorf("IRP: Bitfields are not a multiple of the symbol width")
+	}
+	for i := uint(0); i < uint(len(bits)); i += width {
+		symbol := uint(0)
+		for j := uint(0); j < width; j++ {
+			if bits[i+j] == false {
+				continue
+			} else if this.MSB {
+				symbol |= 1 << (width - j - 1)
+			} else {
+				symbol |= 1 << j
+			}
+		}
+		*values = append(*values, this.Bits[symbol]...)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODE
+
+// Decode matches the start of the received levels against a frame, and
+// returns the parameter values and the number of levels consumed
+func (this *Protocol) decode(frame []atom, levels []level, timing *remotes.Timing) (map[string]uint64, int, status) {
+	c, symbols, status := this.match(frame, 0, cursor{}, 0, make([]uint, 0, 64), levels, timing)
+	if status != STATUS_OK {
+		return nil, 0, status
+	}
+
+	// Consume any partially matched level
+	consumed := c.index
+	if c.used > 0 {
+		consumed++
+	}
+
+	// Convert the symbols back into bits
+	width := this.symbolWidth()
+	bits := make([]bool, 0, uint(len(symbols))*width)
+	for _, symbol := range symbols {
+		for j := uint(0); j < width; j++ {
+			if this.MSB {
+				bits = append(bits, symbol&(1<<(width-j-1)) != 0)
+			} else {
+				bits = append(bits, symbol&(1<<j) != 0)
+			}
+		}
+	}
+
+	// Assign bits to parameters, and check expressions and constants
+	params := make(map[string]uint64, len(this.Params))
+	known := make(map[string]uint64, len(this.Params))
+	checks := make([]*bitfield, 0)
+	values := make([]uint64, 0)
+	for _, atom := range frame {
+		run, ok := atom.(*bitrun)
+		if ok == false {
+			continue
+		}
+		for _, field := range run.fields {
+			value := uint64(0)
+			for j := uint(0); j < field.width; j++ {
+				if bits[0] == false {
+					// Zero bit
+				} else if this.MSB {
+					value |= 1 << (field.width - j - 1)
+				} else {
+					value |= 1 << j
+				}
+				bits = bits[1:]
+			}
+			mask := uint64(1)<<field.width - 1
+			if field.complement {
+				value = ^value & mask
+			}
+			if name, ok := field.value.(nameExpr); ok {
+				mask, value := mask<<field.offset, value<<field.offset
+				if known[string(name)]&mask&(params[string(name)]^value) != 0 {
+					return nil, 0, STATUS_FAIL
+				}
+				params[string(name)] |= value
+				known[string(name)] |= mask
+			} else {
+				checks = append(checks, field)
+				values = append(values, value)
+			}
+		}
+	}
+	for i, field := range checks {
+		if value, err := field.value.eval(params); err != nil {
+			return nil, 0, STATUS_FAIL
+		} else if (value>>field.offset)&(uint64(1)<<field.width-1) != values[i] {
+			return nil, 0, STATUS_FAIL
+		}
+	}
+
+	// Check parameter ranges
+	for _, param := range this.Params {
+		if value, exists := params[param.Name]; exists && (value < uint64(param.Min) || value > uint64(param.Max)) {
+			return nil, 0, STATUS_FAIL
+		}
+	}
+
+	// Return success
+	return params, consumed, STATUS_OK
+}
+
+// match performs a depth-first match of the atoms from index i against the
+// received levels, returning the symbols matched
+func (this *Protocol) match(frame []atom, i int, c cursor, elapsed float64, symbols []uint, levels []level, timing *remotes.Timing) (cursor, []uint, status) {
+	if i == len(frame) {
+		return c, symbols, STATUS_OK
+	}
+	switch atom := frame[i].(type) {
+	case *duration:
+		if c, _, status := matchDuration(c, atom.value, levels, timing); status != STATUS_OK {
+			return c, nil, status
+		} else {
+			return this.match(frame, i+1, c, elapsed+math.Abs(atom.value), symbols, levels, timing)
+		}
+	case *extent:
+		// The remainder of the current space is consumed
+		gap := atom.value - elapsed
+		if c.index >= len(levels) {
+			return c, nil, STATUS_INCOMPLETE
+		} else if levels[c.index].pulse {
+			if gap > 0 {
+				return c, nil, STATUS_FAIL
+			}
+		} else if min, _ := window(timing, false, gap); gap > 0 && levels[c.index].value-c.used < min {
+			return c, nil, STATUS_FAIL
+		} else {
+			c = cursor{c.index + 1, 0}
+		}
+		return this.match(frame, i+1, c, 0, symbols, levels, timing)
+	case *bitrun:
+		return this.matchSymbols(frame, i, 0, c, elapsed, symbols, levels, timing)
+	default:
+		return c, nil, STATUS_FAIL
+	}
+}
+
+// matchSymbols matches the n'th symbol in a run of bitfields. Where more
+// than one symbol matches, the closest match is tried first
+func (this *Protocol) matchSymbols(frame []atom, i int, n uint, c cursor, elapsed float64, symbols []uint, levels []level, timing *remotes.Timing) (cursor, []uint, status) {
+	run := frame[i].(*bitrun)
+	if n == run.length/this.symbolWidth() {
+		return this.match(frame, i+1, c, elapsed, symbols, levels, timing)
+	}
+	incomplete := false
+	candidates := make([]candidate, 0, len(this.Bits))
+	for symbol, durations := range this.Bits {
+		candidate := candidate{symbol: uint(symbol), c: c, elapsed: elapsed}
+		status := STATUS_OK
+		for _, value := range durations {
+			var deviation float64
+			if candidate.c, deviation, status = matchDuration(candidate.c, value, levels, timing); status != STATUS_OK {
+				break
+			}
+			candidate.elapsed += math.Abs(value)
+			candidate.deviation += deviation
+		}
+		if status == STATUS_OK {
+			candidates = append(candidates, candidate)
+		} else if status == STATUS_INCOMPLETE {
+			incomplete = true
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].deviation < candidates[j].deviation
+	})
+	for _, candidate := range candidates {
+		// Copy the symbols so that other alternatives are not overwritten
+		symbols_ := append(symbols[:len(symbols):len(symbols)], candidate.symbol)
+		if c_, symbols_, status := this.matchSymbols(frame, i, n+1, candidate.c, candidate.elapsed, symbols_, levels, timing); status == STATUS_OK {
+			return c_, symbols_, status
+		} else if status == STATUS_INCOMPLETE {
+			incomplete = true
+		}
+	}
+	if incomplete {
+		return c, nil, STATUS_INCOMPLETE
+	} else {
+		return c, nil, STATUS_FAIL
+	}
+}
+
+// matchDuration matches a pulse (positive value) or space (negative value),
+// and returns the relative deviation from the expected value. Where
+// the received level is longer than expected, only part of it is consumed
+func matchDuration(c cursor, value float64, levels []level, timing *remotes.Timing) (cursor, float64, status) {
+	if c.index >= len(levels) {
+		return c, 0, STATUS_INCOMPLETE
+	}
+	level := levels[c.index]
+	if level.pulse != (value > 0) {
+		return c, 0, STATUS_FAIL
+	}
+	value = math.Abs(value)
+	remaining := level.value - c.used
+	if min, max := window(timing, level.pulse, value); remaining >= min && remaining <= max {
+		return cursor{c.index + 1, 0}, math.Abs(remaining-value) / value, STATUS_OK
+	} else if remaining > value {
+		return cursor{c.index, c.used + value}, 0, STATUS_OK
+	} else {
+		return c, 0, STATUS_FAIL
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// window returns the minimum and maximum durations which match a pulse
+// or space
+func window(timing *remotes.Timing, pulse bool, value float64) (float64, float64) {
+	evt_type := gopi.LIRC_TYPE_SPACE
+	if pulse {
+		evt_type = gopi.LIRC_TYPE_PULSE
+	}
+	min, max := timing.Window(evt_type, uint32(value))
+	return float64(min), float64(max)
+}
+
+// symbolWidth returns the number of bits for each symbol
+func (this *Protocol) symbolWidth() uint {
+	return uint(bits.TrailingZeros(uint(len(this.Bits))))
+}
+
+// flattenItems returns atoms for a list of items, with consecutive
+// bitfields merged into runs and nested streams expanded
+func flattenItems(items []interface{}) []atom {
+	atoms := make([]atom, 0, len(items))
+	var run *bitrun
+	for _, item := range items {
+		if field, ok := item.(*bitfield); ok {
+			if run == nil {
+				run = &bitrun{}
+				atoms = append(atoms, run)
+			}
+			run.fields = append(run.fields, field)
+			run.length += field.width
+			continue
+		}
+		run = nil
+		switch item := item.(type) {
+		case *stream:
+			count := item.min
+			if count == 0 {
+				count = 1
+			}
+			for i := uint(0); i < count; i++ {
+				atoms = append(atoms, flattenItems(item.items)...)
+			}
+		default:
+			atoms = append(atoms, item)
+		}
+	}
+	return atoms
+}
+
+func (s status) String() string {
+	switch s {
+	case STATUS_OK:
+		return "STATUS_OK"
+	case STATUS_FAIL:
+		return "STATUS_FAIL"
+	case STATUS_INCOMPLETE:
+		return "STATUS_INCOMPLETE"
+	default:
+		return "[?? Invalid status]"
+	}
+}
@@ -0,0 +1,40 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register remotes/irp, which creates a codec for each protocol
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/irp",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagString("irp.path", "/var/local/remotes/irp", "Folder containing IRP protocol files")
+			config.AppFlags.FlagUint("irp.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			path, _ := app.AppFlags.GetString("irp.path")
+			tolerance, _ := app.AppFlags.GetUint("irp.tolerance")
+			return gopi.Open(Protocols{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Path:      path,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+}
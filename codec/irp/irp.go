@@ -0,0 +1,444 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// IRP Configuration
+type Codec struct {
+	LIRC      gopi.LIRC
+	Type      remotes.CodecType
+	Protocol  *Protocol
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type codec struct {
+	log        gopi.Logger
+	lirc       gopi.LIRC
+	carriers   *remotes.Carriers
+	timing     *remotes.Timing
+	codec_type remotes.CodecType
+	protocol   *Protocol
+	levels     []level
+	persistent map[string]uint64
+	last_value map[string]uint64
+	last_ts    time.Duration
+	decoding   bool
+	elapsed    time.Duration
+
+	event.Publisher
+	event.Tasks
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	REPEAT_DURATION = 250 * time.Millisecond // Frames within this duration can be repeats
+	MAX_LEVELS      = 1000                   // Maximum number of received levels to buffer
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	timestamp = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.irp>Open{ lirc=%v type=%v protocol=%v }", config.LIRC, config.Type, config.Protocol)
+
+	// Check for codec type and protocol
+	if config.Type == remotes.CODEC_NONE || config.Protocol == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(config.Protocol.Frequency, config.Protocol.DutyCycle)
+	this.codec_type = config.Type
+	this.protocol = config.Protocol
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
+
+	// Set initial values for persistent parameters
+	this.persistent = make(map[string]uint64)
+	for _, param := range this.protocol.Params {
+		if param.Persistent == false {
+			continue
+		} else if param.Default == nil {
+			this.persistent[param.Name] = uint64(param.Min)
+		} else if value, err := param.Default.eval(this.persistent); err != nil {
+			return nil, err
+		} else {
+			this.persistent[param.Name] = value
+		}
+	}
+
+	// Reset state
+	this.Reset()
+
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *codec) Close() error {
+	this.log.Debug("<remotes.codec.irp>Close>{ type=%v }", this.codec_type)
+
+	// Remove subscribers to this codec
+	this.Publisher.Close()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.lirc = nil
+	this.protocol = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	return fmt.Sprintf("<remotes.Codec.IRP>{ type=%v protocol=%v }", this.codec_type, this.protocol)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *codec) Type() remotes.CodecType {
+	return this.codec_type
+}
+
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
+func (this *codec) Reset() {
+	this.levels = make([]level, 0, 100)
+	this.last_value = nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *codec) Emit(params map[string]uint64, repeat bool) {
+	scancode, device := this.codeForParams(params)
+	this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, repeat))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *codec) PulseTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	events := this.lirc.Subscribe()
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt != nil {
+				this.receive(evt.(gopi.LIRCEvent))
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.lirc.Unsubscribe(events)
+
+	// Success
+	return nil
+}
+
+func (this *codec) receive(evt gopi.LIRCEvent) {
+	this.log.Debug2("<remotes.codec.irp>Receive{ type=%v evt=%v }", this.codec_type, evt)
+
+	// Append the level, merging it with the previous level if the same
+	switch evt.Type() {
+	case gopi.LIRC_TYPE_PULSE:
+		this.append(true, float64(evt.Value()))
+	case gopi.LIRC_TYPE_SPACE:
+		this.append(false, float64(evt.Value()))
+	case gopi.LIRC_TYPE_TIMEOUT:
+		this.append(false, math.Inf(1))
+	default:
+		return
+	}
+
+	// A gap longer than the repeat duration since the previous frame ends
+	// the transmission, so repeat frames which follow are ignored
+	if this.last_value != nil && this.now()-this.last_ts > REPEAT_DURATION {
+		this.last_value = nil
+	}
+
+	// Decode as many frames as possible from the start of the levels
+	for len(this.levels) > 0 {
+		if this.levels[0].pulse == false {
+			this.levels = this.levels[1:]
+			continue
+		}
+		if params, consumed, status := this.decode(this.protocol.intro); status == STATUS_OK {
+			this.eject(params, false)
+			this.levels = this.levels[consumed:]
+		} else if this.last_value != nil && this.protocol.repeat != nil {
+			if params, consumed_, status_ := this.decode(this.protocol.repeat); status_ == STATUS_OK {
+				this.eject(params, true)
+				this.levels = this.levels[consumed_:]
+			} else if status == STATUS_INCOMPLETE || status_ == STATUS_INCOMPLETE {
+				break
+			} else {
+				this.levels = this.levels[1:]
+			}
+		} else if status == STATUS_INCOMPLETE {
+			break
+		} else {
+			this.levels = this.levels[1:]
+		}
+	}
+
+	// A timeout or a space longer than the repeat duration also ends the
+	// transmission, after any frame it completes
+	if evt.Type() == gopi.LIRC_TYPE_TIMEOUT {
+		this.last_value = nil
+	} else if evt.Type() == gopi.LIRC_TYPE_SPACE && time.Duration(evt.Value())*time.Microsecond > REPEAT_DURATION {
+		this.last_value = nil
+	}
+
+	// Limit the number of levels buffered
+	if len(this.levels) > MAX_LEVELS {
+		this.levels = this.levels[len(this.levels)-MAX_LEVELS:]
+	}
+}
+
+func (this *codec) append(pulse bool, value float64) {
+	if n := len(this.levels); n > 0 && this.levels[n-1].pulse == pulse {
+		this.levels[n-1].value += value
+	} else {
+		this.levels = append(this.levels, level{pulse, value})
+	}
+}
+
+// eject emits an event for a decoded frame. Where the frame contains no
+// parameters it repeats the previous frame, otherwise it's a repeat if the
+// parameters are the same as the previous frame and it was received recently
+func (this *codec) eject(params map[string]uint64, repeat_frame bool) {
+	ts := this.now()
+	if repeat_frame && len(params) == 0 {
+		params = this.last_value
+	}
+	repeat := repeat_frame || (ts-this.last_ts < REPEAT_DURATION && equalParams(params, this.last_value))
+	this.last_value = params
+	this.last_ts = ts
+	this.Emit(params, repeat)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), codec_type: this.codec_type, protocol: this.protocol, decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SENDING
+
+func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
+	this.log.Debug2("<remotes.codec.irp>Send{ type=%v device=0x%08X scancode=0x%08X repeats=%v }", this.codec_type, device, scancode, repeats)
+
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.irp> Send: No LIRC driver")
+		return gopi.ErrAppError
+	}
+
+	// Encode the pulses
+	pulses, err := this.Encode(device, scancode, repeats)
+	if err != nil {
+		return err
+	}
+
+	// Update persistent parameters (toggles) for the next send
+	for _, param := range this.protocol.Params {
+		if param.Persistent {
+			if value := this.persistent[param.Name] + 1; value > uint64(param.Max) {
+				this.persistent[param.Name] = uint64(param.Min)
+			} else {
+				this.persistent[param.Name] = value
+			}
+		}
+	}
+
+	// Perform the sending
+	return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+}
+
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	// Set parameters from device and scancode, and persistent parameters
+	params, err := this.paramsForCode(scancode, device)
+	if err != nil {
+		this.log.Error("<remotes.codec.irp> Encode: %v", err)
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Encode the pulses
+	pulses, err := this.protocol.Encode(params, repeats)
+	if err != nil {
+		this.log.Error("<remotes.codec.irp> Encode: %v", err)
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Return the pulses
+	return pulses, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
+// decode matches the start of the received levels against a frame with
+// the nominal timings. Otherwise the timings for each calibrated device are
+// tried, and the frame is only decoded when it is for the same device
+func (this *codec) decode(frame []atom) (map[string]uint64, int, status) {
+	params, consumed, status := this.protocol.decode(frame, this.levels, this.timing)
+	if status == STATUS_OK {
+		return params, consumed, status
+	}
+	for _, device := range this.timing.Devices() {
+		timing := this.timing.Device(device)
+		if timing == nil {
+			continue
+		}
+		params_, consumed_, status_ := this.protocol.decode(frame, this.levels, timing)
+		if status_ == STATUS_INCOMPLETE {
+			status = STATUS_INCOMPLETE
+		} else if status_ != STATUS_OK {
+			continue
+		} else if len(params_) == 0 && this.last_value != nil {
+			// A repeat frame without parameters is for the previous device
+			if _, device_ := this.codeForParams(this.last_value); device_ == device {
+				return params_, consumed_, status_
+			}
+		} else if _, device_ := this.codeForParams(params_); device_ == device {
+			return params_, consumed_, status_
+		}
+	}
+	return params, consumed, status
+}
+
+// codeForParams returns the scancode and device for decoded parameters. The
+// device parameters are packed in order of declaration, first parameter
+// in the most significant bits
+func (this *codec) codeForParams(params map[string]uint64) (uint32, uint32) {
+	device := uint32(0)
+	for _, param := range this.protocol.deviceParams() {
+		device = device<<param.width() | uint32(params[param.Name])
+	}
+	return uint32(params[PARAM_SCANCODE]), device
+}
+
+// paramsForCode returns parameters for a scancode and device
+func (this *codec) paramsForCode(scancode, device uint32) (map[string]uint64, error) {
+	params := make(map[string]uint64, len(this.protocol.Params))
+	device_params := this.protocol.deviceParams()
+	for i := len(device_params) - 1; i >= 0; i-- {
+		param := device_params[i]
+		params[param.Name] = uint64(device & (1<<param.width() - 1))
+		device >>= param.width()
+	}
+	if device != 0 {
+		return nil, fmt.Errorf("Invalid device parameter")
+	}
+	params[PARAM_SCANCODE] = uint64(scancode)
+	for name, value := range this.persistent {
+		params[name] = value
+	}
+
+	// Check parameter ranges
+	for _, param := range this.protocol.Params {
+		if value := params[param.Name]; value < uint64(param.Min) || value > uint64(param.Max) {
+			return nil, fmt.Errorf("Invalid value 0x%X for parameter %v", value, param.Name)
+		}
+	}
+
+	// Return success
+	return params, nil
+}
+
+func equalParams(a, b map[string]uint64) bool {
+	if a == nil || b == nil || len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if value, exists := b[k]; exists == false || value != v {
+			return false
+		}
+	}
+	return true
+}
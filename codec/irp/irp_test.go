@@ -0,0 +1,154 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/irp"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Repeats with a repeat code
+	IRP_NEC = "{38k,564}<1,-1|1,-3>(16,-8,D:8,F:8,1,^108m,(16,-4,1,^108m)*)[D:0..255,F:0..255]"
+	// Repeats the frame, most significant bit first
+	IRP_MITSUBISHI = "{32.6k,300,msb}<1,-3|1,-7>(D:8,F:8,1,-80)*[D:0..255,F:0..127]"
+	// Three device parameters, two bits per symbol
+	IRP_NOKIA32 = "{36k,msb}<164,-276|164,-445|164,-614|164,-783>(412,-276,D:8,S:8,T:1,X:7,F:8,164,^100m)*[D:0..255,S:0..255,F:0..255,T@:0..1=0,X:0..127]"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		irp              string
+		device, scancode uint32
+		repeats          uint
+	}{
+		{IRP_NEC, 0x00, 0x00, 0},
+		{IRP_NEC, 0xFF, 0xFF, 0},
+		{IRP_NEC, 0x2A, 0xC3, 1},
+		{IRP_NEC, 0x2A, 0xC3, 3},
+		{IRP_MITSUBISHI, 0x00, 0x00, 0},
+		{IRP_MITSUBISHI, 0xFF, 0x7F, 0},
+		{IRP_MITSUBISHI, 0x47, 0x15, 1},
+		{IRP_MITSUBISHI, 0x47, 0x15, 3},
+		{IRP_NOKIA32, 0x0000, 0x00, 0},
+		{IRP_NOKIA32, 0x7FFFFF, 0xFF, 0},
+		{IRP_NOKIA32, 0x1234, 0x56, 1},
+		{IRP_NOKIA32, 0x1234, 0x56, 3},
+	}
+	for _, test := range tests {
+		codec := openCodec(t, test.irp)
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("%v device=0x%X scancode=0x%X: %v", test.irp, test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, codec.Type(), remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	tests := []struct {
+		irp              string
+		device, scancode uint32
+	}{
+		{IRP_NEC, 0x100, 0x00},
+		{IRP_NEC, 0x00, 0x100},
+		{IRP_MITSUBISHI, 0x00, 0x80},
+		{IRP_NOKIA32, 0x800000, 0x00},
+		{IRP_NOKIA32, 0x0000, 0x100},
+	}
+	for _, test := range tests {
+		codec := openCodec(t, test.irp)
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("%v device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.irp, test.device, test.scancode, err)
+		}
+		codec.Close()
+	}
+}
+
+func TestStaleRepeat(t *testing.T) {
+	codec := openCodec(t, IRP_NEC)
+	defer codec.Close()
+
+	// Repeat frames are ignored after a timeout or a gap longer than the
+	// repeat duration
+	frame, err := codec.Encode(0x2A, 0xC3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pulses, err := codec.Encode(0x2A, 0xC3, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repeat := pulses[len(frame)+1:]
+	remotestest.CheckEvents(t, codec.Type(), remotestest.Decode(t, codec, pulses), 0x2A, 0xC3, 1)
+	stale := append(append(append([]uint32{}, frame...), 5000000), repeat...)
+	remotestest.CheckEvents(t, codec.Type(), remotestest.Decode(t, codec, stale), 0x2A, 0xC3, 0)
+	if evts, err := codec.Decode(append(remotes.PulseEvents(frame, 0), remotes.PulseEvents(repeat, 0)...)); err != nil {
+		t.Fatal(err)
+	} else {
+		remotestest.CheckEvents(t, codec.Type(), evts, 0x2A, 0xC3, 0)
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	codec := openCodec(t, IRP_NEC)
+	defer codec.Close()
+
+	// A remote which is 50% slower than the nominal values is only decoded
+	// once it's calibrated, and the calibration doesn't affect another remote
+	slow, err := codec.Encode(0x2A, 0xC3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := codec.Encode(0x15, 0xC3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evts := remotestest.Decode(t, codec, remotestest.Scale(slow, 1.5)); len(evts) != 0 {
+		t.Errorf("Expected no events before calibration, got %v", evts)
+	}
+	if err := codec.(remotes.CalibratedCodec).Calibrate(0x2A, &remotes.Calibration{Pulse: 1.5, Space: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	remotestest.CheckEvents(t, codec.Type(), remotestest.Decode(t, codec, remotestest.Scale(slow, 1.5)), 0x2A, 0xC3, 0)
+	remotestest.CheckEvents(t, codec.Type(), remotestest.Decode(t, codec, slow), 0x2A, 0xC3, 0)
+	remotestest.CheckEvents(t, codec.Type(), remotestest.Decode(t, codec, other), 0x15, 0xC3, 0)
+	if evts := remotestest.Decode(t, codec, remotestest.Scale(other, 1.5)); len(evts) != 0 {
+		t.Errorf("Expected no events for another remote, got %v", evts)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openCodec(t *testing.T, notation string) remotes.Codec {
+	t.Helper()
+	codec_type, err := remotes.NamedCodecType("irp_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	protocol, err := irp.ParseProtocol(notation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return remotestest.OpenCodec(t, irp.Codec{Type: codec_type, Protocol: protocol})
+}
@@ -0,0 +1,568 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Protocol is a parsed IRP protocol description
+type Protocol struct {
+	Frequency uint32      // Carrier frequency in Hz, or zero if not specified
+	DutyCycle uint32      // Duty cycle in percent, or zero if not specified
+	Unit      float64     // Unit duration in microseconds
+	MSB       bool        // Bitfields are sent most significant bit first
+	Bits      [][]float64 // Durations for each symbol in microseconds, negative for gaps
+	Params    []*Param    // Parameters, in order of declaration
+
+	stream *stream
+	intro  []atom
+	repeat []atom
+}
+
+// Param is a parameter declared in the protocol description
+type Param struct {
+	Name       string
+	Persistent bool // Persistent parameters (such as toggles) are maintained by the sender
+	Min, Max   uint32
+	Default    expr
+}
+
+type stream struct {
+	items []interface{} // *duration, *extent, *bitfield or *stream
+	min   uint          // Minimum number of times the stream is sent
+	max   uint          // Maximum number of times the stream is sent, or REPEAT_INFINITE
+}
+
+type duration struct {
+	value float64 // Microseconds, negative for a gap
+}
+
+type extent struct {
+	value float64 // Microseconds from the start of the stream
+}
+
+type bitfield struct {
+	complement bool
+	value      expr
+	width      uint
+	offset     uint
+}
+
+type token struct {
+	kind     rune // 'n' for number, 'a' for name, otherwise punctuation
+	value    string
+	adjacent bool // Token follows the previous token without whitespace
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	unit   float64
+	freq   float64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	REPEAT_INFINITE = ^uint(0)
+	DEFAULT_UNIT    = 1.0
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSE
+
+// ParseProtocol parses an IRP protocol description of the form
+// {generalspec}<bitspec>(irstream)[parameterspecs]
+func ParseProtocol(irp string) (*Protocol, error) {
+	tokens, err := tokenize(irp)
+	if err != nil {
+		return nil, err
+	}
+	this := &parser{tokens: tokens, unit: DEFAULT_UNIT}
+	protocol := new(Protocol)
+	if err := this.parseGeneralSpec(protocol); err != nil {
+		return nil, err
+	}
+	if err := this.parseBitSpec(protocol); err != nil {
+		return nil, err
+	}
+	if stream, err := this.parseStream(); err != nil {
+		return nil, err
+	} else {
+		protocol.stream = stream
+	}
+	if this.peek('[') {
+		if err := this.parseParamSpecs(protocol); err != nil {
+			return nil, err
+		}
+	}
+	if this.pos != len(this.tokens) {
+		return nil, this.errorf("Unexpected %v", this.tokens[this.pos].value)
+	}
+
+	// Check parameters and flatten the stream into intro and repeat frames
+	if err := protocol.check(); err != nil {
+		return nil, err
+	}
+	protocol.intro, protocol.repeat = protocol.flatten()
+
+	// Return success
+	return protocol, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *Protocol) String() string {
+	params := make([]string, len(this.Params))
+	for i, param := range this.Params {
+		params[i] = param.Name
+	}
+	return fmt.Sprintf("<remotes.irp.Protocol>{ frequency=%v unit=%v msb=%v symbols=%v params=%v }", this.Frequency, this.Unit, this.MSB, len(this.Bits), strings.Join(params, ","))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSER
+
+func (this *parser) parseGeneralSpec(protocol *Protocol) error {
+	if err := this.expect('{'); err != nil {
+		return err
+	}
+	for this.peek('}') == false {
+		if this.peek('a') {
+			switch name := this.next().value; name {
+			case "msb":
+				protocol.MSB = true
+			case "lsb":
+				protocol.MSB = false
+			default:
+				return this.errorf("Unexpected %v in general spec", name)
+			}
+		} else if value, err := this.parseNumber(); err != nil {
+			return err
+		} else if this.peekAdjacent('a') && this.tokens[this.pos].value == "k" {
+			this.next()
+			this.freq = value * 1000
+			protocol.Frequency = uint32(this.freq)
+		} else if this.peekAdjacent('%') {
+			this.next()
+			protocol.DutyCycle = uint32(value)
+		} else if this.peekAdjacent('a') {
+			// Unit can be in microseconds or periods
+			if unit, err := this.parseUnit(value); err != nil {
+				return err
+			} else {
+				this.unit = unit
+			}
+		} else {
+			this.unit = value
+		}
+		if this.peek(',') {
+			this.next()
+		}
+	}
+	protocol.Unit = this.unit
+	return this.expect('}')
+}
+
+func (this *parser) parseBitSpec(protocol *Protocol) error {
+	if err := this.expect('<'); err != nil {
+		return err
+	}
+	protocol.Bits = make([][]float64, 0, 4)
+	for {
+		symbol := make([]float64, 0, 2)
+		for {
+			if value, err := this.parseDuration(); err != nil {
+				return err
+			} else {
+				symbol = append(symbol, value)
+			}
+			if this.peek(',') == false {
+				break
+			}
+			this.next()
+		}
+		protocol.Bits = append(protocol.Bits, symbol)
+		if this.peek('|') == false {
+			break
+		}
+		this.next()
+	}
+	if err := this.expect('>'); err != nil {
+		return err
+	}
+
+	// The number of symbols needs to be a power of two
+	if n := len(protocol.Bits); n < 2 || n&(n-1) != 0 {
+		return this.errorf("Invalid number of symbols in bit spec: %v", n)
+	}
+
+	// Success
+	return nil
+}
+
+func (this *parser) parseStream() (*stream, error) {
+	if err := this.expect('('); err != nil {
+		return nil, err
+	}
+	stream := &stream{items: make([]interface{}, 0), min: 1, max: 1}
+	for {
+		if item, err := this.parseItem(); err != nil {
+			return nil, err
+		} else {
+			stream.items = append(stream.items, item)
+		}
+		if this.peek(',') == false {
+			break
+		}
+		this.next()
+	}
+	if err := this.expect(')'); err != nil {
+		return nil, err
+	}
+
+	// Repeat marker
+	if this.peek('*') {
+		this.next()
+		stream.min, stream.max = 0, REPEAT_INFINITE
+	} else if this.peek('+') {
+		this.next()
+		stream.min, stream.max = 1, REPEAT_INFINITE
+	} else if this.peek('n') {
+		if value, err := this.parseNumber(); err != nil {
+			return nil, err
+		} else {
+			stream.min, stream.max = uint(value), uint(value)
+		}
+		if this.peek('+') {
+			this.next()
+			stream.max = REPEAT_INFINITE
+		}
+	}
+
+	// Success
+	return stream, nil
+}
+
+func (this *parser) parseItem() (interface{}, error) {
+	switch {
+	case this.peek('^'):
+		this.next()
+		if value, err := this.parseDuration(); err != nil {
+			return nil, err
+		} else {
+			return &extent{value}, nil
+		}
+	case this.peek('-'):
+		if value, err := this.parseDuration(); err != nil {
+			return nil, err
+		} else {
+			return &duration{value}, nil
+		}
+	case this.peek('~'), this.peek('a'):
+		return this.parseBitfield()
+	case this.peek('n'):
+		// A number followed by a colon is a bitfield, otherwise a duration
+		if this.pos+1 < len(this.tokens) && this.tokens[this.pos+1].kind == ':' {
+			return this.parseBitfield()
+		} else if value, err := this.parseDuration(); err != nil {
+			return nil, err
+		} else {
+			return &duration{value}, nil
+		}
+	case this.peek('('):
+		// Either a bitfield with an expression, or a stream
+		pos := this.pos
+		if bitfield, err := this.parseBitfield(); err == nil {
+			return bitfield, nil
+		}
+		this.pos = pos
+		return this.parseStream()
+	default:
+		return nil, this.errorf("Unexpected token in stream")
+	}
+}
+
+func (this *parser) parseBitfield() (*bitfield, error) {
+	bitfield := new(bitfield)
+	if this.peek('~') {
+		this.next()
+		bitfield.complement = true
+	}
+	if value, err := this.parsePrimary(); err != nil {
+		return nil, err
+	} else {
+		bitfield.value = value
+	}
+	if err := this.expect(':'); err != nil {
+		return nil, err
+	}
+	if width, err := this.parseNumber(); err != nil {
+		return nil, err
+	} else if width < 1 || width > 64 {
+		return nil, this.errorf("Invalid bitfield width: %v", width)
+	} else {
+		bitfield.width = uint(width)
+	}
+	if this.peek(':') {
+		this.next()
+		if offset, err := this.parseNumber(); err != nil {
+			return nil, err
+		} else {
+			bitfield.offset = uint(offset)
+		}
+	}
+	return bitfield, nil
+}
+
+func (this *parser) parseParamSpecs(protocol *Protocol) error {
+	if err := this.expect('['); err != nil {
+		return err
+	}
+	protocol.Params = make([]*Param, 0, 4)
+	for {
+		param := new(Param)
+		if this.peek('a') == false {
+			return this.errorf("Expected parameter name")
+		}
+		param.Name = this.next().value
+		if this.peek('@') {
+			this.next()
+			param.Persistent = true
+		}
+		if err := this.expect(':'); err != nil {
+			return err
+		}
+		if min, err := this.parseNumber(); err != nil {
+			return err
+		} else if err := this.expect('.'); err != nil {
+			return err
+		} else if err := this.expect('.'); err != nil {
+			return err
+		} else if max, err := this.parseNumber(); err != nil {
+			return err
+		} else {
+			param.Min, param.Max = uint32(min), uint32(max)
+		}
+		if this.peek('=') {
+			this.next()
+			if value, err := this.parseExpr(); err != nil {
+				return err
+			} else {
+				param.Default = value
+			}
+		}
+		protocol.Params = append(protocol.Params, param)
+		if this.peek(',') == false {
+			break
+		}
+		this.next()
+	}
+	return this.expect(']')
+}
+
+// parseDuration returns a duration in microseconds, which is negative for a gap
+func (this *parser) parseDuration() (float64, error) {
+	sign := 1.0
+	if this.peek('-') {
+		this.next()
+		sign = -1.0
+	}
+	if value, err := this.parseNumber(); err != nil {
+		return 0, err
+	} else if this.peekAdjacent('a') {
+		value, err := this.parseUnit(value)
+		return sign * value, err
+	} else {
+		return sign * value * this.unit, nil
+	}
+}
+
+// parseUnit returns a value in microseconds for a value with a unit
+// suffix of m (milliseconds), u (microseconds) or p (carrier periods)
+func (this *parser) parseUnit(value float64) (float64, error) {
+	switch unit := this.next().value; unit {
+	case "m":
+		return value * 1000, nil
+	case "u":
+		return value, nil
+	case "p":
+		if this.freq == 0 {
+			return 0, this.errorf("Unit in periods requires a frequency")
+		}
+		return value * 1e6 / this.freq, nil
+	default:
+		return 0, this.errorf("Invalid unit: %v", unit)
+	}
+}
+
+func (this *parser) parseNumber() (float64, error) {
+	if this.peek('n') == false {
+		return 0, this.errorf("Expected number")
+	} else if value, err := strconv.ParseFloat(this.next().value, 64); err != nil {
+		return 0, this.errorf("Invalid number: %v", err)
+	} else {
+		return value, nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TOKENS
+
+func tokenize(irp string) ([]token, error) {
+	tokens := make([]token, 0, len(irp))
+	adjacent := false
+	runes := []rune(irp)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			adjacent = false
+			i++
+			continue
+		case unicode.IsDigit(r):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || (runes[j] == '.' && j+1 < len(runes) && unicode.IsDigit(runes[j+1]))) {
+				j++
+			}
+			tokens = append(tokens, token{'n', string(runes[i:j]), adjacent})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, token{'a', string(runes[i:j]), adjacent})
+			i = j
+		case strings.ContainsRune("{}<>|(),:-~^*+[]=@%./&!", r):
+			tokens = append(tokens, token{r, string(r), adjacent})
+			i++
+		default:
+			return nil, fmt.Errorf("Invalid character '%c' in IRP", r)
+		}
+		adjacent = true
+	}
+	return tokens, nil
+}
+
+func (this *parser) peek(kind rune) bool {
+	return this.pos < len(this.tokens) && this.tokens[this.pos].kind == kind
+}
+
+func (this *parser) peekAdjacent(kind rune) bool {
+	return this.peek(kind) && this.tokens[this.pos].adjacent
+}
+
+func (this *parser) next() token {
+	token := this.tokens[this.pos]
+	this.pos++
+	return token
+}
+
+func (this *parser) expect(kind rune) error {
+	if this.peek(kind) == false {
+		return this.errorf("Expected '%c'", kind)
+	}
+	this.next()
+	return nil
+}
+
+func (this *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("IRP: "+format+" (at token %v)", append(args, this.pos)...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// check ensures all named parameters are declared, and that the
+// device fits into 32 bits
+func (this *Protocol) check() error {
+	declared := make(map[string]bool, len(this.Params))
+	for _, param := range this.Params {
+		declared[param.Name] = true
+	}
+	if declared[PARAM_SCANCODE] == false {
+		return fmt.Errorf("IRP: Missing parameter %v", PARAM_SCANCODE)
+	}
+	for _, name := range this.stream.names() {
+		if declared[name] == false {
+			return fmt.Errorf("IRP: Undeclared parameter %v", name)
+		}
+	}
+	if this.deviceWidth() > 32 {
+		return fmt.Errorf("IRP: Device parameters exceed 32 bits")
+	}
+	return nil
+}
+
+// flatten returns the items for the first frame and for the frames sent
+// when a key is held down. Where the whole stream repeats, these are the same
+func (this *Protocol) flatten() ([]atom, []atom) {
+	if this.stream.max > 1 {
+		frame := flattenItems(this.stream.items)
+		return frame, frame
+	}
+	intro := make([]interface{}, 0, len(this.stream.items))
+	for _, item := range this.stream.items {
+		if stream, ok := item.(*stream); ok && stream.max == REPEAT_INFINITE {
+			return flattenItems(intro), flattenItems(stream.items)
+		} else {
+			intro = append(intro, item)
+		}
+	}
+	return flattenItems(intro), nil
+}
+
+// deviceParams returns the parameters which make up the device, which are all
+// parameters except the scancode and persistent parameters
+func (this *Protocol) deviceParams() []*Param {
+	params := make([]*Param, 0, len(this.Params))
+	for _, param := range this.Params {
+		if param.Name != PARAM_SCANCODE && param.Persistent == false {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+func (this *Protocol) deviceWidth() uint {
+	width := uint(0)
+	for _, param := range this.deviceParams() {
+		width += param.width()
+	}
+	return width
+}
+
+func (this *Param) width() uint {
+	return uint(bits.Len32(this.Max))
+}
+
+func (this *stream) names() []string {
+	names := make([]string, 0)
+	for _, item := range this.items {
+		switch item := item.(type) {
+		case *bitfield:
+			names = append(names, item.value.names()...)
+		case *stream:
+			names = append(names, item.names()...)
+		}
+	}
+	return names
+}
@@ -0,0 +1,78 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package irp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Definition is a protocol name and the protocol which implements it
+type Definition struct {
+	Name     string
+	Protocol *Protocol
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Extension for protocol files
+	EXT_PROTOCOLS = ".irp"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ReadProtocols returns protocol definitions, one per line. Each line is
+// a protocol name followed by IRP notation. Blank lines and lines beginning
+// with # are ignored
+func ReadProtocols(r io.Reader) ([]*Definition, error) {
+	definitions := make([]*Definition, 0)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Line %v: Expected protocol name and IRP", line)
+		}
+		if protocol, err := ParseProtocol(fields[1]); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		} else {
+			definitions = append(definitions, &Definition{fields[0], protocol})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// readProtocolFile returns the protocol definitions in a file
+func readProtocolFile(path string) ([]*Definition, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return ReadProtocols(fh)
+}
@@ -0,0 +1,38 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package jvc
+
+// Reference:
+//   https://www.sbprojects.net/knowledge/ir/jvc.php
+//
+// The JVC IR transmission protocol uses pulse distance encoding of the
+// message bits, at a carrier frequency of 38kHz. Each pulse burst is 525µs
+// in length. Logical bits are transmitted as follows:
+//
+//   * Logical '0' – a 525µs pulse burst followed by a 525µs space
+//   * Logical '1' – a 525µs pulse burst followed by a 1.575ms space
+//
+// When a key is pressed on the remote controller, the message transmitted
+// consists of the following, in order:
+//
+//  * An 8.4ms leading pulse burst
+//  * A 4.2ms space
+//  * The 8-bit address for the receiving device
+//  * The 8-bit command
+//  * A final 525µs pulse burst to signify the end of message transmission.
+//
+// The address and command are sent least significant bit first.
+//
+// REPEAT CODES
+//
+// If the key on the remote controller is kept depressed, the message is
+// repeated without the leading pulse burst and space, every 55ms or so. This
+// means a receiver has to recognize a frame which starts with a data bit
+// immediately after the end of the previous frame.
@@ -1,13 +1,13 @@
 /*
 	Go Language Raspberry Pi Interface
-    (c) Copyright David Thorpe 2016-2018
+    (c) Copyright David Thorpe 2016-2019
     All Rights Reserved
 
 	Documentation http://djthorpe.github.io/gopi/
 	For Licensing and Usage information, please see LICENSE.md
 */
 
-package panasonic
+package jvc
 
 import (
 	// Frameworks
@@ -18,14 +18,19 @@ import (
 // INIT
 
 func init() {
-	// Register remotes/panasonic
+	// Register remotes/jvc
 	gopi.RegisterModule(gopi.Module{
-		Name:     "remotes/panasonic",
+		Name:     "remotes/jvc",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("jvc.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("jvc.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
@@ -0,0 +1,401 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package jvc
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// JVC Configuration
+type Codec struct {
+	LIRC      gopi.LIRC
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type codec struct {
+	log      gopi.Logger
+	lirc     gopi.LIRC
+	carriers *remotes.Carriers
+	timing   *remotes.Timing
+	state    state
+	value    uint32
+	length   uint
+	repeat   bool
+	decoding bool
+	elapsed  time.Duration
+
+	event.Publisher
+	event.Tasks
+}
+
+type state uint32
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// state
+	STATE_EXPECT_HEADER_PULSE state = iota
+	STATE_EXPECT_HEADER_SPACE
+	STATE_EXPECT_PULSE
+	STATE_EXPECT_SPACE
+	STATE_EXPECT_TRAIL_PULSE
+	STATE_EXPECT_REPEAT_SPACE
+	STATE_EXPECT_REPEAT_PULSE
+)
+
+const (
+	TOLERANCE         = 35    // 35% tolerance on values
+	CARRIER_FREQUENCY = 38000 // 38kHz carrier
+	DUTY_CYCLE        = 33    // 33% duty cycle
+	BIT_LENGTH        = 16    // 8 device bits and 8 scancode bits
+	TX_DURATION       = 55000 // 55ms between the start of each transmission
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	HEADER_PULSE = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 8400, TOLERANCE) // 8.4ms
+	HEADER_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 4200, TOLERANCE) // 4.2ms
+	BIT_PULSE    = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 525, TOLERANCE)
+	ONE_SPACE    = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 1575, TOLERANCE)
+	ZERO_SPACE   = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 525, TOLERANCE)
+	TRAIL_PULSE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 525, TOLERANCE)
+	REPEAT_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 25000, 80) // Between 5ms and 45ms depending on the bits sent
+)
+
+var (
+	timestamp = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.jvc>Open{ lirc=%v }", config.LIRC)
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
+
+	// Reset state
+	this.Reset()
+
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *codec) Close() error {
+	this.log.Debug("<remotes.codec.jvc>Close>{}")
+
+	// Remove subscribers to this codec
+	this.Publisher.Close()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.lirc = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	return fmt.Sprintf("<remotes.Codec.JVC>{}")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *codec) Type() remotes.CodecType {
+	return remotes.CODEC_JVC
+}
+
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
+func (this *codec) Reset() {
+	this.state = STATE_EXPECT_HEADER_PULSE
+	this.value = 0
+	this.length = 0
+	this.repeat = false
+	this.timing.Reset()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *codec) Emit(value uint32, repeat bool) {
+	if scancode, device := codeForValue(value); this.timing.Accepts(device) {
+		this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, repeat))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *codec) PulseTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	events := this.lirc.Subscribe()
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt != nil {
+				this.receive(evt.(gopi.LIRCEvent))
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.lirc.Unsubscribe(events)
+
+	// Success
+	return nil
+}
+
+func (this *codec) receive(evt gopi.LIRCEvent) {
+	this.log.Debug2("<remotes.codec.jvc>Receive{ state=%v evt=%v }", this.state, evt)
+	switch this.state {
+	case STATE_EXPECT_HEADER_PULSE:
+		if this.timing.Matches(HEADER_PULSE, evt) {
+			this.state = STATE_EXPECT_HEADER_SPACE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_HEADER_SPACE:
+		if this.timing.Matches(HEADER_SPACE, evt) {
+			this.state = STATE_EXPECT_PULSE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_PULSE:
+		if this.timing.Matches(BIT_PULSE, evt) {
+			this.state = STATE_EXPECT_SPACE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_SPACE:
+		// Register a zero or one, least significant bit first
+		if this.timing.Matches(ZERO_SPACE, evt) {
+			this.length = this.length + 1
+		} else if this.timing.Matches(ONE_SPACE, evt) {
+			this.value |= 1 << this.length
+			this.length = this.length + 1
+		} else {
+			this.Reset()
+			return
+		}
+
+		// Advance state to expect the trailing pulse
+		if this.length == BIT_LENGTH {
+			this.state = STATE_EXPECT_TRAIL_PULSE
+		} else {
+			this.state = STATE_EXPECT_PULSE
+		}
+	case STATE_EXPECT_TRAIL_PULSE:
+		if this.timing.Matches(TRAIL_PULSE, evt) {
+			this.Emit(this.value, this.repeat)
+			this.state = STATE_EXPECT_REPEAT_SPACE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_REPEAT_SPACE:
+		if this.timing.Matches(REPEAT_SPACE, evt) {
+			this.state = STATE_EXPECT_REPEAT_PULSE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_REPEAT_PULSE:
+		if this.timing.Matches(HEADER_PULSE, evt) {
+			// A new frame with a header, which is a new key press
+			this.Reset()
+			this.state = STATE_EXPECT_HEADER_SPACE
+		} else if this.timing.Matches(BIT_PULSE, evt) {
+			// A headerless frame, which is a repeat of the key press
+			this.value = 0
+			this.length = 0
+			this.repeat = true
+			this.state = STATE_EXPECT_SPACE
+		} else {
+			this.Reset()
+		}
+	default:
+		this.Reset()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SENDING
+
+func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
+	this.log.Debug2("<remotes.codec.jvc>Send{ device=0x%08X scancode=0x%08X repeats=%v }", device, scancode, repeats)
+
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.jvc> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	// Ensure the device is 8 bits and the scancode is 8 bits
+	if uint32(uint8(device)) != device {
+		this.log.Error("<remotes.codec.jvc> Encode: Invalid device parameter")
+		return nil, gopi.ErrBadParameter
+	}
+	if uint32(uint8(scancode)) != scancode {
+		this.log.Error("<remotes.codec.jvc> Encode: Invalid scancode parameter")
+		return nil, gopi.ErrBadParameter
+	}
+
+	// The header is only sent on the first frame
+	pulses := make([]uint32, 0, 100)
+	for i := uint(0); i < (repeats + 1); i++ {
+		length := uint32(0)
+		if i == 0 {
+			pulses = append(pulses, HEADER_PULSE.Value, HEADER_SPACE.Value)
+			length += HEADER_PULSE.Value + HEADER_SPACE.Value
+		}
+
+		// Emit the device and scancode, followed by the trail pulse
+		frame := make([]uint32, 0, BIT_LENGTH*2+1)
+		frame = this.sendbyte(frame, uint8(device))
+		frame = this.sendbyte(frame, uint8(scancode))
+		frame = append(frame, TRAIL_PULSE.Value)
+		for _, value := range frame {
+			length += value
+		}
+		pulses = append(pulses, frame...)
+
+		// If repeats then send the space up to the start of the next frame
+		if i < repeats {
+			pulses = append(pulses, TX_DURATION-length)
+		}
+	}
+
+	// Return the pulses
+	return pulses, nil
+}
+
+func (this *codec) sendbyte(pulses []uint32, value uint8) []uint32 {
+	for i := 0; i < 8; i++ {
+		pulses = append(pulses, BIT_PULSE.Value)
+		if value&0x01 == 0 { // Send zero
+			pulses = append(pulses, ZERO_SPACE.Value)
+		} else {
+			// Send one
+			pulses = append(pulses, ONE_SPACE.Value)
+		}
+		value >>= 1
+	}
+	return pulses
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
+// codeForValue returns the scancode and device, where the device is
+// the first byte received and the scancode is the second byte
+func codeForValue(value uint32) (uint32, uint32) {
+	return (value & 0xFF00) >> 8, value & 0x00FF
+}
+
+func (s state) String() string {
+	switch s {
+	case STATE_EXPECT_HEADER_PULSE:
+		return "STATE_EXPECT_HEADER_PULSE"
+	case STATE_EXPECT_HEADER_SPACE:
+		return "STATE_EXPECT_HEADER_SPACE"
+	case STATE_EXPECT_PULSE:
+		return "STATE_EXPECT_PULSE"
+	case STATE_EXPECT_SPACE:
+		return "STATE_EXPECT_SPACE"
+	case STATE_EXPECT_TRAIL_PULSE:
+		return "STATE_EXPECT_TRAIL_PULSE"
+	case STATE_EXPECT_REPEAT_SPACE:
+		return "STATE_EXPECT_REPEAT_SPACE"
+	case STATE_EXPECT_REPEAT_PULSE:
+		return "STATE_EXPECT_REPEAT_PULSE"
+	default:
+		return "[?? Invalid state]"
+	}
+}
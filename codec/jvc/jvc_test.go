@@ -0,0 +1,115 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package jvc_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/jvc"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	FRAME_PERIOD = 55000 // Each frame starts 55ms after the previous frame
+)
+
+var (
+	// A 525us pulse for each bit, least significant bit first, followed by
+	// a 525us space for a zero or a 1575us space for a one
+	JVC_BITS = remotestest.PulseDistance{Pulse: 525, Zero: 525, One: 1575}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	codec := remotestest.OpenCodec(t, jvc.Codec{})
+	defer codec.Close()
+
+	tests := []struct {
+		device, scancode uint32
+		repeats          uint
+	}{
+		{0x00, 0x00, 0},
+		{0xFF, 0xFF, 0},
+		{0x03, 0x17, 0},
+		{0x03, 0x17, 1},
+		{0x03, 0x17, 3},
+	}
+	for _, test := range tests {
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("device=0x%X scancode=0x%X: %v", test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, remotes.CODEC_JVC, remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	codec := remotestest.OpenCodec(t, jvc.Codec{})
+	defer codec.Close()
+
+	tests := []struct {
+		device, scancode uint32
+	}{
+		{0x100, 0x00},
+		{0x00, 0x100},
+	}
+	for _, test := range tests {
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.device, test.scancode, err)
+		}
+	}
+}
+
+func TestEncodePulses(t *testing.T) {
+	codec := remotestest.OpenCodec(t, jvc.Codec{})
+	defer codec.Close()
+
+	// An 8.4ms header pulse and 4.2ms space, then the device and scancode
+	// least significant bit first and a trail pulse. Repeats have no header,
+	// and each frame starts 55ms after the start of the previous frame
+	frame := append(append(JVC_BITS.Pulses(0x03, 8), JVC_BITS.Pulses(0x17, 8)...), 525)
+	header := append([]uint32{8400, 4200}, frame...)
+	expected := remotestest.Frames(FRAME_PERIOD, header, frame, frame)
+	if pulses, err := codec.Encode(0x03, 0x17, 2); err != nil {
+		t.Fatal(err)
+	} else if remotestest.Equals(pulses, expected) == false {
+		t.Errorf("Expected %v, got %v", expected, pulses)
+	}
+}
+
+func TestHeaderlessRepeat(t *testing.T) {
+	codec := remotestest.OpenCodec(t, jvc.Codec{})
+	defer codec.Close()
+
+	// Frames without a header are repeats, but only after a frame with a header
+	frame := append(append(JVC_BITS.Pulses(0x03, 8), JVC_BITS.Pulses(0x17, 8)...), 525)
+	header := append([]uint32{8400, 4200}, frame...)
+	remotestest.CheckEvents(t, remotes.CODEC_JVC, remotestest.Decode(t, codec, remotestest.Frames(FRAME_PERIOD, header, frame, frame)), 0x03, 0x17, 2)
+	if evts := remotestest.Decode(t, codec, remotestest.Frames(FRAME_PERIOD, frame, frame)); len(evts) != 0 {
+		t.Errorf("Expected no events, got %v", evts)
+	}
+}
+
+func TestCarrier(t *testing.T) {
+	codec := remotestest.OpenCodec(t, jvc.Codec{})
+	defer codec.Close()
+
+	if carrier := codec.Carrier(0); carrier.Frequency != 38000 || carrier.DutyCycle != 33 {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package kaseikyo
+
+/*
+
+Not sure there's a source for Panasonic but seems to be:
+http://www.remotecentral.com/cgi-bin/mboard/rc-pronto/thread.cgi?26152
+
+The Kaseikyo protocol is shared by Panasonic, Denon, JVC, Mitsubishi and
+Sharp (amongst others) and only differs in the vendor ID:
+https://www.mikrocontroller.net/articles/IRMP_-_english#KASEIKYO
+
+  * Header Pulse of 3.5ms, then space of 1.7ms
+  * A one bit:
+  *   450ns pulse, 1.3ms space
+  * A zero bit:
+  *   450ns pulse, 450ns space
+  * Trail pulse of 450ns, a repeat space is 75ms then repeat the code
+  *
+  * It's 48 bits long (6 bytes)
+  * <V1> <V2> <D1> <D2> <SC> <XOR D1|D2|SC>
+  * V1 V2 = Vendor ID
+  * D1 = Device, where the top four bits are the vendor parity
+  * D2 = Subdevice
+  * SC = Scancode
+  * XOR = Checksum (XOR of D1,D2 and SC)
+
+Each byte is received most significant bit first, so the vendor IDs are
+bit-reversed compared to the published values: Panasonic 0x2002 is 0x4004.
+The vendor parity is the XOR of the four nibbles of the vendor ID, which is
+zero for Panasonic and Denon.
+
+CODEC_PANASONIC only decodes the Panasonic vendor ID, and reports the device
+as D1 and D2. CODEC_KASEIKYO decodes all other vendor IDs, and reports the
+device as V1, V2, D1 and D2 so that the vendor can be recovered using the
+SplitDevice function.
+
+*/
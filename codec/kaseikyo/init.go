@@ -0,0 +1,59 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package kaseikyo
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register remotes/panasonic
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/panasonic",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("panasonic.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("panasonic.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_PANASONIC,
+				Vendor:    VENDOR_PANASONIC,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/kaseikyo
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/kaseikyo",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("kaseikyo.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("kaseikyo.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_KASEIKYO,
+				Vendor:    VENDOR_ANY,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+}
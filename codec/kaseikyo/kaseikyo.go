@@ -0,0 +1,468 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package kaseikyo
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Kaseikyo Configuration - Panasonic is Kaseikyo with VENDOR_PANASONIC,
+// and CODEC_KASEIKYO with VENDOR_ANY decodes all other vendors
+type Codec struct {
+	LIRC      gopi.LIRC
+	Type      remotes.CodecType
+	Vendor    uint16
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type codec struct {
+	log        gopi.Logger
+	lirc       gopi.LIRC
+	carriers   *remotes.Carriers
+	timing     *remotes.Timing
+	codec_type remotes.CodecType
+	vendor     uint16
+	state      state
+	value      uint64
+	length     uint
+	repeat     bool
+	decoding   bool
+	elapsed    time.Duration
+
+	event.Publisher
+	event.Tasks
+}
+
+type state uint32
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// state
+	STATE_EXPECT_HEADER_PULSE state = iota
+	STATE_EXPECT_HEADER_SPACE
+	STATE_EXPECT_PULSE
+	STATE_EXPECT_SPACE
+	STATE_EXPECT_TRAIL
+	STATE_EXPECT_REPEAT
+)
+
+const (
+	TOLERANCE         = 35    // 35% tolerance on values
+	CARRIER_FREQUENCY = 37000 // 37kHz carrier
+	DUTY_CYCLE        = 33    // 33% duty cycle
+	BIT_LENGTH        = 48
+)
+
+const (
+	// Vendor ID's as received, which are bit-reversed from the published values
+	VENDOR_ANY        uint16 = 0x0000
+	VENDOR_PANASONIC  uint16 = 0x4004 // 0x2002
+	VENDOR_DENON      uint16 = 0x2A4C // 0x3254
+	VENDOR_JVC        uint16 = 0xC080 // 0x0103
+	VENDOR_MITSUBISHI uint16 = 0xC4D3 // 0xCB23
+	VENDOR_SHARP      uint16 = 0x555A // 0x5AAA
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	HEADER_PULSE = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 3500, TOLERANCE)
+	HEADER_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 1700, TOLERANCE)
+	BIT_PULSE    = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 450, TOLERANCE)
+	ONE_SPACE    = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 1300, TOLERANCE)
+	ZERO_SPACE   = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 450, TOLERANCE)
+	TRAIL_PULSE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 450, TOLERANCE)
+	REPEAT_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 75000, TOLERANCE)
+)
+
+var (
+	timestamp = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.kaseikyo>Open{ lirc=%v type=%v vendor=0x%04X }", config.LIRC, config.Type, config.Vendor)
+
+	// Check codec type and vendor. CODEC_KASEIKYO decodes any vendor,
+	// other codecs are for a single vendor
+	switch config.Type {
+	case remotes.CODEC_KASEIKYO:
+		if config.Vendor != VENDOR_ANY {
+			return nil, gopi.ErrBadParameter
+		}
+	case remotes.CODEC_PANASONIC:
+		if config.Vendor != VENDOR_PANASONIC {
+			return nil, gopi.ErrBadParameter
+		}
+	default:
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
+	this.codec_type = config.Type
+	this.vendor = config.Vendor
+
+	// Reset state
+	this.Reset()
+
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *codec) Close() error {
+	this.log.Debug("<remotes.codec.kaseikyo>Close>{ type=%v }", this.codec_type)
+
+	// Remove subscribers to this codec
+	this.Publisher.Close()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.lirc = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	return fmt.Sprintf("<remotes.Codec.Kaseikyo>{ type=%v vendor=0x%04X }", this.codec_type, this.vendor)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *codec) Type() remotes.CodecType {
+	return this.codec_type
+}
+
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
+func (this *codec) Reset() {
+	this.state = STATE_EXPECT_HEADER_PULSE
+	this.value = 0
+	this.length = 0
+	this.repeat = false
+	this.timing.Reset()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *codec) Emit(value uint64, repeat bool) {
+	if scancode, device, err := codeForCodec(this.vendor, value); err != nil {
+		if err != gopi.ErrBadParameter {
+			this.log.Warn("Emit: %v", err)
+		}
+	} else if this.timing.Accepts(device) {
+		this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, repeat))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *codec) PulseTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	events := this.lirc.Subscribe()
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt != nil {
+				this.receive(evt.(gopi.LIRCEvent))
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.lirc.Unsubscribe(events)
+
+	// Success
+	return nil
+}
+
+func (this *codec) receive(evt gopi.LIRCEvent) {
+	this.log.Debug2("<remotes.codec.kaseikyo>Receive{ type=%v state=%v evt=%v }", this.codec_type, this.state, evt)
+	switch this.state {
+	case STATE_EXPECT_HEADER_PULSE:
+		if this.timing.Matches(HEADER_PULSE, evt) {
+			this.state = STATE_EXPECT_HEADER_SPACE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_HEADER_SPACE:
+		if this.timing.Matches(HEADER_SPACE, evt) {
+			this.state = STATE_EXPECT_PULSE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_PULSE:
+		if this.timing.Matches(BIT_PULSE, evt) {
+			this.state = STATE_EXPECT_SPACE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_SPACE:
+		// Register a zero or one
+		if this.timing.Matches(ZERO_SPACE, evt) {
+			this.value <<= 1
+			this.length = this.length + 1
+		} else if this.timing.Matches(ONE_SPACE, evt) {
+			this.value = this.value<<1 | 1
+			this.length = this.length + 1
+		} else {
+			this.Reset()
+			return
+		}
+
+		// Advance state if the correct length
+		if this.length == BIT_LENGTH {
+			this.state = STATE_EXPECT_TRAIL
+		} else {
+			this.state = STATE_EXPECT_PULSE
+		}
+	case STATE_EXPECT_TRAIL:
+		if this.timing.Matches(TRAIL_PULSE, evt) {
+			this.Emit(this.value, this.repeat)
+			this.state = STATE_EXPECT_REPEAT
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_REPEAT:
+		if this.timing.Matches(REPEAT_SPACE, evt) {
+			this.repeat = true
+			this.state = STATE_EXPECT_HEADER_PULSE
+			this.value = 0
+			this.length = 0
+		} else {
+			this.Reset()
+		}
+	default:
+		this.Reset()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), codec_type: this.codec_type, vendor: this.vendor, decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SENDING
+
+func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
+	this.log.Debug2("<remotes.codec.kaseikyo>Send{ type=%v device=0x%08X scancode=0x%08X repeats=%v }", this.codec_type, device, scancode, repeats)
+
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.kaseikyo> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+// Encode returns the pulses for a scancode without sending them
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	value, err := valueForCodec(this.vendor, device, scancode)
+	if err != nil {
+		this.log.Error("<remotes.codec.kaseikyo> Encode: %v", err)
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Header Pulse of 3.5ms, then space of 1.7ms, then the 48 bits
+	// and a trail pulse. The frame is repeated after a 75ms space
+	pulses := make([]uint32, 0, 100)
+	for i := uint(0); i < (repeats + 1); i++ {
+		pulses = append(pulses, HEADER_PULSE.Value, HEADER_SPACE.Value)
+		for j := uint(0); j < BIT_LENGTH; j++ {
+			pulses = append(pulses, BIT_PULSE.Value)
+			if value&(1<<(BIT_LENGTH-j-1)) == 0 {
+				// Send zero
+				pulses = append(pulses, ZERO_SPACE.Value)
+			} else {
+				// Send one
+				pulses = append(pulses, ONE_SPACE.Value)
+			}
+		}
+		pulses = append(pulses, TRAIL_PULSE.Value)
+
+		// If repeats then send repeat space
+		if i < repeats {
+			pulses = append(pulses, REPEAT_SPACE.Value)
+		}
+	}
+
+	// Return success
+	return pulses, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Device returns the device for CODEC_KASEIKYO from a vendor ID, device and
+// subdevice
+func Device(vendor uint16, device, subdevice uint8) uint32 {
+	return uint32(vendor)<<16 | uint32(device)<<8 | uint32(subdevice)
+}
+
+// SplitDevice returns the vendor ID, device and subdevice from a
+// CODEC_KASEIKYO device. The vendor ID is VENDOR_ANY for CODEC_PANASONIC
+func SplitDevice(device uint32) (uint16, uint8, uint8) {
+	return uint16(device >> 16), uint8(device >> 8), uint8(device)
+}
+
+// VendorParity returns the four-bit parity for a vendor ID, which is
+// sent in the top four bits of the device
+func VendorParity(vendor uint16) uint8 {
+	return uint8(vendor>>12^vendor>>8^vendor>>4^vendor) & 0x0F
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
+// codeForCodec returns scancode and device for a 48-bit value. When vendor is
+// VENDOR_ANY then the vendor ID is returned in the top 16 bits of the device,
+// but Panasonic codes are ignored as they are decoded as CODEC_PANASONIC
+func codeForCodec(vendor uint16, value uint64) (uint32, uint32, error) {
+	vendor_ := uint16(value >> 32)
+	device := uint8(value >> 24)
+	subdevice := uint8(value >> 16)
+	scancode := uint8(value >> 8)
+	ck1 := uint8(value)
+	ck2 := device ^ subdevice ^ scancode
+	if vendor == VENDOR_ANY && vendor_ == VENDOR_PANASONIC {
+		// Decoded as Panasonic
+		return 0, 0, gopi.ErrBadParameter
+	} else if vendor != VENDOR_ANY && vendor_ != vendor {
+		// Bad vendor
+		return 0, 0, gopi.ErrBadParameter
+	} else if device>>4 != VendorParity(vendor_) {
+		// Bad vendor parity
+		return 0, 0, gopi.ErrBadParameter
+	} else if ck1 != ck2 {
+		// Bad checksum
+		return 0, 0, gopi.ErrBadParameter
+	} else if vendor == VENDOR_ANY {
+		return uint32(scancode), Device(vendor_, device, subdevice), nil
+	} else {
+		// Merge device together with subdevice
+		return uint32(scancode), uint32(device)<<8 | uint32(subdevice), nil
+	}
+}
+
+// valueForCodec returns the 48-bit value to send for a device and scancode
+func valueForCodec(vendor uint16, device, scancode uint32) (uint64, error) {
+	if vendor == VENDOR_ANY {
+		if vendor, device = uint16(device>>16), device&0xFFFF; vendor == VENDOR_ANY {
+			return 0, fmt.Errorf("Missing vendor in device 0x%08X", device)
+		}
+	} else if device&0xFFFF0000 != 0 {
+		return 0, fmt.Errorf("Invalid device 0x%08X", device)
+	}
+	if device>>12 != uint32(VendorParity(vendor)) {
+		return 0, fmt.Errorf("Invalid vendor parity in device 0x%04X for vendor 0x%04X", device, vendor)
+	}
+	if scancode&0xFFFFFF00 != 0 {
+		return 0, fmt.Errorf("Invalid scancode 0x%08X", scancode)
+	}
+	ck := uint8(device>>8) ^ uint8(device) ^ uint8(scancode)
+	return uint64(vendor)<<32 | uint64(device)<<16 | uint64(scancode)<<8 | uint64(ck), nil
+}
+
+func (s state) String() string {
+	switch s {
+	case STATE_EXPECT_HEADER_PULSE:
+		return "STATE_EXPECT_HEADER_PULSE"
+	case STATE_EXPECT_HEADER_SPACE:
+		return "STATE_EXPECT_HEADER_SPACE"
+	case STATE_EXPECT_PULSE:
+		return "STATE_EXPECT_PULSE"
+	case STATE_EXPECT_SPACE:
+		return "STATE_EXPECT_SPACE"
+	case STATE_EXPECT_TRAIL:
+		return "STATE_EXPECT_TRAIL"
+	case STATE_EXPECT_REPEAT:
+		return "STATE_EXPECT_REPEAT"
+	default:
+		return "[?? Invalid state]"
+	}
+}
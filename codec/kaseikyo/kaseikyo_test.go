@@ -0,0 +1,179 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package kaseikyo_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/kaseikyo"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		vendor           uint16
+		device, scancode uint32
+		repeats          uint
+	}{
+		{remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC, 0x0000, 0x00, 0},
+		{remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC, 0x0FFF, 0xFF, 0},
+		{remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC, 0x0801, 0x3D, 1},
+		{remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC, 0x0801, 0x3D, 3},
+		{remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY, kaseikyo.Device(kaseikyo.VENDOR_DENON, 0x00, 0x00), 0x00, 0},
+		{remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY, kaseikyo.Device(kaseikyo.VENDOR_DENON, 0x0F, 0xFF), 0xFF, 0},
+		{remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY, kaseikyo.Device(kaseikyo.VENDOR_JVC, 0x41, 0x02), 0x44, 1},
+		{remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY, kaseikyo.Device(kaseikyo.VENDOR_SHARP, 0xF5, 0x02), 0x44, 3},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, kaseikyo.Codec{Type: test.codec_type, Vendor: test.vendor})
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("%v device=0x%X scancode=0x%X: %v", test.codec_type, test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, test.codec_type, remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		vendor           uint16
+		device, scancode uint32
+	}{
+		{remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC, 0x1000, 0x00},
+		{remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC, 0x10000, 0x00},
+		{remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC, 0x0000, 0x100},
+		{remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY, 0x0000, 0x00},
+		{remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY, kaseikyo.Device(kaseikyo.VENDOR_DENON, 0x10, 0x00), 0x00},
+		{remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY, kaseikyo.Device(kaseikyo.VENDOR_DENON, 0x00, 0x00), 0x100},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, kaseikyo.Codec{Type: test.codec_type, Vendor: test.vendor})
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("%v device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.codec_type, test.device, test.scancode, err)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodePulses(t *testing.T) {
+	codec := remotestest.OpenCodec(t, kaseikyo.Codec{Type: remotes.CODEC_PANASONIC, Vendor: kaseikyo.VENDOR_PANASONIC})
+	defer codec.Close()
+
+	// A 3.5ms header pulse and 1.7ms space, then the vendor, device,
+	// subdevice, scancode and checksum most significant bit first and a
+	// trail pulse. Repeats follow a 75ms space
+	frame := framePulses(0x400408013D34)
+	expected := append([]uint32{}, frame...)
+	for i := 0; i < 2; i++ {
+		expected = append(append(expected, 75000), frame...)
+	}
+	if pulses, err := codec.Encode(0x0801, 0x3D, 2); err != nil {
+		t.Fatal(err)
+	} else if remotestest.Equals(pulses, expected) == false {
+		t.Errorf("Expected %v, got %v", expected, pulses)
+	}
+}
+
+func TestVendorBitOrder(t *testing.T) {
+	codec := remotestest.OpenCodec(t, kaseikyo.Codec{Type: remotes.CODEC_KASEIKYO, Vendor: kaseikyo.VENDOR_ANY})
+	defer codec.Close()
+
+	// Vendor ID's are stored bit-reversed, so the published values are
+	// sent least significant bit first
+	tests := []struct {
+		vendor, published uint16
+	}{
+		{kaseikyo.VENDOR_PANASONIC, 0x2002},
+		{kaseikyo.VENDOR_DENON, 0x3254},
+		{kaseikyo.VENDOR_JVC, 0x0103},
+		{kaseikyo.VENDOR_MITSUBISHI, 0xCB23},
+		{kaseikyo.VENDOR_SHARP, 0x5AAA},
+	}
+	for _, test := range tests {
+		device := kaseikyo.Device(test.vendor, kaseikyo.VendorParity(test.vendor)<<4, 0x00)
+		if pulses, err := codec.Encode(device, 0x00, 0); err != nil {
+			t.Errorf("vendor=0x%04X: %v", test.vendor, err)
+		} else {
+			// Read the first 16 bits after the header, least significant bit first
+			published := uint16(0)
+			for i := uint(0); i < 16; i++ {
+				if pulses[2+i*2+1] == 1300 {
+					published |= 1 << i
+				}
+			}
+			if published != test.published {
+				t.Errorf("vendor=0x%04X: Expected 0x%04X, got 0x%04X", test.vendor, test.published, published)
+			}
+		}
+	}
+}
+
+func TestInvalidFrame(t *testing.T) {
+	codec := remotestest.OpenCodec(t, kaseikyo.Codec{Type: remotes.CODEC_PANASONIC, Vendor: kaseikyo.VENDOR_PANASONIC})
+	defer codec.Close()
+
+	// Frames with a bad vendor parity or checksum are ignored
+	remotestest.CheckEvents(t, remotes.CODEC_PANASONIC, remotestest.Decode(t, codec, framePulses(0x400408013D34)), 0x0801, 0x3D, 0)
+	for _, value := range []uint64{0x400418013D24, 0x400408013D35} {
+		if evts := remotestest.Decode(t, codec, framePulses(value)); len(evts) != 0 {
+			t.Errorf("value=0x%012X: Expected no events, got %v", value, evts)
+		}
+	}
+}
+
+func TestVendor(t *testing.T) {
+	panasonic := remotestest.OpenCodec(t, kaseikyo.Codec{Type: remotes.CODEC_PANASONIC, Vendor: kaseikyo.VENDOR_PANASONIC})
+	defer panasonic.Close()
+	any_vendor := remotestest.OpenCodec(t, kaseikyo.Codec{Type: remotes.CODEC_KASEIKYO, Vendor: kaseikyo.VENDOR_ANY})
+	defer any_vendor.Close()
+
+	// Panasonic frames are only decoded as CODEC_PANASONIC, and frames
+	// from other vendors are only decoded as CODEC_KASEIKYO
+	if pulses, err := panasonic.Encode(0x0801, 0x3D, 0); err != nil {
+		t.Fatal(err)
+	} else if evts := remotestest.Decode(t, any_vendor, pulses); len(evts) != 0 {
+		t.Errorf("Expected no CODEC_KASEIKYO events, got %v", evts)
+	}
+	device := kaseikyo.Device(kaseikyo.VENDOR_DENON, 0x05, 0x02)
+	if pulses, err := any_vendor.Encode(device, 0x44, 0); err != nil {
+		t.Fatal(err)
+	} else if evts := remotestest.Decode(t, panasonic, pulses); len(evts) != 0 {
+		t.Errorf("Expected no CODEC_PANASONIC events, got %v", evts)
+	}
+}
+
+func TestCarrier(t *testing.T) {
+	codec := remotestest.OpenCodec(t, kaseikyo.Codec{Type: remotes.CODEC_PANASONIC, Vendor: kaseikyo.VENDOR_PANASONIC})
+	defer codec.Close()
+
+	if carrier := codec.Carrier(0); carrier.Frequency != 37000 || carrier.DutyCycle != 33 {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// framePulses returns a header, then a 450us pulse for each of the 48 bits,
+// most significant bit first, followed by a 450us space for a zero or a
+// 1300us space for a one, and a trail pulse
+func framePulses(value uint64) []uint32 {
+	bits := remotestest.PulseDistance{Pulse: 450, Zero: 450, One: 1300, MSB: true}
+	return append(append([]uint32{3500, 1700}, bits.Pulses(value, 48)...), 450)
+}
@@ -7,7 +7,7 @@
 	For Licensing and Usage information, please see LICENSE.md
 */
 
-// Code for both the NEC32 protocol and the Legacy AppleTV protocols
+// Code for the NEC32, NECX and Samsung32 protocols and the Legacy AppleTV protocols
 package nec
 
 // Reference:
@@ -47,6 +47,26 @@ package nec
 //  * A 2.25ms space
 //  * A 562.5µs pulse burst to mark the end of the space (and hence end
 //    of the transmitted repeat code).
+//
+// EXTENDED NEC (NECX)
+//
+// Many remotes don't send the logical inverse of the command, and use
+// the second byte as part of a 16-bit command instead. These are decoded
+// as CODEC_NECX with a 16-bit address and 16-bit command, with no check on
+// the command. Codes where the command and its inverse do match are decoded
+// as CODEC_NEC32 instead.
+
+// Reference:
+//   https://www.mikrocontroller.net/articles/IRMP_-_english#SAMSUNG
+//
+// Samsung32
+//
+// The Samsung32 protocol uses the same bit encoding as NEC, but the leading
+// pulse burst is 4.5ms rather than 9ms. The message consists of a 16-bit
+// address (usually the same 8-bit address sent twice), the 8-bit command and
+// the 8-bit logical inverse of the command. There are no repeat codes:
+// instead the whole message is repeated every 108ms while the key is
+// kept depressed.
 
 // Reference:
 //   https://gist.github.com/darconeous/4437f79a34e3b6441628
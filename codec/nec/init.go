@@ -24,10 +24,15 @@ func init() {
 		Name:     "remotes/nec32",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("nec32.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("nec32.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
-				Type: remotes.CODEC_NEC32,
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_NEC32,
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
@@ -37,10 +42,15 @@ func init() {
 		Name:     "remotes/nec16",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("nec16.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("nec16.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
-				Type: remotes.CODEC_NEC16,
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_NEC16,
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
@@ -50,10 +60,51 @@ func init() {
 		Name:     "remotes/appletv2",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("appletv2.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("appletv2.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_APPLETV,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/necx
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/necx",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("necx.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("necx.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_NECX,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/samsung32
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/samsung32",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("samsung32.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("samsung32.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
-				Type: remotes.CODEC_APPLETV,
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_SAMSUNG32,
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
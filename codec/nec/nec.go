@@ -23,17 +23,21 @@ import (
 ////////////////////////////////////////////////////////////////////////////////
 // TYPES
 
-// NEC Configuration - NEC32 is supported
+// NEC Configuration - NEC32, NEC16, NECX, AppleTV and Samsung32 are supported
 type Codec struct {
-	LIRC gopi.LIRC
-	Type remotes.CodecType
+	LIRC      gopi.LIRC
+	Type      remotes.CodecType
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
 }
 
 type codec struct {
 	log         gopi.Logger
 	lirc        gopi.LIRC
+	carriers    *remotes.Carriers
+	timing      *remotes.Timing
 	codec_type  remotes.CodecType
 	bit_length  uint
+	header      *remotes.MarkSpace
 	cancel      context.CancelFunc
 	done        chan struct{}
 	events      <-chan gopi.Event
@@ -42,6 +46,9 @@ type codec struct {
 	value       uint32
 	length      uint
 	repeat      bool
+	last_value  uint32
+	decoding    bool
+	elapsed     time.Duration
 }
 
 type state uint32
@@ -58,13 +65,17 @@ const (
 	STATE_EXPECT_END_PULSE
 	STATE_EXPECT_TRAIL_SPACE_17500
 	STATE_EXPECT_TRAIL_SPACE_35000
+	STATE_EXPECT_FRAME_SPACE
 	STATE_EXPECT_REPEAT_SPACE
 	STATE_EXPECT_REPEAT_PULSE
 )
 
 const (
-	TOLERANCE    = 35 // 35% tolerance on values
-	APPLETV_CODE = 0x77E1
+	TOLERANCE         = 35    // 35% tolerance on values
+	CARRIER_FREQUENCY = 38000 // 38kHz carrier
+	DUTY_CYCLE        = 33    // 33% duty cycle
+	APPLETV_CODE      = 0x77E1
+	SAMSUNG_DURATION  = 108000 // 108ms between the start of each Samsung32 frame
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -82,6 +93,8 @@ var (
 	REPEAT_PULSE      = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 9000, TOLERANCE)  // 9ms
 	REPEAT_SPACE      = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 2500, TOLERANCE)
 	REPEAT_SPACE2     = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 96577, TOLERANCE)
+	SAMSUNG_PULSE     = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 4500, TOLERANCE) // 4.5ms
+	SAMSUNG_SPACE     = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 45000, 60)       // Between 18ms and 72ms depending on the bits sent
 )
 
 var (
@@ -94,16 +107,19 @@ var (
 func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 	log.Debug("<remotes.Codec.NEC.Open>{ lirc=%v type=%v }", config.LIRC, config.Type)
 
-	// Check for LIRC
-	if config.LIRC == nil {
-		return nil, gopi.ErrBadParameter
-	}
-
 	this := new(codec)
 
 	// Set log and lirc objects
 	this.log = log
 	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
 
 	// Set codec and bit length
 	if bit_length := bitLengthForCodec(config.Type); bit_length == 0 {
@@ -113,18 +129,27 @@ func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 		this.codec_type = config.Type
 	}
 
+	// Samsung32 has a shorter header pulse
+	if config.Type == remotes.CODEC_SAMSUNG32 {
+		this.header = SAMSUNG_PULSE
+	} else {
+		this.header = HEADER_PULSE
+	}
+
 	// Set up channels
 	this.done = make(chan struct{})
-	this.events = this.lirc.Subscribe()
 	this.subscribers = evt.NewPubSub(0)
 
 	// Reset
 	this.Reset()
 
-	// Create background routine
-	if ctx, cancel := context.WithCancel(context.Background()); ctx != nil {
-		this.cancel = cancel
-		go this.acceptEvents(ctx)
+	// Create background routine, when there is a LIRC driver
+	if this.lirc != nil {
+		this.events = this.lirc.Subscribe()
+		if ctx, cancel := context.WithCancel(context.Background()); ctx != nil {
+			this.cancel = cancel
+			go this.acceptEvents(ctx)
+		}
 	}
 
 	// Return success
@@ -135,12 +160,13 @@ func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 func (this *codec) Close() error {
 	this.log.Debug("<remotes.Codec.NEC.Close>{ type=%v }", this.codec_type)
 
-	// Unsubscribe from LIRC signals
-	this.lirc.Unsubscribe(this.events)
-
-	// Cancel background thread, wait for done signal
-	this.cancel()
-	_ = <-this.done
+	// Unsubscribe from LIRC signals, cancel background thread
+	// and wait for done signal
+	if this.lirc != nil {
+		this.lirc.Unsubscribe(this.events)
+		this.cancel()
+		_ = <-this.done
+	}
 
 	// Remove subscribers to this codec
 	this.subscribers.Close()
@@ -169,11 +195,28 @@ func (this *codec) Type() remotes.CodecType {
 	return this.codec_type
 }
 
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
 func (this *codec) Reset() {
 	this.state = STATE_EXPECT_HEADER_PULSE
 	this.value = 0
 	this.length = 0
 	this.repeat = false
+	this.timing.Reset()
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -184,7 +227,10 @@ func (this *codec) Subscribe() <-chan gopi.Event {
 }
 
 func (this *codec) Unsubscribe(subscriber <-chan gopi.Event) {
-	this.subscribers.Unsubscribe(subscriber)
+	// Subscribers are removed when the codec is closed
+	if this.subscribers != nil {
+		this.subscribers.Unsubscribe(subscriber)
+	}
 }
 
 func (this *codec) Emit(value uint32, repeat bool) {
@@ -193,8 +239,8 @@ func (this *codec) Emit(value uint32, repeat bool) {
 		if err != gopi.ErrBadParameter {
 			this.log.Warn("Emit: %v", err)
 		}
-	} else {
-		this.subscribers.Emit(remotes.NewRemoteEvent(this, time.Since(timestamp), scancode, device, repeat))
+	} else if this.timing.Accepts(device) {
+		this.subscribers.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, repeat))
 	}
 }
 
@@ -220,29 +266,29 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 	this.log.Debug2("<remotes.Codec.NEC.Receive>{ type=%v evt=%v }", this.codec_type, evt)
 	switch this.state {
 	case STATE_EXPECT_HEADER_PULSE:
-		if HEADER_PULSE.Matches(evt) {
+		if this.timing.Matches(this.header, evt) {
 			this.state = STATE_EXPECT_HEADER_SPACE
 		} else {
 			this.Reset()
 		}
 	case STATE_EXPECT_HEADER_SPACE:
-		if HEADER_SPACE.Matches(evt) {
+		if this.timing.Matches(HEADER_SPACE, evt) {
 			this.state = STATE_EXPECT_PULSE
 		} else {
 			this.Reset()
 		}
 	case STATE_EXPECT_PULSE:
-		if BIT_PULSE.Matches(evt) {
+		if this.timing.Matches(BIT_PULSE, evt) {
 			this.state = STATE_EXPECT_SPACE
 		} else {
 			this.Reset()
 		}
 	case STATE_EXPECT_SPACE:
 		// Register a zero or one
-		if ZERO_SPACE.Matches(evt) {
+		if this.timing.Matches(ZERO_SPACE, evt) {
 			this.value = (this.value << 1) | 0
 			this.length = this.length + 1
-		} else if ONE_SPACE.Matches(evt) {
+		} else if this.timing.Matches(ONE_SPACE, evt) {
 			this.value = (this.value << 1) | 1
 			this.length = this.length + 1
 		} else {
@@ -258,9 +304,14 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 		}
 	case STATE_EXPECT_END_PULSE:
 		// Mark the end of transmission
-		if BIT_PULSE.Matches(evt) {
+		if this.timing.Matches(BIT_PULSE, evt) {
 			if this.codec_type == remotes.CODEC_NEC16 {
 				this.state = STATE_EXPECT_TRAIL_SPACE_17500
+			} else if this.codec_type == remotes.CODEC_SAMSUNG32 {
+				// Samsung32 repeats the whole frame rather than sending a repeat code,
+				// so a frame is a repeat if it's the same as the previous frame
+				this.Emit(this.value, this.repeat && this.value == this.last_value)
+				this.state = STATE_EXPECT_FRAME_SPACE
 			} else {
 				// Emit key press for NEC32
 				this.Emit(this.value, this.repeat)
@@ -270,38 +321,53 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 			this.Reset()
 		}
 	case STATE_EXPECT_TRAIL_SPACE_17500:
-		if TRAIL_SPACE_17500.Matches(evt) {
+		if this.timing.Matches(TRAIL_SPACE_17500, evt) {
 			// End of NEC16 code
 			this.Emit(this.value, this.repeat)
 			this.state = STATE_EXPECT_PULSE
 			this.value = 0
 			this.length = 0
 			this.repeat = true
+		} else if this.timing.GreaterThan(TRAIL_SPACE_17500, evt) || evt.Type() == gopi.LIRC_TYPE_TIMEOUT {
+			// The last NEC16 code ends with a long space or timeout
+			this.Emit(this.value, this.repeat)
+			this.Reset()
 		} else {
 			this.Reset()
 		}
 	case STATE_EXPECT_TRAIL_SPACE_35000:
-		if TRAIL_SPACE_35000.Matches(evt) {
+		if this.timing.Matches(TRAIL_SPACE_35000, evt) {
 			// End of NEC32 code
 			this.state = STATE_EXPECT_REPEAT_PULSE
-		} else if REPEAT_SPACE2.Matches(evt) {
+		} else if this.timing.Matches(REPEAT_SPACE2, evt) {
 			// End of NEC32 repeat
 			this.state = STATE_EXPECT_REPEAT_PULSE
 		} else {
 			this.Reset()
 		}
+	case STATE_EXPECT_FRAME_SPACE:
+		if this.timing.Matches(SAMSUNG_SPACE, evt) {
+			// End of Samsung32 code, expect the header of the next frame
+			this.state = STATE_EXPECT_HEADER_PULSE
+			this.last_value = this.value
+			this.value = 0
+			this.length = 0
+			this.repeat = true
+		} else {
+			this.Reset()
+		}
 	case STATE_EXPECT_REPEAT_PULSE:
-		if REPEAT_PULSE.Matches(evt) {
+		if this.timing.Matches(REPEAT_PULSE, evt) {
 			this.repeat = true
 			this.state = STATE_EXPECT_REPEAT_SPACE
 		} else {
 			this.Reset()
 		}
 	case STATE_EXPECT_REPEAT_SPACE:
-		if REPEAT_SPACE.Matches(evt) || REPEAT_SPACE2.Matches(evt) {
-			this.Emit(this.value, this.repeat)
+		if this.timing.Matches(REPEAT_SPACE, evt) || this.timing.Matches(REPEAT_SPACE2, evt) {
+			// The repeat is emitted on the trailing pulse
 			this.state = STATE_EXPECT_END_PULSE
-		} else if HEADER_SPACE.Matches(evt) {
+		} else if this.timing.Matches(HEADER_SPACE, evt) {
 			this.state = STATE_EXPECT_PULSE
 			this.value = 0
 			this.length = 0
@@ -314,26 +380,84 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), codec_type: this.codec_type, bit_length: this.bit_length, header: this.header, subscribers: evt.NewPubSub(0), decoding: true}
+	decoder.Reset()
+	defer decoder.subscribers.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SENDING
 
 func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 	this.log.Debug2("<remotes.Codec.NEC>Send{ codec_type=%v device=0x%08X scancode=0x%08X repeats=%v }", this.codec_type, device, scancode, repeats)
 
-	// 9ms leading pulse burst and 4.5ms space
+	if this.lirc == nil {
+		this.log.Error("<remotes.Codec.NEC> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+// Encode returns the pulses for a scancode without sending them
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	// 9ms leading pulse burst (4.5ms for Samsung32) and 4.5ms space
 	pulses := make([]uint32, 0, 100)
-	pulses = append(pulses, HEADER_PULSE.Value, HEADER_SPACE.Value)
+	pulses = append(pulses, this.header.Value, HEADER_SPACE.Value)
 
 	switch this.codec_type {
 	case remotes.CODEC_NEC32:
 		// Ensure the device is 16 bits and the scancode is 8 bits
 		if uint32(uint16(device)) != device {
-			this.log.Error("<remotes.Codec.NEC> Send: Invalid device parameter")
-			return gopi.ErrBadParameter
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid device parameter")
+			return nil, gopi.ErrBadParameter
+		}
+		if uint32(uint8(scancode)) != scancode {
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid scancode parameter")
+			return nil, gopi.ErrBadParameter
+		}
+		// Emit the device and scancode
+		pulses = this.sendbyte(pulses, uint8((device&0xFF00)>>8))
+		pulses = this.sendbyte(pulses, uint8(device&0x00FF))
+		pulses = this.sendbyte(pulses, uint8(scancode&0x00FF))
+		pulses = this.sendbyte(pulses, uint8(scancode^0xFF))
+	case remotes.CODEC_NECX:
+		// Ensure the device is 16 bits and the scancode is 16 bits
+		if uint32(uint16(device)) != device {
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid device parameter")
+			return nil, gopi.ErrBadParameter
+		}
+		if uint32(uint16(scancode)) != scancode {
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid scancode parameter")
+			return nil, gopi.ErrBadParameter
+		}
+		// Emit the device and scancode, without inversion
+		pulses = this.sendbyte(pulses, uint8((device&0xFF00)>>8))
+		pulses = this.sendbyte(pulses, uint8(device&0x00FF))
+		pulses = this.sendbyte(pulses, uint8((scancode&0xFF00)>>8))
+		pulses = this.sendbyte(pulses, uint8(scancode&0x00FF))
+	case remotes.CODEC_SAMSUNG32:
+		// Ensure the device is 16 bits and the scancode is 8 bits
+		if uint32(uint16(device)) != device {
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid device parameter")
+			return nil, gopi.ErrBadParameter
 		}
 		if uint32(uint8(scancode)) != scancode {
-			this.log.Error("<remotes.Codec.NEC> Send: Invalid scancode parameter")
-			return gopi.ErrBadParameter
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid scancode parameter")
+			return nil, gopi.ErrBadParameter
 		}
 		// Emit the device and scancode
 		pulses = this.sendbyte(pulses, uint8((device&0xFF00)>>8))
@@ -343,12 +467,12 @@ func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 	case remotes.CODEC_NEC16:
 		// Ensure the device is 8 bits and the scancode is 8 bits
 		if uint32(uint8(device)) != device {
-			this.log.Error("<remotes.Codec.NEC> Send: Invalid device parameter")
-			return gopi.ErrBadParameter
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid device parameter")
+			return nil, gopi.ErrBadParameter
 		}
 		if uint32(uint8(scancode)) != scancode {
-			this.log.Error("<remotes.Codec.NEC> Send: Invalid scancode parameter")
-			return gopi.ErrBadParameter
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid scancode parameter")
+			return nil, gopi.ErrBadParameter
 		}
 		// Emit the device and scancode
 		pulses = this.sendbyte(pulses, uint8(device&0x00FF))
@@ -356,37 +480,58 @@ func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 	case remotes.CODEC_APPLETV:
 		// Ensure device code is 8 bits and scancode is 8 bits
 		if uint32(uint8(device)) != device {
-			this.log.Error("<remotes.Codec.NEC> Send: Invalid device parameter")
-			return gopi.ErrBadParameter
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid device parameter")
+			return nil, gopi.ErrBadParameter
 		}
 		if uint32(uint8(scancode)) != scancode {
-			this.log.Error("<remotes.Codec.NEC> Send: Invalid scancode parameter")
-			return gopi.ErrBadParameter
+			this.log.Error("<remotes.Codec.NEC> Encode: Invalid scancode parameter")
+			return nil, gopi.ErrBadParameter
 		}
 		// Emit the AppleTV code, then the scancode and device
 		pulses = this.sendbyte(pulses, uint8(APPLETV_CODE&0xFF00>>8))
 		pulses = this.sendbyte(pulses, uint8(APPLETV_CODE&0x00FF))
-		pulses = this.sendbyte(pulses, uint8(device&0x00FF))
 		pulses = this.sendbyte(pulses, uint8(scancode&0x00FF))
+		pulses = this.sendbyte(pulses, uint8(device&0x00FF))
 	default:
-		return gopi.ErrNotImplemented
+		return nil, gopi.ErrNotImplemented
 	}
 
 	// A final 562.5µs pulse
 	pulses = append(pulses, TRAIL_PULSE.Value)
 
-	// If there is one or more repeats, then do these
-	if repeats > 0 {
+	// Samsung32 repeats the whole frame, every 108ms, and NEC16 repeats the
+	// frame without the header after a 17.5ms space. Otherwise, if there
+	// is one or more repeats, then send the repeat codes
+	if this.codec_type == remotes.CODEC_NEC16 {
+		frame := append([]uint32{}, pulses[2:]...)
+		for i := uint(0); i < repeats; i++ {
+			pulses = append(pulses, TRAIL_SPACE_17500.Value)
+			pulses = append(pulses, frame...)
+		}
+	} else if this.codec_type == remotes.CODEC_SAMSUNG32 {
+		frame := append([]uint32{}, pulses...)
+		length := uint32(0)
+		for _, value := range frame {
+			length += value
+		}
+		for i := uint(0); i < repeats; i++ {
+			pulses = append(pulses, SAMSUNG_DURATION-length)
+			pulses = append(pulses, frame...)
+		}
+	} else if repeats > 0 {
+		// Each repeat code is a 9ms pulse, 2.25ms space and a final
+		// 562.5µs pulse, every 108ms
 		pulses = append(pulses, TRAIL_SPACE_35000.Value)
 		for i := uint(0); i < repeats; i++ {
-			pulses = append(pulses, REPEAT_PULSE.Value, REPEAT_SPACE.Value)
+			if i > 0 {
+				pulses = append(pulses, REPEAT_SPACE2.Value)
+			}
+			pulses = append(pulses, REPEAT_PULSE.Value, REPEAT_SPACE.Value, TRAIL_PULSE.Value)
 		}
-		// A final 562.5µs pulse
-		pulses = append(pulses, TRAIL_PULSE.Value)
 	}
 
-	// Perform the pulse send
-	return this.lirc.PulseSend(pulses)
+	// Return success
+	return pulses, nil
 }
 
 func (this *codec) sendbyte(pulses []uint32, value uint8) []uint32 {
@@ -406,6 +551,16 @@ func (this *codec) sendbyte(pulses []uint32, value uint8) []uint32 {
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
 func bitLengthForCodec(codec remotes.CodecType) uint {
 	switch codec {
 	case remotes.CODEC_NEC32:
@@ -414,6 +569,10 @@ func bitLengthForCodec(codec remotes.CodecType) uint {
 		return 16
 	case remotes.CODEC_APPLETV:
 		return 32
+	case remotes.CODEC_NECX:
+		return 32
+	case remotes.CODEC_SAMSUNG32:
+		return 32
 	default:
 		return 0
 	}
@@ -443,6 +602,29 @@ func codeForCodec(codec remotes.CodecType, value uint32) (uint32, uint32, error)
 			return 0, 0, fmt.Errorf("Invalid scancode 0x%02X or device 0x%04X for codec %v (the code received was 0x%08X, the scancodes were %02X and %02X)", scancode1, device, codec, value, scancode1, scancode2^0xFF)
 		}
 		return scancode1, device, nil
+	case remotes.CODEC_NECX:
+		// Ignore Apple TV
+		device := value & 0xFFFF0000 >> 16
+		if device == APPLETV_CODE {
+			return 0, 0, gopi.ErrBadParameter
+		}
+		// There is no check on the scancode, but ignore codes where the scancode
+		// and reverse of scancode match, as these are reported as NEC32
+		scancode1 := value & 0x0000FF00 >> 8
+		scancode2 := value & 0x000000FF
+		if scancode1 == scancode2^0x00FF {
+			return 0, 0, gopi.ErrBadParameter
+		}
+		return value & 0x0000FFFF, device, nil
+	case remotes.CODEC_SAMSUNG32:
+		// Check to make sure scancode and reverse of scancode match
+		device := value & 0xFFFF0000 >> 16
+		scancode1 := value & 0x0000FF00 >> 8
+		scancode2 := value & 0x000000FF
+		if scancode1 != scancode2^0x00FF {
+			return 0, 0, fmt.Errorf("Invalid scancode 0x%02X or device 0x%04X for codec %v (the code received was 0x%08X, the scancodes were %02X and %02X)", scancode1, device, codec, value, scancode1, scancode2^0xFF)
+		}
+		return scancode1, device, nil
 	case remotes.CODEC_NEC16:
 		// Check to make sure scancode and reverse of scancode match
 		scancode := value & 0x000000FF
@@ -469,6 +651,8 @@ func (s state) String() string {
 		return "STATE_EXPECT_TRAIL_SPACE_17500"
 	case STATE_EXPECT_TRAIL_SPACE_35000:
 		return "STATE_EXPECT_TRAIL_SPACE_35000"
+	case STATE_EXPECT_FRAME_SPACE:
+		return "STATE_EXPECT_FRAME_SPACE"
 	case STATE_EXPECT_REPEAT_SPACE:
 		return "STATE_EXPECT_REPEAT_SPACE"
 	case STATE_EXPECT_REPEAT_PULSE:
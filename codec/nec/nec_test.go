@@ -0,0 +1,208 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package nec_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/nec"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+var (
+	// A 562us pulse for each bit, most significant bit first, followed by
+	// a 562us space for a zero or a 1688us space for a one
+	NEC_BITS = remotestest.PulseDistance{Pulse: 562, Zero: 562, One: 1688, MSB: true}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+		repeats          uint
+	}{
+		{remotes.CODEC_NEC32, 0x0000, 0x00, 0},
+		{remotes.CODEC_NEC32, 0xFFFF, 0xFF, 0},
+		{remotes.CODEC_NEC32, 0x40BF, 0x12, 1},
+		{remotes.CODEC_NEC32, 0x40BF, 0x12, 3},
+		{remotes.CODEC_NEC32, 0x1234, 0x56, 1},
+		{remotes.CODEC_NEC16, 0x00, 0x00, 0},
+		{remotes.CODEC_NEC16, 0xFF, 0xFF, 0},
+		{remotes.CODEC_NEC16, 0x5A, 0x12, 1},
+		{remotes.CODEC_NEC16, 0x5A, 0x12, 3},
+		{remotes.CODEC_NECX, 0x0000, 0x0000, 0},
+		{remotes.CODEC_NECX, 0xFFFF, 0xFFFF, 0},
+		{remotes.CODEC_NECX, 0x40BF, 0x1234, 1},
+		{remotes.CODEC_NECX, 0x40BF, 0x1234, 3},
+		{remotes.CODEC_SAMSUNG32, 0x0000, 0x00, 0},
+		{remotes.CODEC_SAMSUNG32, 0xFFFF, 0xFF, 0},
+		{remotes.CODEC_SAMSUNG32, 0x0707, 0x02, 1},
+		{remotes.CODEC_SAMSUNG32, 0x0707, 0x02, 3},
+		{remotes.CODEC_APPLETV, 0x00, 0x00, 0},
+		{remotes.CODEC_APPLETV, 0xFF, 0xFF, 0},
+		{remotes.CODEC_APPLETV, 0x5A, 0x0C, 1},
+		{remotes.CODEC_APPLETV, 0x5A, 0x0C, 3},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, nec.Codec{Type: test.codec_type})
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("%v device=0x%X scancode=0x%X: %v", test.codec_type, test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, test.codec_type, remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+	}{
+		{remotes.CODEC_NEC32, 0x10000, 0x00},
+		{remotes.CODEC_NEC32, 0x0000, 0x100},
+		{remotes.CODEC_NEC16, 0x100, 0x00},
+		{remotes.CODEC_NEC16, 0x00, 0x100},
+		{remotes.CODEC_NECX, 0x10000, 0x0000},
+		{remotes.CODEC_NECX, 0x0000, 0x10000},
+		{remotes.CODEC_SAMSUNG32, 0x10000, 0x00},
+		{remotes.CODEC_SAMSUNG32, 0x0000, 0x100},
+		{remotes.CODEC_APPLETV, 0x100, 0x00},
+		{remotes.CODEC_APPLETV, 0x00, 0x100},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, nec.Codec{Type: test.codec_type})
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("%v device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.codec_type, test.device, test.scancode, err)
+		}
+		codec.Close()
+	}
+}
+
+func TestNECX(t *testing.T) {
+	nec32 := remotestest.OpenCodec(t, nec.Codec{Type: remotes.CODEC_NEC32})
+	defer nec32.Close()
+	necx := remotestest.OpenCodec(t, nec.Codec{Type: remotes.CODEC_NECX})
+	defer necx.Close()
+
+	// Frames where the command and its inverse don't match are decoded as
+	// NECX with a 16-bit address and command, and are ignored by NEC32
+	pulses := append(append([]uint32{9000, 4500}, NEC_BITS.Pulses(0x40BF1234, 32)...), 562)
+	remotestest.CheckEvents(t, remotes.CODEC_NECX, remotestest.Decode(t, necx, pulses), 0x40BF, 0x1234, 0)
+	if evts := remotestest.Decode(t, nec32, pulses); len(evts) != 0 {
+		t.Errorf("Expected no NEC32 events, got %v", evts)
+	}
+	// Frames where they match are decoded as NEC32 with any address
+	for _, value := range []uint32{0x40BF12ED, 0x123456A9} {
+		pulses := append(append([]uint32{9000, 4500}, NEC_BITS.Pulses(uint64(value), 32)...), 562)
+		remotestest.CheckEvents(t, remotes.CODEC_NEC32, remotestest.Decode(t, nec32, pulses), value>>16, value>>8&0xFF, 0)
+		if evts := remotestest.Decode(t, necx, pulses); len(evts) != 0 {
+			t.Errorf("value=0x%08X: Expected no NECX events, got %v", value, evts)
+		}
+	}
+}
+
+func TestEncodePulses(t *testing.T) {
+	// A 9ms header pulse and 4.5ms space, then the address and the command
+	// most significant bit first without inversion, and a trail pulse. NECX
+	// repeat codes follow a 35ms space and are separated by 96.5ms
+	necx := append(append([]uint32{9000, 4500}, NEC_BITS.Pulses(0x12345678, 32)...), 562)
+	repeat := []uint32{9000, 2500, 562}
+	// Samsung32 has a 4.5ms header pulse, and repeats the whole frame
+	// so that each frame starts 108ms after the start of the previous frame
+	samsung := append(append([]uint32{4500, 4500}, NEC_BITS.Pulses(0x070702FD, 32)...), 562)
+
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+		repeats          uint
+		expected         []uint32
+	}{
+		{remotes.CODEC_NECX, 0x1234, 0x5678, 0, necx},
+		{remotes.CODEC_NECX, 0x1234, 0x5678, 2, remotestest.Join(remotestest.Join(necx, 35000, repeat), 96577, repeat)},
+		{remotes.CODEC_SAMSUNG32, 0x0707, 0x02, 0, samsung},
+		{remotes.CODEC_SAMSUNG32, 0x0707, 0x02, 2, remotestest.Join(remotestest.Join(samsung, 108000-remotestest.Length(samsung), samsung), 108000-remotestest.Length(samsung), samsung)},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, nec.Codec{Type: test.codec_type})
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("%v: %v", test.codec_type, err)
+		} else if remotestest.Equals(pulses, test.expected) == false {
+			t.Errorf("%v repeats=%v: Expected %v, got %v", test.codec_type, test.repeats, test.expected, pulses)
+		}
+		codec.Close()
+	}
+}
+
+func TestSamsungHeader(t *testing.T) {
+	nec32 := remotestest.OpenCodec(t, nec.Codec{Type: remotes.CODEC_NEC32})
+	defer nec32.Close()
+	samsung := remotestest.OpenCodec(t, nec.Codec{Type: remotes.CODEC_SAMSUNG32})
+	defer samsung.Close()
+
+	// The header pulse separates NEC32 and Samsung32 frames with the same bits
+	if pulses, err := samsung.Encode(0x40BF, 0x12, 0); err != nil {
+		t.Fatal(err)
+	} else if evts := remotestest.Decode(t, nec32, pulses); len(evts) != 0 {
+		t.Errorf("Expected no NEC32 events, got %v", evts)
+	}
+	if pulses, err := nec32.Encode(0x40BF, 0x12, 0); err != nil {
+		t.Fatal(err)
+	} else if evts := remotestest.Decode(t, samsung, pulses); len(evts) != 0 {
+		t.Errorf("Expected no Samsung32 events, got %v", evts)
+	}
+}
+
+func TestCarrier(t *testing.T) {
+	for _, codec_type := range []remotes.CodecType{remotes.CODEC_NECX, remotes.CODEC_SAMSUNG32} {
+		codec := remotestest.OpenCodec(t, nec.Codec{Type: codec_type})
+		if carrier := codec.Carrier(0); carrier.Frequency != 38000 || carrier.DutyCycle != 33 {
+			t.Errorf("%v: Unexpected carrier %v", codec_type, carrier)
+		}
+		codec.Close()
+	}
+}
+
+func TestCalibration(t *testing.T) {
+	codec := remotestest.OpenCodec(t, nec.Codec{Type: remotes.CODEC_NEC32})
+	defer codec.Close()
+
+	// A remote which is 50% slower than the nominal values is only decoded
+	// once it's calibrated, and the calibration doesn't affect another remote
+	slow, err := codec.Encode(0x40BF, 0x12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := codec.Encode(0x20DF, 0x12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evts := remotestest.Decode(t, codec, remotestest.Scale(slow, 1.5)); len(evts) != 0 {
+		t.Errorf("Expected no events before calibration, got %v", evts)
+	}
+	if err := codec.(remotes.CalibratedCodec).Calibrate(0x40BF, &remotes.Calibration{Pulse: 1.5, Space: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	remotestest.CheckEvents(t, remotes.CODEC_NEC32, remotestest.Decode(t, codec, remotestest.Scale(slow, 1.5)), 0x40BF, 0x12, 1)
+	remotestest.CheckEvents(t, remotes.CODEC_NEC32, remotestest.Decode(t, codec, slow), 0x40BF, 0x12, 1)
+	remotestest.CheckEvents(t, remotes.CODEC_NEC32, remotestest.Decode(t, codec, other), 0x20DF, 0x12, 1)
+	if evts := remotestest.Decode(t, codec, remotestest.Scale(other, 1.5)); len(evts) != 0 {
+		t.Errorf("Expected no events for another remote, got %v", evts)
+	}
+}
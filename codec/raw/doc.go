@@ -0,0 +1,34 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package raw
+
+// The raw codec is used for remotes which no other codec understands, such
+// as air-conditioner remotes. It captures the full sequence of pulses and
+// spaces in a frame, where a frame ends with a space of 40ms or more, or
+// a receive timeout.
+//
+// Captured frames are normalised, so that pulses (or spaces) within 20% of
+// each other are replaced by their mean, and then quantised to 10µs. The
+// scancode is a hash of the normalised frame, and the device is the header
+// pulse and space in units of 500µs, so that all frames from a remote
+// usually have the same device.
+//
+// Learned frames are stored in the keymap entry as pulses, and registered
+// with the codec using SetPulses (or remotes.SetRawPulses for all keymaps).
+// A received frame matches a learned frame when it has the same number of
+// values and each is within 35% (or 150µs) of the learned value. Learned
+// frames are sent verbatim with a 38kHz carrier, unless the keymap sets a
+// carrier for the device, with 50ms between repeats.
+//
+// Events are only emitted for frames which match a learned frame, so that
+// frames which other codecs decode are not reported twice. When learning
+// (see SetLearning) every frame is captured and emitted, including frames
+// which other codecs also decode, so events from other codecs should be
+// preferred when learning a key.
@@ -0,0 +1,37 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package raw
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register remotes/raw
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/raw",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("raw.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("raw.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+}
@@ -0,0 +1,524 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package raw
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Raw Configuration
+type Codec struct {
+	LIRC      gopi.LIRC
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type codec struct {
+	log       gopi.Logger
+	lirc      gopi.LIRC
+	carriers  *remotes.Carriers
+	tolerance uint32
+	levels    []uint32
+	learned   map[key]*frame
+	captured  []*frame
+	last      *frame
+	last_ts   time.Duration
+	learning  bool
+	decoding  bool
+	elapsed   time.Duration
+
+	sync.Mutex
+	event.Publisher
+	event.Tasks
+}
+
+// key is the device and scancode for a frame
+type key struct {
+	device, scancode uint32
+}
+
+// frame is a normalised sequence of pulses and spaces
+type frame struct {
+	key
+	pulses []uint32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	TOLERANCE         = 35     // 35% tolerance on values when matching frames
+	CARRIER_FREQUENCY = 38000  // 38kHz carrier
+	DUTY_CYCLE        = 33     // 33% duty cycle
+	MIN_DEVIATION     = 150    // Short values can deviate by 150us
+	CLUSTER_TOLERANCE = 20     // Values within 20% of each other are normalised to the same value
+	QUANTUM           = 10     // Values are quantised to 10us
+	HEADER_UNIT       = 500    // Header values are quantised to 500us for the device
+	FRAME_GAP         = 40000  // A space of 40ms or more ends a frame
+	MIN_LENGTH        = 7      // Frames with fewer values are ignored as noise
+	MAX_LENGTH        = 2000   // Frames with more values are discarded
+	MAX_CAPTURED      = 32     // Number of unlearned frames remembered
+	REPEAT_GAP        = 150000 // Frames sent with a gap of less than 150ms are repeats
+	TX_GAP            = 50000  // 50ms between each transmission
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	timestamp = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.raw>Open{ lirc=%v }", config.LIRC)
+
+	// Check tolerance
+	if config.Tolerance > 100 {
+		return nil, gopi.ErrBadParameter
+	} else if config.Tolerance == 0 {
+		config.Tolerance = TOLERANCE
+	}
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+	this.tolerance = config.Tolerance
+	this.learned = make(map[key]*frame)
+	this.captured = make([]*frame, 0, MAX_CAPTURED)
+
+	// Reset state
+	this.Reset()
+
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *codec) Close() error {
+	this.log.Debug("<remotes.codec.raw>Close>{}")
+
+	// Remove subscribers to this codec
+	this.Publisher.Close()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.lirc = nil
+	this.learned = nil
+	this.captured = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	this.Lock()
+	defer this.Unlock()
+	return fmt.Sprintf("<remotes.Codec.Raw>{ learned=%v captured=%v }", len(this.learned), len(this.captured))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *codec) Type() remotes.CodecType {
+	return remotes.CODEC_RAW
+}
+
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.tolerance
+}
+
+func (this *codec) Reset() {
+	this.levels = make([]uint32, 0, 100)
+	this.last = nil
+}
+
+// Pulses returns the learned or captured pulses for a device and scancode
+func (this *codec) Pulses(device, scancode uint32) []uint32 {
+	this.Lock()
+	defer this.Unlock()
+	if frame := this.frameForKey(key{device, scancode}); frame == nil {
+		return nil
+	} else {
+		return append([]uint32{}, frame.pulses...)
+	}
+}
+
+// SetLearning sets whether events are emitted for frames which have not
+// been learned
+func (this *codec) SetLearning(flag bool) {
+	this.log.Debug2("<remotes.codec.raw>SetLearning{ flag=%v }", flag)
+
+	this.Lock()
+	defer this.Unlock()
+	this.learning = flag
+}
+
+// SetPulses sets learned pulses for a device and scancode
+func (this *codec) SetPulses(device, scancode uint32, pulses []uint32) error {
+	this.log.Debug2("<remotes.codec.raw>SetPulses{ device=0x%08X scancode=0x%08X pulses=%v }", device, scancode, len(pulses))
+
+	// Pulses start and end with a pulse
+	if len(pulses) == 0 || len(pulses)%2 == 0 {
+		return gopi.ErrBadParameter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	this.learned[key{device, scancode}] = &frame{key{device, scancode}, append([]uint32{}, pulses...)}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *codec) Emit(frame *frame, repeat bool) {
+	this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), frame.scancode, frame.device, repeat))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *codec) PulseTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	events := this.lirc.Subscribe()
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt != nil {
+				this.receive(evt.(gopi.LIRCEvent))
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.lirc.Unsubscribe(events)
+
+	// Success
+	return nil
+}
+
+func (this *codec) receive(evt gopi.LIRCEvent) {
+	this.log.Debug2("<remotes.codec.raw>Receive{ evt=%v levels=%v }", evt, len(this.levels))
+
+	// Levels alternate between pulse and space, with consecutive
+	// values of the same type merged. A long space or timeout
+	// ends the frame
+	switch evt.Type() {
+	case gopi.LIRC_TYPE_PULSE:
+		if len(this.levels)%2 == 0 {
+			this.levels = append(this.levels, evt.Value())
+		} else {
+			this.levels[len(this.levels)-1] += evt.Value()
+		}
+	case gopi.LIRC_TYPE_SPACE:
+		if len(this.levels) == 0 {
+			// Ignore leading spaces
+		} else if evt.Value() >= FRAME_GAP {
+			this.eject()
+		} else if len(this.levels)%2 == 1 {
+			this.levels = append(this.levels, evt.Value())
+		} else {
+			this.levels[len(this.levels)-1] += evt.Value()
+		}
+	case gopi.LIRC_TYPE_TIMEOUT:
+		this.eject()
+	}
+
+	// Discard frames which are too long
+	if len(this.levels) > MAX_LENGTH {
+		this.log.Debug("<remotes.codec.raw> Discarding frame with %v values", len(this.levels))
+		this.levels = this.levels[:0]
+	}
+}
+
+// eject is called at the end of a frame, and emits an event for a learned
+// frame. When learning, frames which have not been learned are captured
+// and an event is emitted for them too
+func (this *codec) eject() {
+	// Remove trailing space
+	pulses := this.levels
+	if len(pulses)%2 == 0 && len(pulses) > 0 {
+		pulses = pulses[:len(pulses)-1]
+	}
+	this.levels = make([]uint32, 0, cap(this.levels))
+
+	// Ignore noise
+	if len(pulses) < MIN_LENGTH {
+		return
+	}
+
+	// Calculate the gap between this frame and the last one
+	ts := this.now()
+	gap := ts - duration(pulses) - this.last_ts
+	this.last_ts = ts
+
+	// Find a matching frame, or capture a new one when learning
+	this.Lock()
+	frame := this.matchLearned(pulses)
+	if frame == nil && this.learning {
+		if frame = this.matchCaptured(pulses); frame == nil {
+			frame = this.captureFrame(pulses)
+		}
+	}
+	this.Unlock()
+
+	// Frames which other codecs decode are not emitted, unless learning
+	if frame == nil {
+		this.last = nil
+		return
+	}
+
+	// Emit the event
+	repeat := this.last == frame && gap < REPEAT_GAP*time.Microsecond
+	this.last = frame
+	this.Emit(frame, repeat)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state and the frames learned so far
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	this.Lock()
+	learned := make(map[key]*frame, len(this.learned))
+	for k, frame := range this.learned {
+		learned[k] = frame
+	}
+	learning := this.learning
+	this.Unlock()
+
+	decoder := &codec{log: this.log, tolerance: this.tolerance, learned: learned, captured: make([]*frame, 0, MAX_CAPTURED), learning: learning, decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SENDING
+
+func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
+	this.log.Debug2("<remotes.codec.raw>Send{ device=0x%08X scancode=0x%08X repeats=%v }", device, scancode, repeats)
+
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.raw> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	this.Lock()
+	frame := this.frameForKey(key{device, scancode})
+	this.Unlock()
+	if frame == nil {
+		return nil, remotes.ErrNotFound
+	}
+
+	// Send the frame verbatim, repeated with a gap between each
+	pulses := make([]uint32, 0, (len(frame.pulses)+1)*int(repeats+1))
+	for i := uint(0); i <= repeats; i++ {
+		if i > 0 {
+			pulses = append(pulses, TX_GAP)
+		}
+		pulses = append(pulses, frame.pulses...)
+	}
+
+	// Return the pulses
+	return pulses, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
+// frameForKey returns a learned or captured frame
+func (this *codec) frameForKey(k key) *frame {
+	if frame, exists := this.learned[k]; exists {
+		return frame
+	}
+	for _, frame := range this.captured {
+		if frame.key == k {
+			return frame
+		}
+	}
+	return nil
+}
+
+// matchLearned returns the learned frame which matches the received
+// pulses, or nil
+func (this *codec) matchLearned(pulses []uint32) *frame {
+	for _, frame := range this.learned {
+		if this.matchPulses(pulses, frame.pulses) {
+			return frame
+		}
+	}
+	return nil
+}
+
+// matchCaptured returns the captured frame which matches the received
+// pulses, or nil
+func (this *codec) matchCaptured(pulses []uint32) *frame {
+	for _, frame := range this.captured {
+		if this.matchPulses(pulses, frame.pulses) {
+			return frame
+		}
+	}
+	return nil
+}
+
+// captureFrame normalises the received pulses and remembers them
+// as a new frame, discarding the oldest captured frame if necessary
+func (this *codec) captureFrame(pulses []uint32) *frame {
+	pulses = normalise(pulses)
+	frame := &frame{key{deviceForPulses(pulses), scancodeForPulses(pulses)}, pulses}
+	if len(this.captured) >= MAX_CAPTURED {
+		this.captured = this.captured[1:]
+	}
+	this.captured = append(this.captured, frame)
+	return frame
+}
+
+// matchPulses returns true if the received pulses are the same length
+// as the expected pulses and each value is within tolerance
+func (this *codec) matchPulses(pulses, expected []uint32) bool {
+	if len(pulses) != len(expected) {
+		return false
+	}
+	for i, value := range pulses {
+		deviation := expected[i] * this.tolerance / 100
+		if deviation < MIN_DEVIATION {
+			deviation = MIN_DEVIATION
+		}
+		if value+deviation < expected[i] || value > expected[i]+deviation {
+			return false
+		}
+	}
+	return true
+}
+
+// normalise returns pulses where values of the same type which are close
+// to each other are replaced with their mean, and then quantised
+func normalise(pulses []uint32) []uint32 {
+	normalised := make([]uint32, len(pulses))
+	for parity := 0; parity < 2; parity++ {
+		// Sort the pulses or spaces by value
+		indexes := make([]int, 0, len(pulses)/2+1)
+		for i := parity; i < len(pulses); i += 2 {
+			indexes = append(indexes, i)
+		}
+		sort.Slice(indexes, func(i, j int) bool {
+			return pulses[indexes[i]] < pulses[indexes[j]]
+		})
+		// Each cluster is a run of values within tolerance of the previous value
+		for start := 0; start < len(indexes); {
+			end, sum := start+1, uint64(pulses[indexes[start]])
+			for end < len(indexes) && pulses[indexes[end]] <= pulses[indexes[end-1]]*(100+CLUSTER_TOLERANCE)/100 {
+				sum += uint64(pulses[indexes[end]])
+				end++
+			}
+			mean := quantise(uint32(sum / uint64(end-start)))
+			for _, i := range indexes[start:end] {
+				normalised[i] = mean
+			}
+			start = end
+		}
+	}
+	return normalised
+}
+
+// quantise rounds a value to the nearest quantum
+func quantise(value uint32) uint32 {
+	if value = (value + QUANTUM/2) / QUANTUM * QUANTUM; value == 0 {
+		return QUANTUM
+	} else {
+		return value
+	}
+}
+
+// duration returns the total duration of a frame
+func duration(pulses []uint32) time.Duration {
+	total := time.Duration(0)
+	for _, value := range pulses {
+		total += time.Duration(value) * time.Microsecond
+	}
+	return total
+}
+
+// deviceForPulses returns the device for a frame, which is the header
+// pulse and space in units of 500us, so that frames from the same remote
+// are usually given the same device
+func deviceForPulses(pulses []uint32) uint32 {
+	pulse := (pulses[0] + HEADER_UNIT/2) / HEADER_UNIT
+	space := (pulses[1] + HEADER_UNIT/2) / HEADER_UNIT
+	return (pulse&0xFFFF)<<16 | (space & 0xFFFF)
+}
+
+// scancodeForPulses returns a hash of the normalised pulses
+func scancodeForPulses(pulses []uint32) uint32 {
+	hash := fnv.New32a()
+	for _, value := range pulses {
+		hash.Write([]byte{byte(value), byte(value >> 8), byte(value >> 16), byte(value >> 24)})
+	}
+	return hash.Sum32()
+}
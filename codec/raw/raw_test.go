@@ -0,0 +1,108 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package raw_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/raw"
+	"github.com/djthorpe/remotes/codec/sharp"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestDecodedFrames(t *testing.T) {
+	codec := openCodec(t)
+	defer codec.Close()
+
+	// A frame which another codec decodes is not emitted
+	pulses := sharpPulses(t, 0x11, 0x5A)
+	if evts := remotestest.Decode(t, codec, pulses); len(evts) != 0 {
+		t.Errorf("Expected no events, got %v", evts)
+	}
+
+	// Unless learning, when it is captured
+	codec.SetLearning(true)
+	evts := remotestest.Decode(t, codec, pulses)
+	if len(evts) != 1 || evts[0].Codec() != remotes.CODEC_RAW {
+		t.Fatalf("Expected one raw event, got %v", evts)
+	}
+	codec.SetLearning(false)
+
+	// A frame which has been learned is emitted
+	if err := codec.SetPulses(evts[0].Device(), evts[0].ScanCode(), pulses); err != nil {
+		t.Fatal(err)
+	} else if evts_ := remotestest.Decode(t, codec, pulses); len(evts_) != 1 {
+		t.Errorf("Expected one event, got %v", evts_)
+	} else if evts_[0].Device() != evts[0].Device() || evts_[0].ScanCode() != evts[0].ScanCode() {
+		t.Errorf("Unexpected event %v", evts_[0])
+	}
+
+	// Other frames are still not emitted
+	if evts := remotestest.Decode(t, codec, sharpPulses(t, 0x11, 0x5B)); len(evts) != 0 {
+		t.Errorf("Expected no events, got %v", evts)
+	}
+}
+
+func TestEncodeLearned(t *testing.T) {
+	codec := openCodec(t)
+	defer codec.Close()
+
+	pulses := []uint32{9000, 4500, 560, 1690, 560, 560, 560}
+	if _, err := codec.Encode(1, 2, 0); err != remotes.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	} else if err := codec.SetPulses(1, 2, pulses[:6]); err != gopi.ErrBadParameter {
+		t.Errorf("Expected ErrBadParameter for even pulses, got %v", err)
+	} else if err := codec.SetPulses(1, 2, pulses); err != nil {
+		t.Fatal(err)
+	}
+
+	// Repeats are separated by a gap
+	encoded, err := codec.Encode(1, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(encoded) != len(pulses)*2+1 || encoded[len(pulses)] != raw.TX_GAP {
+		t.Fatalf("Unexpected pulses %v", encoded)
+	}
+
+	// Both frames are decoded, and the second is a repeat
+	if evts := remotestest.Decode(t, codec, encoded); len(evts) != 2 {
+		t.Errorf("Expected two events, got %v", evts)
+	} else if evts[0].Device() != 1 || evts[0].ScanCode() != 2 || evts[0].EventType() != gopi.INPUT_EVENT_KEYPRESS {
+		t.Errorf("Unexpected event %v", evts[0])
+	} else if evts[1].EventType() != gopi.INPUT_EVENT_KEYREPEAT {
+		t.Errorf("Expected repeat, got %v", evts[1])
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openCodec(t *testing.T) remotes.RawCodec {
+	t.Helper()
+	return remotestest.OpenCodec(t, raw.Codec{}).(remotes.RawCodec)
+}
+
+func sharpPulses(t *testing.T, device, scancode uint32) []uint32 {
+	t.Helper()
+	codec := remotestest.OpenCodec(t, sharp.Codec{})
+	defer codec.Close()
+	if pulses, err := codec.Encode(device, scancode, 0); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return pulses
+	}
+}
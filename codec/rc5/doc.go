@@ -35,3 +35,12 @@ package rc5
 // the message frame will be repeated every 114ms. The Toggle bit will retain the same logic
 // level during all of these repeated message frames. It is up to the receiver software to interpret
 // this auto-repeat feature of the protocol.
+//
+// Reference:
+//   https://www.sbprojects.net/knowledge/ir/rc5.php
+//
+// Extended RC5 (RC5X) uses the second start bit as a field bit, which is the
+// inverted seventh bit of the command. This allows for 128 commands rather than
+// 64. Frames with the field bit set are decoded as CODEC_RC5 with a 6-bit scancode,
+// and frames with the field bit cleared are decoded as CODEC_RC5X_20 with a 7-bit
+// scancode in the range 0x40 to 0x7F.
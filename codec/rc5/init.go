@@ -19,15 +19,38 @@ import (
 // INIT
 
 func init() {
-	// Register remotes/panasonic
+	// Register remotes/rc5
 	gopi.RegisterModule(gopi.Module{
 		Name:     "remotes/rc5",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rc5.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("rc5.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_RC5,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/rc5x
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/rc5x",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rc5x.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("rc5x.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
-				Type: remotes.CODEC_RC5,
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_RC5X_20,
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
@@ -12,7 +12,6 @@ package rc5
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"time"
 
 	// Frameworks
@@ -24,15 +23,18 @@ import (
 ////////////////////////////////////////////////////////////////////////////////
 // TYPES
 
-// Sony Configuration - for 12, 15 and 20
+// RC5 Configuration - for RC5 and RC5X
 type Codec struct {
-	LIRC gopi.LIRC
-	Type remotes.CodecType
+	LIRC      gopi.LIRC
+	Type      remotes.CodecType
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
 }
 
 type codec struct {
 	log         gopi.Logger
 	lirc        gopi.LIRC
+	carriers    *remotes.Carriers
+	timing      *remotes.Timing
 	codec_type  remotes.CodecType
 	bit_length  uint
 	cancel      context.CancelFunc
@@ -42,7 +44,11 @@ type codec struct {
 	state       state
 	bits        []bool
 	length      uint
-	repeat      bool
+	toggle      bool
+	last_value  uint32
+	last_ts     time.Duration
+	decoding    bool
+	elapsed     time.Duration
 }
 
 type state uint32
@@ -58,7 +64,21 @@ const (
 )
 
 const (
-	TOLERANCE = 35 // 35% tolerance on values
+	TOLERANCE         = 35     // 35% tolerance on values
+	CARRIER_FREQUENCY = 36000  // 36kHz carrier
+	DUTY_CYCLE        = 25     // 25% duty cycle
+	TX_DURATION       = 113792 // 64 bit times (114ms) between the start of each transmission
+	BIT_LENGTH        = 14
+)
+
+const (
+	// Frame bits
+	RC5_START_MASK    = 0x2000
+	RC5_FIELD_MASK    = 0x1000
+	RC5_TOGGLE_MASK   = 0x0800
+	RC5_DEVICE_MASK   = 0x07C0
+	RC5_SCANCODE_MASK = 0x003F
+	RC5X_FIELD_BIT    = 0x0040 // Field bit is the inverted seventh scancode bit for RC5X
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -82,33 +102,42 @@ var (
 func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 	log.Debug("<remotes.Codec.RC5.Open>{ lirc=%v type=%v }", config.LIRC, config.Type)
 
-	// Check for LIRC
-	if config.LIRC == nil {
-		return nil, gopi.ErrBadParameter
-	}
-
 	this := new(codec)
 
 	// Set log and lirc objects
 	this.log = log
 	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
+
+	// Set codec, bit length is always 14 bits
+	if config.Type != remotes.CODEC_RC5 && config.Type != remotes.CODEC_RC5X_20 {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.bit_length = BIT_LENGTH
+		this.codec_type = config.Type
+	}
 
 	// Set up channels
 	this.done = make(chan struct{})
-	this.events = this.lirc.Subscribe()
 	this.subscribers = evt.NewPubSub(0)
 
-	// Set bit length to 14 bits
-	this.bit_length = 14
-	this.codec_type = remotes.CODEC_RC5
-
 	// Reset
-	this.Reset(false)
-
-	// Create background routine
-	if ctx, cancel := context.WithCancel(context.Background()); ctx != nil {
-		this.cancel = cancel
-		go this.acceptEvents(ctx)
+	this.Reset()
+
+	// Create background routine, when there is a LIRC driver
+	if this.lirc != nil {
+		this.events = this.lirc.Subscribe()
+		if ctx, cancel := context.WithCancel(context.Background()); ctx != nil {
+			this.cancel = cancel
+			go this.acceptEvents(ctx)
+		}
 	}
 
 	// Return success
@@ -118,12 +147,13 @@ func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 func (this *codec) Close() error {
 	this.log.Debug("<remotes.Codec.RC5.Close>{ type=%v }", this.codec_type)
 
-	// Unsubscribe from LIRC signals
-	this.lirc.Unsubscribe(this.events)
-
-	// Cancel background thread, wait for done signal
-	this.cancel()
-	_ = <-this.done
+	// Unsubscribe from LIRC signals, cancel background thread
+	// and wait for done signal
+	if this.lirc != nil {
+		this.lirc.Unsubscribe(this.events)
+		this.cancel()
+		_ = <-this.done
+	}
 
 	// Remove subscribers to this codec
 	this.subscribers.Close()
@@ -152,11 +182,27 @@ func (this *codec) Type() remotes.CodecType {
 	return this.codec_type
 }
 
-func (this *codec) Reset(repeat bool) {
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
+func (this *codec) Reset() {
 	this.state = STATE_EXPECT_FIRST_PULSE
 	this.bits = make([]bool, 0, this.bit_length*2)
 	this.length = 0
-	this.repeat = repeat
+	this.timing.Reset()
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -167,16 +213,27 @@ func (this *codec) Subscribe() <-chan gopi.Event {
 }
 
 func (this *codec) Unsubscribe(subscriber <-chan gopi.Event) {
-	this.subscribers.Unsubscribe(subscriber)
+	// Subscribers are removed when the codec is closed
+	if this.subscribers != nil {
+		this.subscribers.Unsubscribe(subscriber)
+	}
 }
 
-func (this *codec) Emit(value uint32, repeat bool) {
+func (this *codec) Emit(value uint32) {
+	// A frame is a repeat when it is the same as the previous frame, including
+	// the toggle bit, and arrives before the next frame would be expected. The
+	// toggle bit is inverted by the remote on each new key press
+	ts := this.now()
+	repeat := value == this.last_value && ts-this.last_ts < 2*TX_DURATION*time.Microsecond
+	this.last_value = value
+	this.last_ts = ts
+
 	if scancode, device, err := codeForCodec(this.codec_type, value); err != nil {
 		if err != gopi.ErrBadParameter {
 			this.log.Warn("Emit: %v", err)
 		}
-	} else {
-		this.subscribers.Emit(remotes.NewRemoteEvent(this, time.Since(timestamp), scancode, device, repeat))
+	} else if this.timing.Accepts(device) {
+		this.subscribers.Emit(remotes.NewRemoteEvent(this, ts, scancode, device, repeat))
 	}
 }
 
@@ -201,42 +258,46 @@ FOR_LOOP:
 
 func (this *codec) receive(evt gopi.LIRCEvent) {
 	this.log.Debug2("<remotes.Codec.RC5.Receive>{ type=%v state=%v evt=%v }", this.codec_type, this.state, evt)
-	//fmt.Println(this.state, evt)
 	switch this.state {
 	case STATE_EXPECT_FIRST_PULSE:
-		if LONG_PULSE.Matches(evt) {
+		if this.timing.Matches(LONG_PULSE, evt) {
 			this.eject(false, true, true)
 			this.state = STATE_EXPECT_SPACE
-		} else if SHORT_PULSE.Matches(evt) {
+		} else if this.timing.Matches(SHORT_PULSE, evt) {
 			this.eject(false, true)
 			this.state = STATE_EXPECT_SPACE
 		} else {
-			this.Reset(false)
+			this.Reset()
 		}
 	case STATE_EXPECT_PULSE:
-		if LONG_PULSE.Matches(evt) {
+		if this.timing.Matches(LONG_PULSE, evt) {
 			this.eject(true, true)
 			this.state = STATE_EXPECT_SPACE
-		} else if SHORT_PULSE.Matches(evt) {
+		} else if this.timing.Matches(SHORT_PULSE, evt) {
 			this.eject(true)
 			this.state = STATE_EXPECT_SPACE
 		} else {
-			this.Reset(false)
+			this.Reset()
 		}
 	case STATE_EXPECT_SPACE:
-		if LONG_SPACE.Matches(evt) {
+		if this.timing.Matches(LONG_SPACE, evt) {
 			this.eject(false, false)
 			this.state = STATE_EXPECT_PULSE
-		} else if SHORT_SPACE.Matches(evt) {
+		} else if this.timing.Matches(SHORT_SPACE, evt) {
 			this.eject(false)
 			this.state = STATE_EXPECT_PULSE
-		} else if REPEAT_SPACE.Matches(evt) {
-			this.Reset(true)
+		} else if this.timing.GreaterThan(REPEAT_SPACE, evt) || evt.Type() == gopi.LIRC_TYPE_TIMEOUT {
+			// A frame ending in a zero bit ends on a space, which merges
+			// with the space after the frame
+			if uint(len(this.bits)) == this.bit_length*2-1 {
+				this.eject(false)
+			}
+			this.Reset()
 		} else {
-			this.Reset(false)
+			this.Reset()
 		}
 	default:
-		this.Reset(false)
+		this.Reset()
 	}
 }
 
@@ -259,35 +320,192 @@ func (this *codec) eject(bits ...bool) {
 				value |= 1
 			}
 		}
-		this.Emit(value, this.repeat)
+		this.Emit(value)
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), codec_type: this.codec_type, bit_length: this.bit_length, subscribers: evt.NewPubSub(0), decoding: true}
+	decoder.Reset()
+	defer decoder.subscribers.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SENDING
 
 func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
-	return gopi.ErrNotImplemented
+	this.log.Debug2("<remotes.Codec.RC5>Send{ codec_type=%v device=0x%08X scancode=0x%08X repeats=%v }", this.codec_type, device, scancode, repeats)
+
+	// The toggle bit is inverted on every new key press, but retains
+	// the same value for repeated frames
+	if this.lirc == nil {
+		this.log.Error("<remotes.Codec.RC5> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.encode(device, scancode, repeats, !this.toggle); err != nil {
+		return err
+	} else {
+		this.toggle = !this.toggle
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+// Encode returns the pulses for a scancode without sending them, using
+// the toggle bit from the last key sent
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	return this.encode(device, scancode, repeats, this.toggle)
+}
+
+func (this *codec) encode(device uint32, scancode uint32, repeats uint, toggle bool) ([]uint32, error) {
+	value, err := valueForCodec(this.codec_type, device, scancode, toggle)
+	if err != nil {
+		this.log.Error("<remotes.Codec.RC5> Encode: %v", err)
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Array of pulses
+	pulses := make([]uint32, 0, 100)
+	for i := uint(0); i < (repeats + 1); i++ {
+		frame := manchesterEncode(value, this.bit_length)
+		pulses = append(pulses, frame...)
+
+		// If repeats then send the space up to the start of the next frame
+		if i < repeats {
+			length := uint32(0)
+			for _, value := range frame {
+				length += value
+			}
+			pulses = append(pulses, TX_DURATION-length)
+		}
+	}
+
+	// Return success
+	return pulses, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
 func codeForCodec(codec remotes.CodecType, value uint32) (uint32, uint32, error) {
+	// scancode is lowest 6 bits (0x03F), device is next 5 bits (7C0)
+	scancode := value & RC5_SCANCODE_MASK
+	device := value & RC5_DEVICE_MASK >> 6
+
+	// The start bit is always set
+	if value&RC5_START_MASK == 0 {
+		return 0, 0, gopi.ErrBadParameter
+	}
+
+	// The field bit is set for RC5, and for RC5X it is the inverted seventh
+	// bit of the scancode. RC5X frames with the field bit set are reported
+	// as RC5
 	switch codec {
 	case remotes.CODEC_RC5:
-		// scancode is lowest 6 bits (0x03F), device is next 5 bits (7C0)
-		scancode := value & 0x003F
-		device := value & 0x07C0 >> 6
-		header := value & 0x3800 >> 11
-		fmt.Printf("value=%v header=%v scancode=%X device=%X\n", strconv.FormatUint(uint64(value), 2), strconv.FormatUint(uint64(header), 2), scancode, device)
-		// TODO: Check header
+		if value&RC5_FIELD_MASK == 0 {
+			return 0, 0, gopi.ErrBadParameter
+		}
 		return scancode, device, nil
+	case remotes.CODEC_RC5X_20:
+		if value&RC5_FIELD_MASK != 0 {
+			return 0, 0, gopi.ErrBadParameter
+		}
+		return scancode | RC5X_FIELD_BIT, device, nil
 	default:
 		return 0, 0, gopi.ErrNotImplemented
 	}
 }
 
+func valueForCodec(codec remotes.CodecType, device, scancode uint32, toggle bool) (uint32, error) {
+	// Start bit, then the device
+	if device&(RC5_DEVICE_MASK>>6) != device {
+		return 0, fmt.Errorf("Invalid device 0x%08X for codec %v", device, codec)
+	}
+	value := RC5_START_MASK | device<<6
+
+	// The field bit and scancode
+	switch codec {
+	case remotes.CODEC_RC5:
+		if scancode&RC5_SCANCODE_MASK != scancode {
+			return 0, fmt.Errorf("Invalid scancode 0x%08X for codec %v", scancode, codec)
+		}
+		value |= RC5_FIELD_MASK | scancode
+	case remotes.CODEC_RC5X_20:
+		if scancode&(RC5X_FIELD_BIT|RC5_SCANCODE_MASK) != scancode {
+			return 0, fmt.Errorf("Invalid scancode 0x%08X for codec %v", scancode, codec)
+		}
+		if scancode&RC5X_FIELD_BIT == 0 {
+			value |= RC5_FIELD_MASK
+		}
+		value |= scancode & RC5_SCANCODE_MASK
+	default:
+		return 0, gopi.ErrNotImplemented
+	}
+
+	// Toggle bit
+	if toggle {
+		value |= RC5_TOGGLE_MASK
+	}
+
+	return value, nil
+}
+
+// manchesterEncode returns the pulses and spaces for a value, most significant
+// bit first. A logical '1' is a space followed by a pulse and a logical '0'
+// is a pulse followed by a space. The leading and trailing spaces are not
+// returned, so the result always starts and ends with a pulse
+func manchesterEncode(value uint32, length uint) []uint32 {
+	// Make the half-bit levels, where true is a pulse
+	levels := make([]bool, 0, length*2)
+	mask := uint32(1) << (length - 1)
+	for i := uint(0); i < length; i++ {
+		if value&mask != 0 {
+			levels = append(levels, false, true)
+		} else {
+			levels = append(levels, true, false)
+		}
+		mask >>= 1
+	}
+
+	// Merge adjacent half-bits with the same level into a single
+	// pulse or space, ignoring any leading space
+	pulses := make([]uint32, 0, length*2)
+	for i := 0; i < len(levels); i++ {
+		if len(pulses) == 0 && levels[i] == false {
+			continue
+		} else if i > 0 && levels[i] == levels[i-1] && len(pulses) > 0 {
+			pulses[len(pulses)-1] += SHORT_PULSE.Value
+		} else {
+			pulses = append(pulses, SHORT_PULSE.Value)
+		}
+	}
+
+	// Remove any trailing space
+	if len(pulses)%2 == 0 {
+		pulses = pulses[:len(pulses)-1]
+	}
+
+	return pulses
+}
+
 func (s state) String() string {
 	switch s {
 	case STATE_EXPECT_FIRST_PULSE:
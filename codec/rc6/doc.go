@@ -0,0 +1,43 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rc6
+
+// Reference:
+//   https://www.sbprojects.net/knowledge/ir/rc6.php
+//   https://github.com/torvalds/linux/blob/master/drivers/media/rc/ir-rc6-decoder.c
+//
+// The Philips RC6 IR transmission protocol uses Manchester encoding of the message
+// bits, at a carrier frequency of 36kHz. The unit of time (t) is 444us. Unlike RC5,
+// logical bits are transmitted as follows:
+//
+//   * Logical '1' – a 444us pulse burst followed by a 444us space
+//   * Logical '0' – a 444us space followed by a 444us pulse burst
+//
+// A message frame consists of the following, in order:
+//
+//   * A leader, which is a 2.666ms pulse (6t) and a 889us space (2t)
+//   * A start bit, which is always a logical '1'
+//   * Three mode bits, which are 000 for mode 0 and 110 for mode 6A
+//   * A trailer bit, which is twice the width of the other bits
+//   * The data bits, most significant bit first
+//
+// In mode 0 the trailer bit is the toggle bit, and the data is an 8-bit address
+// followed by an 8-bit command. In mode 6A the data is 20, 24 or 32 bits, depending
+// on the manufacturer. The address is the top bits and the command is the lowest
+// eight bits.
+//
+// The Windows Media Center (MCE) remote uses mode 6A with 32 bits, where the top 16
+// bits are the OEM code 0x800F. The toggle bit is bit 15, the device is bits 8 to 14
+// and the command is the lowest eight bits. For MCE the device is reported as the OEM
+// code and device combined (for example, 0x00800F04) and the scancode as the command.
+//
+// The toggle bit is inverted each time a key is released and pressed again. As long
+// as the key is kept depressed, the frame is repeated every 106.7ms with the same
+// toggle bit.
@@ -0,0 +1,111 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rc6
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register remotes/rc6_0
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/rc6_0",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rc6_0.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("rc6_0.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_RC6_0,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/rc6_6a_20
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/rc6_6a_20",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rc6_6a_20.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("rc6_6a_20.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_RC6_6A_20,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/rc6_6a_24
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/rc6_6a_24",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rc6_6a_24.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("rc6_6a_24.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_RC6_6A_24,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/rc6_6a_32
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/rc6_6a_32",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rc6_6a_32.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("rc6_6a_32.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_RC6_6A_32,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+
+	// Register remotes/rc6_mce
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/rc6_mce",
+		Requires: []string{"lirc"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("rc6_mce.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("rc6_mce.tolerance")
+			return gopi.Open(Codec{
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_RC6_MCE,
+				Tolerance: uint32(tolerance),
+			}, app.Logger)
+		},
+	})
+}
@@ -0,0 +1,602 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rc6
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RC6 Configuration - for mode 0, mode 6A and MCE
+type Codec struct {
+	LIRC      gopi.LIRC
+	Type      remotes.CodecType
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type codec struct {
+	log          gopi.Logger
+	lirc         gopi.LIRC
+	carriers     *remotes.Carriers
+	timing       *remotes.Timing
+	codec_type   remotes.CodecType
+	mode         uint32
+	bit_length   uint
+	state        state
+	levels       []bool
+	toggle       bool
+	last_value   uint32
+	last_trailer bool
+	last_ts      time.Duration
+	decoding     bool
+	elapsed      time.Duration
+
+	event.Publisher
+	event.Tasks
+}
+
+type state uint32
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// state
+	STATE_EXPECT_HEADER_PULSE state = iota
+	STATE_EXPECT_HEADER_SPACE
+	STATE_EXPECT_PULSE
+	STATE_EXPECT_SPACE
+)
+
+const (
+	TOLERANCE         = 35     // 35% tolerance on values
+	CARRIER_FREQUENCY = 36000  // 36kHz carrier
+	DUTY_CYCLE        = 25     // 25% duty cycle
+	TX_DURATION       = 106560 // 240t (106.7ms) between the start of each transmission
+	UNIT              = 444    // 444us unit of time (t)
+)
+
+const (
+	// Modes
+	MODE_0  = 0
+	MODE_6A = 6
+
+	// Half-bits before the data, which are the start bit, mode bits and trailer bit
+	HEADER_LEVELS = 12
+
+	// MCE OEM code and toggle bit
+	MCE_OEM_MASK    = 0xFFFF0000
+	MCE_OEM_CODE    = 0x800F0000
+	MCE_TOGGLE_MASK = 0x00008000
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	HEADER_PULSE = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 6*UNIT, TOLERANCE) // 2.666ms
+	HEADER_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 2*UNIT, TOLERANCE) // 889us
+	SHORT_PULSE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, UNIT, TOLERANCE)
+	SHORT_SPACE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, UNIT, TOLERANCE)
+	LONG_PULSE   = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 2*UNIT, TOLERANCE)
+	LONG_SPACE   = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 2*UNIT, TOLERANCE)
+	TRAIL_PULSE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 3*UNIT, TOLERANCE) // Around the trailer bit
+	TRAIL_SPACE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 3*UNIT, TOLERANCE) // Around the trailer bit
+)
+
+var (
+	timestamp = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.rc6>Open{ lirc=%v type=%v }", config.LIRC, config.Type)
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
+
+	// Set codec, mode and bit length
+	if mode, bit_length := modeForCodec(config.Type); bit_length == 0 {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.mode = mode
+		this.bit_length = bit_length
+		this.codec_type = config.Type
+	}
+
+	// Reset state
+	this.Reset()
+
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *codec) Close() error {
+	this.log.Debug("<remotes.codec.rc6>Close>{ type=%v }", this.codec_type)
+
+	// Remove subscribers to this codec
+	this.Publisher.Close()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.lirc = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	return fmt.Sprintf("<remotes.Codec.RC6>{ type=%v mode=%v bit_length=%v }", this.codec_type, this.mode, this.bit_length)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *codec) Type() remotes.CodecType {
+	return this.codec_type
+}
+
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
+func (this *codec) Reset() {
+	this.state = STATE_EXPECT_HEADER_PULSE
+	this.levels = make([]bool, 0, HEADER_LEVELS+this.bit_length*2)
+	this.timing.Reset()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *codec) Emit(mode uint32, length uint, trailer bool, value uint32) {
+	if scancode, device, err := codeForCodec(this.codec_type, mode, length, value); err != nil {
+		if err != gopi.ErrBadParameter {
+			this.log.Warn("Emit: %v", err)
+		}
+	} else if this.timing.Accepts(device) {
+		// A frame is a repeat when it is the same as the previous frame, including
+		// the toggle bit, and arrives before the next frame would be expected. The
+		// toggle bit is the trailer bit in mode 0 and part of the value for MCE.
+		// There is no previous frame when last_ts is zero
+		ts := this.now()
+		repeat := this.last_ts != 0 && value == this.last_value && trailer == this.last_trailer && ts-this.last_ts < 2*TX_DURATION*time.Microsecond
+		this.last_value = value
+		this.last_trailer = trailer
+		this.last_ts = ts
+
+		this.Publisher.Emit(remotes.NewRemoteEvent(this, ts, scancode, device, repeat))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *codec) PulseTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	events := this.lirc.Subscribe()
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt != nil {
+				this.receive(evt.(gopi.LIRCEvent))
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.lirc.Unsubscribe(events)
+
+	// Success
+	return nil
+}
+
+func (this *codec) receive(evt gopi.LIRCEvent) {
+	this.log.Debug2("<remotes.codec.rc6>Receive{ type=%v state=%v evt=%v }", this.codec_type, this.state, evt)
+	switch this.state {
+	case STATE_EXPECT_HEADER_PULSE:
+		if this.timing.Matches(HEADER_PULSE, evt) {
+			this.state = STATE_EXPECT_HEADER_SPACE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_HEADER_SPACE:
+		if this.timing.Matches(HEADER_SPACE, evt) {
+			this.state = STATE_EXPECT_PULSE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_PULSE:
+		if units := this.unitsForEvent(evt, SHORT_PULSE, LONG_PULSE, TRAIL_PULSE); units == 0 {
+			this.Reset()
+		} else if this.eject(true, units) {
+			this.state = STATE_EXPECT_SPACE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_SPACE:
+		if units := this.unitsForEvent(evt, SHORT_SPACE, LONG_SPACE, TRAIL_SPACE); units != 0 {
+			if this.eject(false, units) {
+				this.state = STATE_EXPECT_PULSE
+			} else {
+				this.Reset()
+			}
+		} else if (evt.Type() == gopi.LIRC_TYPE_SPACE && this.timing.LessThan(TRAIL_SPACE, evt) == false) || evt.Type() == gopi.LIRC_TYPE_TIMEOUT {
+			// End of the frame, where any final space merges with the space after the frame
+			if mode, length, trailer, value, err := decodeLevels(this.levels); err == nil {
+				this.Emit(mode, length, trailer, value)
+			}
+			this.Reset()
+		} else {
+			this.Reset()
+		}
+	default:
+		this.Reset()
+	}
+}
+
+// eject appends half-bit levels and returns false if there are
+// more levels than expected for the codec
+func (this *codec) eject(level bool, units uint) bool {
+	for i := uint(0); i < units; i++ {
+		this.levels = append(this.levels, level)
+	}
+	return uint(len(this.levels)) <= HEADER_LEVELS+this.bit_length*2
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), codec_type: this.codec_type, mode: this.mode, bit_length: this.bit_length, decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SENDING
+
+func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
+	this.log.Debug2("<remotes.codec.rc6>Send{ codec_type=%v device=0x%08X scancode=0x%08X repeats=%v }", this.codec_type, device, scancode, repeats)
+
+	// The toggle bit is inverted on every new key press, but retains
+	// the same value for repeated frames
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.rc6> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.encode(device, scancode, repeats, !this.toggle); err != nil {
+		return err
+	} else {
+		this.toggle = !this.toggle
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+// Encode returns the pulses for a scancode with the toggle bit of the
+// last key press sent
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	return this.encode(device, scancode, repeats, this.toggle)
+}
+
+func (this *codec) encode(device uint32, scancode uint32, repeats uint, toggle bool) ([]uint32, error) {
+	trailer, value, err := valueForCodec(this.codec_type, device, scancode, toggle)
+	if err != nil {
+		this.log.Error("<remotes.codec.rc6> Encode: %v", err)
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Array of pulses
+	pulses := make([]uint32, 0, 100)
+	for i := uint(0); i < (repeats + 1); i++ {
+		frame := pulsesForFrame(this.mode, this.bit_length, trailer, value)
+		pulses = append(pulses, frame...)
+
+		// If repeats then send the space up to the start of the next frame
+		if i < repeats {
+			length := uint32(0)
+			for _, value := range frame {
+				length += value
+			}
+			pulses = append(pulses, TX_DURATION-length)
+		}
+	}
+
+	// Return the pulses
+	return pulses, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
+func modeForCodec(codec remotes.CodecType) (uint32, uint) {
+	switch codec {
+	case remotes.CODEC_RC6_0:
+		return MODE_0, 16
+	case remotes.CODEC_RC6_6A_20:
+		return MODE_6A, 20
+	case remotes.CODEC_RC6_6A_24:
+		return MODE_6A, 24
+	case remotes.CODEC_RC6_6A_32, remotes.CODEC_RC6_MCE:
+		return MODE_6A, 32
+	default:
+		return 0, 0
+	}
+}
+
+func codeForCodec(codec remotes.CodecType, mode uint32, length uint, value uint32) (uint32, uint32, error) {
+	// Check mode and length against the codec
+	if mode_, length_ := modeForCodec(codec); length_ == 0 {
+		return 0, 0, gopi.ErrNotImplemented
+	} else if mode != mode_ || length != length_ {
+		return 0, 0, gopi.ErrBadParameter
+	}
+
+	// The command is always the lowest eight bits. MCE removes the toggle
+	// bit from the device, and 32-bit frames with the MCE OEM code are
+	// not reported as RC6_6A_32
+	scancode := value & 0xFF
+	switch codec {
+	case remotes.CODEC_RC6_MCE:
+		if value&MCE_OEM_MASK != MCE_OEM_CODE {
+			return 0, 0, gopi.ErrBadParameter
+		}
+		return scancode, (value &^ MCE_TOGGLE_MASK) >> 8, nil
+	case remotes.CODEC_RC6_6A_32:
+		if value&MCE_OEM_MASK == MCE_OEM_CODE {
+			return 0, 0, gopi.ErrBadParameter
+		}
+		return scancode, value >> 8, nil
+	default:
+		return scancode, value >> 8, nil
+	}
+}
+
+func valueForCodec(codec remotes.CodecType, device, scancode uint32, toggle bool) (bool, uint32, error) {
+	_, length := modeForCodec(codec)
+	if length == 0 {
+		return false, 0, gopi.ErrNotImplemented
+	}
+
+	// Scancode is always eight bits
+	if scancode&0xFF != scancode {
+		return false, 0, fmt.Errorf("Invalid scancode 0x%08X for codec %v", scancode, codec)
+	}
+
+	// Check device and set toggle bit
+	device_mask := uint32(1)<<(length-8) - 1
+	switch codec {
+	case remotes.CODEC_RC6_0:
+		if device&device_mask != device {
+			return false, 0, fmt.Errorf("Invalid device 0x%08X for codec %v", device, codec)
+		}
+		return toggle, device<<8 | scancode, nil
+	case remotes.CODEC_RC6_MCE:
+		if device&^0x7F != MCE_OEM_CODE>>8 {
+			return false, 0, fmt.Errorf("Invalid device 0x%08X for codec %v", device, codec)
+		}
+		value := device<<8 | scancode
+		if toggle {
+			value |= MCE_TOGGLE_MASK
+		}
+		return false, value, nil
+	case remotes.CODEC_RC6_6A_32:
+		if device&device_mask != device {
+			return false, 0, fmt.Errorf("Invalid device 0x%08X for codec %v", device, codec)
+		}
+		if device<<8&MCE_OEM_MASK == MCE_OEM_CODE {
+			return false, 0, fmt.Errorf("Invalid device 0x%08X for codec %v (use %v)", device, codec, remotes.CODEC_RC6_MCE)
+		}
+		return false, device<<8 | scancode, nil
+	default:
+		if device&device_mask != device {
+			return false, 0, fmt.Errorf("Invalid device 0x%08X for codec %v", device, codec)
+		}
+		return false, device<<8 | scancode, nil
+	}
+}
+
+// unitsForEvent returns the number of units of time (t) for a pulse or space,
+// or zero if the value doesn't match
+func (this *codec) unitsForEvent(evt gopi.LIRCEvent, short, long, trail *remotes.MarkSpace) uint {
+	if this.timing.Matches(short, evt) {
+		return 1
+	} else if this.timing.Matches(long, evt) {
+		return 2
+	} else if this.timing.Matches(trail, evt) {
+		return 3
+	} else {
+		return 0
+	}
+}
+
+// decodeLevels returns the mode, data length, trailer bit and data
+// from half-bit levels received after the leader, where true is a pulse
+func decodeLevels(levels []bool) (uint32, uint, bool, uint32, error) {
+	// A frame ending in a logical '1' ends on a space
+	if len(levels)%2 == 1 {
+		levels = append(levels, false)
+	}
+	if len(levels) < HEADER_LEVELS || len(levels) > HEADER_LEVELS+64 {
+		return 0, 0, false, 0, gopi.ErrBadParameter
+	}
+
+	// Start bit is always a logical '1'
+	if start, err := manchesterBit(levels[0], levels[1]); err != nil || start == false {
+		return 0, 0, false, 0, gopi.ErrBadParameter
+	}
+
+	// Mode bits
+	mode := uint32(0)
+	for i := 2; i < 8; i += 2 {
+		bit, err := manchesterBit(levels[i], levels[i+1])
+		if err != nil {
+			return 0, 0, false, 0, err
+		}
+		mode <<= 1
+		if bit {
+			mode |= 1
+		}
+	}
+
+	// Trailer bit is double width
+	trailer, err := manchesterBit(levels[8], levels[10])
+	if err != nil || levels[8] != levels[9] || levels[10] != levels[11] {
+		return 0, 0, false, 0, gopi.ErrBadParameter
+	}
+
+	// Data bits
+	value := uint32(0)
+	for i := HEADER_LEVELS; i < len(levels); i += 2 {
+		bit, err := manchesterBit(levels[i], levels[i+1])
+		if err != nil {
+			return 0, 0, false, 0, err
+		}
+		value <<= 1
+		if bit {
+			value |= 1
+		}
+	}
+
+	// Return success
+	return mode, uint(len(levels)-HEADER_LEVELS) / 2, trailer, value, nil
+}
+
+// manchesterBit returns a logical '1' for a pulse followed by a space,
+// and a logical '0' for a space followed by a pulse
+func manchesterBit(first, second bool) (bool, error) {
+	if first == second {
+		return false, gopi.ErrBadParameter
+	} else {
+		return first, nil
+	}
+}
+
+// pulsesForFrame returns the pulses and spaces for a frame, starting
+// with the leader and ending with a pulse
+func pulsesForFrame(mode uint32, length uint, trailer bool, value uint32) []uint32 {
+	// Start bit, mode bits, trailer bit and data bits as half-bit levels,
+	// where true is a pulse
+	levels := make([]bool, 0, HEADER_LEVELS+length*2)
+	levels = appendLevels(levels, 1, 1)
+	levels = appendLevels(levels, mode, 3)
+	if trailer {
+		levels = append(levels, true, true, false, false)
+	} else {
+		levels = append(levels, false, false, true, true)
+	}
+	levels = appendLevels(levels, value, length)
+
+	// Merge adjacent half-bits with the same level into a single pulse
+	// or space. The start bit always begins with a pulse after the leader
+	pulses := []uint32{HEADER_PULSE.Value, HEADER_SPACE.Value}
+	for i := range levels {
+		if i > 0 && levels[i] == levels[i-1] {
+			pulses[len(pulses)-1] += UNIT
+		} else {
+			pulses = append(pulses, UNIT)
+		}
+	}
+
+	// Remove any trailing space
+	if len(pulses)%2 == 0 {
+		pulses = pulses[:len(pulses)-1]
+	}
+
+	return pulses
+}
+
+func appendLevels(levels []bool, value uint32, length uint) []bool {
+	mask := uint32(1) << (length - 1)
+	for i := uint(0); i < length; i++ {
+		if value&mask != 0 {
+			levels = append(levels, true, false)
+		} else {
+			levels = append(levels, false, true)
+		}
+		mask >>= 1
+	}
+	return levels
+}
+
+func (s state) String() string {
+	switch s {
+	case STATE_EXPECT_HEADER_PULSE:
+		return "STATE_EXPECT_HEADER_PULSE"
+	case STATE_EXPECT_HEADER_SPACE:
+		return "STATE_EXPECT_HEADER_SPACE"
+	case STATE_EXPECT_PULSE:
+		return "STATE_EXPECT_PULSE"
+	case STATE_EXPECT_SPACE:
+		return "STATE_EXPECT_SPACE"
+	default:
+		return "[?? Invalid state]"
+	}
+}
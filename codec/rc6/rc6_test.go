@@ -0,0 +1,178 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package rc6_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/rc6"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	FRAME_PERIOD = 106560 // Each frame starts 106.56ms after the previous frame
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+		repeats          uint
+	}{
+		{remotes.CODEC_RC6_0, 0x00, 0x00, 0},
+		{remotes.CODEC_RC6_0, 0xFF, 0xFF, 0},
+		{remotes.CODEC_RC6_0, 0x00, 0x0C, 1},
+		{remotes.CODEC_RC6_0, 0x00, 0x0C, 3},
+		{remotes.CODEC_RC6_6A_20, 0x000, 0x00, 0},
+		{remotes.CODEC_RC6_6A_20, 0xFFF, 0xFF, 0},
+		{remotes.CODEC_RC6_6A_20, 0x123, 0x45, 1},
+		{remotes.CODEC_RC6_6A_24, 0x0000, 0x00, 0},
+		{remotes.CODEC_RC6_6A_24, 0xFFFF, 0xFF, 0},
+		{remotes.CODEC_RC6_6A_24, 0x1234, 0x56, 1},
+		{remotes.CODEC_RC6_6A_32, 0x000000, 0x00, 0},
+		{remotes.CODEC_RC6_6A_32, 0xFFFFFF, 0xFF, 0},
+		{remotes.CODEC_RC6_6A_32, 0x123456, 0x78, 1},
+		{remotes.CODEC_RC6_MCE, 0x800F00, 0x00, 0},
+		{remotes.CODEC_RC6_MCE, 0x800F7F, 0xFF, 0},
+		{remotes.CODEC_RC6_MCE, 0x800F04, 0x0D, 1},
+		{remotes.CODEC_RC6_MCE, 0x800F04, 0x0D, 3},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, rc6.Codec{Type: test.codec_type})
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("%v device=0x%X scancode=0x%X: %v", test.codec_type, test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, test.codec_type, remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+	}{
+		{remotes.CODEC_RC6_0, 0x100, 0x00},
+		{remotes.CODEC_RC6_0, 0x00, 0x100},
+		{remotes.CODEC_RC6_6A_20, 0x1000, 0x00},
+		{remotes.CODEC_RC6_6A_24, 0x10000, 0x00},
+		{remotes.CODEC_RC6_6A_32, 0x1000000, 0x00},
+		{remotes.CODEC_RC6_6A_32, 0x800F04, 0x00},
+		{remotes.CODEC_RC6_MCE, 0x800F80, 0x00},
+		{remotes.CODEC_RC6_MCE, 0x123456, 0x00},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, rc6.Codec{Type: test.codec_type})
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("%v device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.codec_type, test.device, test.scancode, err)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodePulses(t *testing.T) {
+	// A 6t leader pulse and 2t space, where t is 444us, then the start bit,
+	// three mode bits, a double-width trailer bit and the data bits most
+	// significant bit first. Each level lasts t, and a one is a pulse
+	// followed by a space
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+		levels           string
+	}{
+		{remotes.CODEC_RC6_0, 0x00, 0x0C, "11111100" + "10" + "010101" + "0011" + "0101010101010101" + "0101010110100101"},
+		{remotes.CODEC_RC6_6A_20, 0x123, 0x45, "11111100" + "10" + "101001" + "0011" + "0101011001011001" + "0101101001100101" + "01100110"},
+		{remotes.CODEC_RC6_MCE, 0x800F04, 0x0D, "11111100" + "10" + "101001" + "0011" + "1001010101010101" + "0101010110101010" + "0101010101100101" + "0101010110100110"},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, rc6.Codec{Type: test.codec_type})
+		if pulses, err := codec.Encode(test.device, test.scancode, 0); err != nil {
+			t.Errorf("%v: %v", test.codec_type, err)
+		} else if expected := levelPulses(test.levels); remotestest.Equals(pulses, expected) == false {
+			t.Errorf("%v: Expected %v, got %v", test.codec_type, expected, pulses)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodeRepeats(t *testing.T) {
+	codec := remotestest.OpenCodec(t, rc6.Codec{Type: remotes.CODEC_RC6_MCE})
+	defer codec.Close()
+
+	// Each frame starts 106.56ms (240t) after the start of the previous frame
+	pulses, err := codec.Encode(0x800F04, 0x0D, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame := levelPulses("11111100" + "10" + "101001" + "0011" + "1001010101010101" + "0101010110101010" + "0101010101100101" + "0101010110100110")
+	if expected := remotestest.Frames(FRAME_PERIOD, frame, frame, frame); remotestest.Equals(pulses, expected) == false {
+		t.Errorf("Expected %v, got %v", expected, pulses)
+	}
+}
+
+func TestToggle(t *testing.T) {
+	codec := remotestest.OpenCodec(t, rc6.Codec{Type: remotes.CODEC_RC6_MCE})
+	defer codec.Close()
+
+	// The MCE toggle bit is bit 15 of the data, and is removed from the device.
+	// A frame with the same toggle bit is a repeat
+	frame := levelPulses("11111100" + "10" + "101001" + "0011" + "1001010101010101" + "0101010110101010" + "0101010101100101" + "0101010110100110")
+	toggled := levelPulses("11111100" + "10" + "101001" + "0011" + "1001010101010101" + "0101010110101010" + "1001010101100101" + "0101010110100110")
+	evts := remotestest.Decode(t, codec, remotestest.Frames(FRAME_PERIOD, frame, frame, toggled))
+	if len(evts) != 3 {
+		t.Fatalf("Expected three events, got %v", evts)
+	}
+	for i, evt := range evts {
+		if evt.Device() != 0x800F04 || evt.ScanCode() != 0x0D {
+			t.Errorf("Unexpected event %v", evt)
+		} else if repeat := evt.EventType() == gopi.INPUT_EVENT_KEYREPEAT; repeat != (i == 1) {
+			t.Errorf("Unexpected repeat flag for event %v: %v", i, evt)
+		}
+	}
+}
+
+func TestCarrier(t *testing.T) {
+	codec := remotestest.OpenCodec(t, rc6.Codec{Type: remotes.CODEC_RC6_0})
+	defer codec.Close()
+
+	if carrier := codec.Carrier(0); carrier.Frequency != 36000 || carrier.DutyCycle != 25 {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// levelPulses returns pulses for levels of 444us, where a one is a pulse,
+// merging levels which are the same and removing any trailing space
+func levelPulses(levels string) []uint32 {
+	pulses := make([]uint32, 0, len(levels))
+	for i := range levels {
+		if i > 0 && levels[i] == levels[i-1] {
+			pulses[len(pulses)-1] += 444
+		} else {
+			pulses = append(pulses, 444)
+		}
+	}
+	if len(pulses)%2 == 0 {
+		pulses = pulses[:len(pulses)-1]
+	}
+	return pulses
+}
@@ -0,0 +1,44 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package sanyo
+
+// Reference:
+//   https://www.sbprojects.net/knowledge/ir/sanyo.php
+//
+// The Sanyo IR transmission protocol (based on the LC7461 chip) uses pulse
+// distance encoding of the message bits, at a carrier frequency of 38kHz.
+// Each pulse burst is 560µs in length. Logical bits are transmitted as
+// follows:
+//
+//   * Logical '0' – a 560µs pulse burst followed by a 560µs space
+//   * Logical '1' – a 560µs pulse burst followed by a 1.69ms space
+//
+// When a key is pressed on the remote controller, the message transmitted
+// consists of the following, in order:
+//
+//  * A 9ms leading pulse burst
+//  * A 4.5ms space
+//  * The 13-bit custom code for the receiving device
+//  * The 13-bit logical inverse of the custom code
+//  * The 8-bit key data
+//  * The 8-bit logical inverse of the key data
+//  * A final 560µs pulse burst to signify the end of message transmission.
+//
+// All values are sent least significant bit first. Because every bit is
+// sent along with its inverse, the message is always 84.9ms in length.
+// Messages with a custom code or key data which doesn't match its inverse
+// are discarded.
+//
+// REPEAT CODES
+//
+// If the key on the remote controller is kept depressed, a repeat code
+// will be issued every 108ms from the start of the previous message. The
+// repeat code consists of a 9ms leading pulse burst, a 2.25ms space and a
+// 560µs pulse burst.
@@ -0,0 +1,427 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package sanyo
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Sanyo Configuration
+type Codec struct {
+	LIRC      gopi.LIRC
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type codec struct {
+	log         gopi.Logger
+	lirc        gopi.LIRC
+	carriers    *remotes.Carriers
+	timing      *remotes.Timing
+	state       state
+	value       uint64
+	length      uint
+	last_valid  bool
+	last_device uint32
+	last_code   uint32
+	last_ts     time.Duration
+	frame_ts    time.Duration
+	decoding    bool
+	elapsed     time.Duration
+
+	event.Publisher
+	event.Tasks
+}
+
+type state uint32
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// state
+	STATE_EXPECT_HEADER_PULSE state = iota
+	STATE_EXPECT_HEADER_SPACE
+	STATE_EXPECT_PULSE
+	STATE_EXPECT_SPACE
+	STATE_EXPECT_TRAIL_PULSE
+	STATE_EXPECT_REPEAT_PULSE
+)
+
+const (
+	TOLERANCE         = 35     // 35% tolerance on values
+	CARRIER_FREQUENCY = 38000  // 38kHz carrier
+	DUTY_CYCLE        = 33     // 33% duty cycle
+	BIT_LENGTH        = 42     // 13 custom code bits, 13 inverted, 8 data bits, 8 inverted
+	TX_DURATION       = 108000 // 108ms between the start of each transmission
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	HEADER_PULSE = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 9000, TOLERANCE) // 9ms
+	HEADER_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 4500, TOLERANCE) // 4.5ms
+	REPEAT_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 2250, TOLERANCE) // 2.25ms
+	BIT_PULSE    = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 560, TOLERANCE)
+	ONE_SPACE    = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 1690, TOLERANCE)
+	ZERO_SPACE   = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 560, TOLERANCE)
+	TRAIL_PULSE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 560, TOLERANCE)
+)
+
+var (
+	timestamp = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.sanyo>Open{ lirc=%v }", config.LIRC)
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
+
+	// Reset state
+	this.Reset()
+
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *codec) Close() error {
+	this.log.Debug("<remotes.codec.sanyo>Close>{}")
+
+	// Remove subscribers to this codec
+	this.Publisher.Close()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.lirc = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	return fmt.Sprintf("<remotes.Codec.Sanyo>{}")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *codec) Type() remotes.CodecType {
+	return remotes.CODEC_SANYO
+}
+
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
+func (this *codec) Reset() {
+	this.next()
+	this.last_valid = false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *codec) Emit(value uint64) {
+	if scancode, device, err := codeForCodec(value); err != nil {
+		this.log.Warn("Emit: %v", err)
+		this.last_valid = false
+	} else if this.timing.Accepts(device) == false {
+		this.last_valid = false
+	} else {
+		this.last_valid = true
+		this.last_device = device
+		this.last_code = scancode
+		this.last_ts = this.frame_ts
+		this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, false))
+	}
+}
+
+func (this *codec) EmitRepeat() {
+	// Repeat codes are only emitted when the previous frame was valid,
+	// and started within the repeat period
+	if this.last_valid && this.frame_ts-this.last_ts < TX_DURATION*time.Microsecond*(100+TOLERANCE)/100 && this.timing.Accepts(this.last_device) {
+		this.last_ts = this.frame_ts
+		this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), this.last_code, this.last_device, true))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *codec) PulseTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	events := this.lirc.Subscribe()
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt != nil {
+				this.receive(evt.(gopi.LIRCEvent))
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.lirc.Unsubscribe(events)
+
+	// Success
+	return nil
+}
+
+func (this *codec) receive(evt gopi.LIRCEvent) {
+	this.log.Debug2("<remotes.codec.sanyo>Receive{ state=%v evt=%v }", this.state, evt)
+
+	// A timeout or a gap longer than the repeat period ends the transmission,
+	// and any repeat codes which follow are ignored
+	if evt.Type() == gopi.LIRC_TYPE_TIMEOUT || (evt.Type() == gopi.LIRC_TYPE_SPACE && evt.Value() > TX_DURATION) {
+		this.Reset()
+		return
+	}
+
+	switch this.state {
+	case STATE_EXPECT_HEADER_PULSE:
+		if this.timing.Matches(HEADER_PULSE, evt) {
+			this.frame_ts = this.now()
+			this.state = STATE_EXPECT_HEADER_SPACE
+		} else {
+			this.next()
+		}
+	case STATE_EXPECT_HEADER_SPACE:
+		if this.timing.Matches(HEADER_SPACE, evt) {
+			this.state = STATE_EXPECT_PULSE
+		} else if this.timing.Matches(REPEAT_SPACE, evt) {
+			this.state = STATE_EXPECT_REPEAT_PULSE
+		} else {
+			this.next()
+		}
+	case STATE_EXPECT_PULSE:
+		if this.timing.Matches(BIT_PULSE, evt) {
+			this.state = STATE_EXPECT_SPACE
+		} else {
+			this.next()
+		}
+	case STATE_EXPECT_SPACE:
+		// Register a zero or one, least significant bit first
+		if this.timing.Matches(ZERO_SPACE, evt) {
+			this.length = this.length + 1
+		} else if this.timing.Matches(ONE_SPACE, evt) {
+			this.value |= 1 << this.length
+			this.length = this.length + 1
+		} else {
+			this.next()
+			return
+		}
+
+		// Advance state to expect the trailing pulse
+		if this.length == BIT_LENGTH {
+			this.state = STATE_EXPECT_TRAIL_PULSE
+		} else {
+			this.state = STATE_EXPECT_PULSE
+		}
+	case STATE_EXPECT_TRAIL_PULSE:
+		if this.timing.Matches(TRAIL_PULSE, evt) {
+			this.Emit(this.value)
+		}
+		this.next()
+	case STATE_EXPECT_REPEAT_PULSE:
+		if this.timing.Matches(TRAIL_PULSE, evt) {
+			this.EmitRepeat()
+		}
+		this.next()
+	default:
+		this.next()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SENDING
+
+func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
+	this.log.Debug2("<remotes.codec.sanyo>Send{ device=0x%08X scancode=0x%08X repeats=%v }", device, scancode, repeats)
+
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.sanyo> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	value, err := valueForCodec(device, scancode)
+	if err != nil {
+		this.log.Error("<remotes.codec.sanyo> Encode: %v", err)
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Array of pulses
+	pulses := make([]uint32, 0, 100)
+	pulses = append(pulses, HEADER_PULSE.Value, HEADER_SPACE.Value)
+	for i := uint(0); i < BIT_LENGTH; i++ {
+		pulses = append(pulses, BIT_PULSE.Value)
+		if value&(1<<i) == 0 { // Send zero
+			pulses = append(pulses, ZERO_SPACE.Value)
+		} else {
+			// Send one
+			pulses = append(pulses, ONE_SPACE.Value)
+		}
+	}
+	pulses = append(pulses, TRAIL_PULSE.Value)
+
+	// Send the repeat codes, each starting TX_DURATION after the
+	// start of the previous message
+	length := uint32(0)
+	for _, value := range pulses {
+		length += value
+	}
+	for i := uint(0); i < repeats; i++ {
+		pulses = append(pulses, TX_DURATION-length)
+		pulses = append(pulses, HEADER_PULSE.Value, REPEAT_SPACE.Value, TRAIL_PULSE.Value)
+		length = HEADER_PULSE.Value + REPEAT_SPACE.Value + TRAIL_PULSE.Value
+	}
+
+	// Return the pulses
+	return pulses, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// next clears the state to receive the next frame or repeat code
+func (this *codec) next() {
+	this.state = STATE_EXPECT_HEADER_PULSE
+	this.value = 0
+	this.length = 0
+	this.timing.Reset()
+}
+
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
+// codeForCodec returns the scancode and device from a 42-bit value, or
+// returns an error if the custom code or key data don't match their inverse
+func codeForCodec(value uint64) (uint32, uint32, error) {
+	device := uint32(value & 0x1FFF)
+	device_inverse := uint32(value>>13) & 0x1FFF
+	scancode := uint32(value>>26) & 0xFF
+	scancode_inverse := uint32(value>>34) & 0xFF
+	if device != device_inverse^0x1FFF {
+		return 0, 0, fmt.Errorf("Custom code 0x%04X does not match inverse 0x%04X", device, device_inverse)
+	}
+	if scancode != scancode_inverse^0xFF {
+		return 0, 0, fmt.Errorf("Key data 0x%02X does not match inverse 0x%02X", scancode, scancode_inverse)
+	}
+	return scancode, device, nil
+}
+
+// valueForCodec returns the 42-bit value to transmit for a device and scancode
+func valueForCodec(device, scancode uint32) (uint64, error) {
+	if device&0x1FFF != device {
+		return 0, fmt.Errorf("Invalid device 0x%08X for codec %v", device, remotes.CODEC_SANYO)
+	}
+	if scancode&0xFF != scancode {
+		return 0, fmt.Errorf("Invalid scancode 0x%08X for codec %v", scancode, remotes.CODEC_SANYO)
+	}
+	value := uint64(device)
+	value |= uint64(device^0x1FFF) << 13
+	value |= uint64(scancode) << 26
+	value |= uint64(scancode^0xFF) << 34
+	return value, nil
+}
+
+func (s state) String() string {
+	switch s {
+	case STATE_EXPECT_HEADER_PULSE:
+		return "STATE_EXPECT_HEADER_PULSE"
+	case STATE_EXPECT_HEADER_SPACE:
+		return "STATE_EXPECT_HEADER_SPACE"
+	case STATE_EXPECT_PULSE:
+		return "STATE_EXPECT_PULSE"
+	case STATE_EXPECT_SPACE:
+		return "STATE_EXPECT_SPACE"
+	case STATE_EXPECT_TRAIL_PULSE:
+		return "STATE_EXPECT_TRAIL_PULSE"
+	case STATE_EXPECT_REPEAT_PULSE:
+		return "STATE_EXPECT_REPEAT_PULSE"
+	default:
+		return "[?? Invalid state]"
+	}
+}
@@ -0,0 +1,158 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package sanyo_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/sanyo"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	FRAME_PERIOD = 108000 // Each frame starts 108ms after the previous frame
+)
+
+var (
+	// A 560us pulse for each bit, least significant bit first, followed by
+	// a 560us space for a zero or a 1690us space for a one
+	SANYO_BITS = remotestest.PulseDistance{Pulse: 560, Zero: 560, One: 1690}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sanyo.Codec{})
+	defer codec.Close()
+
+	tests := []struct {
+		device, scancode uint32
+		repeats          uint
+	}{
+		{0x00, 0x00, 0},
+		{0x1FFF, 0xFF, 0},
+		{0x1234, 0x56, 0},
+		{0x1234, 0x56, 1},
+		{0x1234, 0x56, 3},
+	}
+	for _, test := range tests {
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("device=0x%X scancode=0x%X: %v", test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, remotes.CODEC_SANYO, remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sanyo.Codec{})
+	defer codec.Close()
+
+	tests := []struct {
+		device, scancode uint32
+	}{
+		{0x2000, 0x00},
+		{0x00, 0x100},
+	}
+	for _, test := range tests {
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.device, test.scancode, err)
+		}
+	}
+}
+
+func TestEncodePulses(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sanyo.Codec{})
+	defer codec.Close()
+
+	// A 9ms header pulse and 4.5ms space, then the 13 device bits, the
+	// inverted device bits, the scancode and the inverted scancode, least
+	// significant bit first, and a trail pulse. Each repeat is a 9ms pulse,
+	// 2.25ms space and trail pulse, starting 108ms after the previous frame
+	frame := []uint32{9000, 4500}
+	frame = append(frame, SANYO_BITS.Pulses(0x1234, 13)...)
+	frame = append(frame, SANYO_BITS.Pulses(0x1234^0x1FFF, 13)...)
+	frame = append(frame, SANYO_BITS.Pulses(0x56, 8)...)
+	frame = append(frame, SANYO_BITS.Pulses(0x56^0xFF, 8)...)
+	frame = append(frame, 560)
+	repeat := []uint32{9000, 2250, 560}
+	expected := remotestest.Frames(FRAME_PERIOD, frame, repeat, repeat)
+	if pulses, err := codec.Encode(0x1234, 0x56, 2); err != nil {
+		t.Fatal(err)
+	} else if remotestest.Equals(pulses, expected) == false {
+		t.Errorf("Expected %v, got %v", expected, pulses)
+	}
+}
+
+func TestInvertedBits(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sanyo.Codec{})
+	defer codec.Close()
+
+	// Frames are ignored when the inverted device or scancode don't match
+	tests := []struct {
+		device, device_inverse     uint32
+		scancode, scancode_inverse uint32
+	}{
+		{0x1234, 0x1234, 0x56, 0x56 ^ 0xFF},
+		{0x1234, 0x1234 ^ 0x1FFF, 0x56, 0x56},
+		{0x1234, 0x1235 ^ 0x1FFF, 0x56, 0x56 ^ 0xFF},
+	}
+	for _, test := range tests {
+		frame := []uint32{9000, 4500}
+		frame = append(frame, SANYO_BITS.Pulses(uint64(test.device), 13)...)
+		frame = append(frame, SANYO_BITS.Pulses(uint64(test.device_inverse), 13)...)
+		frame = append(frame, SANYO_BITS.Pulses(uint64(test.scancode), 8)...)
+		frame = append(frame, SANYO_BITS.Pulses(uint64(test.scancode_inverse), 8)...)
+		frame = append(frame, 560)
+		if evts := remotestest.Decode(t, codec, frame); len(evts) != 0 {
+			t.Errorf("Expected no events, got %v", evts)
+		}
+	}
+}
+
+func TestStaleRepeat(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sanyo.Codec{})
+	defer codec.Close()
+
+	// Repeat codes are ignored after a timeout, a gap longer than the repeat
+	// period, or when they start more than 108ms after the previous frame
+	frame, err := codec.Encode(0x1234, 0x56, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repeat := []uint32{9000, 2250, 560}
+	remotestest.CheckEvents(t, remotes.CODEC_SANYO, remotestest.Decode(t, codec, remotestest.Frames(FRAME_PERIOD, frame, repeat)), 0x1234, 0x56, 1)
+	for _, gap := range []uint32{5000000, 90000} {
+		pulses := append(append(append([]uint32{}, frame...), gap), repeat...)
+		remotestest.CheckEvents(t, remotes.CODEC_SANYO, remotestest.Decode(t, codec, pulses), 0x1234, 0x56, 0)
+	}
+	events := append(remotes.PulseEvents(frame, 0), remotes.PulseEvents(repeat, 0)...)
+	if evts, err := codec.Decode(events); err != nil {
+		t.Fatal(err)
+	} else {
+		remotestest.CheckEvents(t, remotes.CODEC_SANYO, evts, 0x1234, 0x56, 0)
+	}
+}
+
+func TestCarrier(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sanyo.Codec{})
+	defer codec.Close()
+
+	if carrier := codec.Carrier(0); carrier.Frequency != 38000 || carrier.DutyCycle != 33 {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+}
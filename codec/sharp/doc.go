@@ -0,0 +1,40 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package sharp
+
+// Reference:
+//   https://www.sbprojects.net/knowledge/ir/sharp.php
+//
+// The Sharp IR transmission protocol uses pulse distance encoding of the
+// message bits, at a carrier frequency of 38kHz. Each pulse burst is 320µs
+// in length. Logical bits are transmitted as follows:
+//
+//   * Logical '0' – a 320µs pulse burst followed by a 680µs space
+//   * Logical '1' – a 320µs pulse burst followed by a 1.68ms space
+//
+// There is no leading pulse burst. When a key is pressed on the remote
+// controller, the message transmitted consists of the following, in order:
+//
+//  * The 5-bit address for the receiving device
+//  * The 8-bit command
+//  * The expansion bit, which is '1'
+//  * The check bit, which is '0'
+//  * A final 320µs pulse burst to signify the end of message transmission.
+//
+// All values are sent least significant bit first. The message is sent
+// again 40ms after the start of the first message, this time with the
+// command, expansion and check bits inverted. The address is not inverted.
+// The receiver discards any pair of messages where the inverted bits of the
+// second message don't match the first.
+//
+// REPEAT CODES
+//
+// If the key on the remote controller is kept depressed, the pair of
+// messages is repeated every 80ms or so.
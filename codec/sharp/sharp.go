@@ -0,0 +1,388 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package sharp
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Sharp Configuration
+type Codec struct {
+	LIRC      gopi.LIRC
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
+}
+
+type codec struct {
+	log      gopi.Logger
+	lirc     gopi.LIRC
+	carriers *remotes.Carriers
+	timing   *remotes.Timing
+	state    state
+	value    uint32
+	length   uint
+	frames   []uint32
+	repeat   bool
+	decoding bool
+	elapsed  time.Duration
+
+	event.Publisher
+	event.Tasks
+}
+
+type state uint32
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// state
+	STATE_EXPECT_PULSE state = iota
+	STATE_EXPECT_SPACE
+	STATE_EXPECT_GAP_SPACE
+)
+
+const (
+	TOLERANCE         = 35    // 35% tolerance on values
+	CARRIER_FREQUENCY = 38000 // 38kHz carrier
+	DUTY_CYCLE        = 33    // 33% duty cycle
+	BIT_LENGTH        = 15    // 5 address bits, 8 command bits, expansion and check bits
+	TX_DURATION       = 40000 // 40ms between the start of each frame
+)
+
+const (
+	INVERT_MASK    uint32 = 0x7FE0 // Command, expansion and check bits
+	EXPANSION_MASK uint32 = 0x2000
+	CHECK_MASK     uint32 = 0x4000
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	BIT_PULSE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_PULSE, 320, TOLERANCE)
+	ONE_SPACE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 1680, TOLERANCE)
+	ZERO_SPACE = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 680, TOLERANCE)
+	GAP_SPACE  = remotes.NewMarkSpace(gopi.LIRC_TYPE_SPACE, 25000, 80) // Between 5ms and 45ms depending on the bits sent
+)
+
+var (
+	timestamp = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.codec.sharp>Open{ lirc=%v }", config.LIRC)
+
+	this := new(codec)
+	this.log = log
+	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
+
+	// Reset state
+	this.Reset()
+
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
+
+	// Return success
+	return this, nil
+}
+
+func (this *codec) Close() error {
+	this.log.Debug("<remotes.codec.sharp>Close>{}")
+
+	// Remove subscribers to this codec
+	this.Publisher.Close()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Release resources
+	this.lirc = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *codec) String() string {
+	return fmt.Sprintf("<remotes.Codec.Sharp>{}")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *codec) Type() remotes.CodecType {
+	return remotes.CODEC_SHARP
+}
+
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
+func (this *codec) Reset() {
+	this.state = STATE_EXPECT_PULSE
+	this.value = 0
+	this.length = 0
+	this.frames = make([]uint32, 0, 2)
+	this.repeat = false
+	this.timing.Reset()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *codec) Emit(first, second uint32) bool {
+	if scancode, device, err := codeForCodec(first, second); err != nil {
+		this.log.Warn("Emit: %v", err)
+		return false
+	} else if this.timing.Accepts(device) == false {
+		return false
+	} else {
+		this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, this.repeat))
+		return true
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *codec) PulseTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	events := this.lirc.Subscribe()
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if evt != nil {
+				this.receive(evt.(gopi.LIRCEvent))
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.lirc.Unsubscribe(events)
+
+	// Success
+	return nil
+}
+
+func (this *codec) receive(evt gopi.LIRCEvent) {
+	this.log.Debug2("<remotes.codec.sharp>Receive{ state=%v evt=%v }", this.state, evt)
+	switch this.state {
+	case STATE_EXPECT_PULSE:
+		if this.timing.Matches(BIT_PULSE, evt) == false {
+			this.Reset()
+		} else if this.length == BIT_LENGTH {
+			// This is the trailing pulse, so the frame is complete
+			this.eject()
+		} else {
+			this.state = STATE_EXPECT_SPACE
+		}
+	case STATE_EXPECT_SPACE:
+		// Register a zero or one, least significant bit first
+		if this.timing.Matches(ZERO_SPACE, evt) {
+			this.length = this.length + 1
+			this.state = STATE_EXPECT_PULSE
+		} else if this.timing.Matches(ONE_SPACE, evt) {
+			this.value |= 1 << this.length
+			this.length = this.length + 1
+			this.state = STATE_EXPECT_PULSE
+		} else {
+			this.Reset()
+		}
+	case STATE_EXPECT_GAP_SPACE:
+		// The space between frames, after which there is no header
+		if this.timing.Matches(GAP_SPACE, evt) {
+			this.value = 0
+			this.length = 0
+			this.state = STATE_EXPECT_PULSE
+		} else {
+			this.Reset()
+		}
+	default:
+		this.Reset()
+	}
+}
+
+// eject stores a completed frame, and emits an event once both frames
+// of a pair have been received
+func (this *codec) eject() {
+	this.frames = append(this.frames, this.value)
+	if len(this.frames) == 2 {
+		if this.Emit(this.frames[0], this.frames[1]) {
+			// Any further pairs of frames are repeats
+			this.frames = this.frames[:0]
+			this.repeat = true
+		} else {
+			this.Reset()
+			return
+		}
+	}
+	this.state = STATE_EXPECT_GAP_SPACE
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SENDING
+
+func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
+	this.log.Debug2("<remotes.codec.sharp>Send{ device=0x%08X scancode=0x%08X repeats=%v }", device, scancode, repeats)
+
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.sharp> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	value, err := valueForCodec(device, scancode)
+	if err != nil {
+		this.log.Error("<remotes.codec.sharp> Encode: %v", err)
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Each transmission is a pair of frames, the second with the command,
+	// expansion and check bits inverted
+	pulses := make([]uint32, 0, 100)
+	for i := uint(0); i < (repeats + 1); i++ {
+		for j, frame := range []uint32{value, value ^ INVERT_MASK} {
+			length := uint32(0)
+			for k := uint(0); k < BIT_LENGTH; k++ {
+				if frame&(1<<k) == 0 { // Send zero
+					pulses = append(pulses, BIT_PULSE.Value, ZERO_SPACE.Value)
+					length += BIT_PULSE.Value + ZERO_SPACE.Value
+				} else {
+					// Send one
+					pulses = append(pulses, BIT_PULSE.Value, ONE_SPACE.Value)
+					length += BIT_PULSE.Value + ONE_SPACE.Value
+				}
+			}
+			pulses = append(pulses, BIT_PULSE.Value)
+			length += BIT_PULSE.Value
+
+			// Send the space up to the start of the next frame
+			if j == 0 || i < repeats {
+				pulses = append(pulses, TX_DURATION-length)
+			}
+		}
+	}
+
+	// Return the pulses
+	return pulses, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
+// codeForCodec returns the scancode and device from a pair of frames, or
+// returns an error if the inverted bits of the second frame don't match
+// the first frame. The frames may be received in either order
+func codeForCodec(first, second uint32) (uint32, uint32, error) {
+	if first&CHECK_MASK != 0 {
+		first, second = second, first
+	}
+	if first&CHECK_MASK != 0 || first&EXPANSION_MASK == 0 {
+		return 0, 0, fmt.Errorf("Invalid expansion or check bits in frame 0x%04X", first)
+	}
+	if first != second^INVERT_MASK {
+		return 0, 0, fmt.Errorf("Frame 0x%04X does not match inverted frame 0x%04X", first, second)
+	}
+	return (first >> 5) & 0xFF, first & 0x1F, nil
+}
+
+// valueForCodec returns the 15-bit value for the first frame
+func valueForCodec(device, scancode uint32) (uint32, error) {
+	if device&0x1F != device {
+		return 0, fmt.Errorf("Invalid device 0x%08X for codec %v", device, remotes.CODEC_SHARP)
+	}
+	if scancode&0xFF != scancode {
+		return 0, fmt.Errorf("Invalid scancode 0x%08X for codec %v", scancode, remotes.CODEC_SHARP)
+	}
+	return device | scancode<<5 | EXPANSION_MASK, nil
+}
+
+func (s state) String() string {
+	switch s {
+	case STATE_EXPECT_PULSE:
+		return "STATE_EXPECT_PULSE"
+	case STATE_EXPECT_SPACE:
+		return "STATE_EXPECT_SPACE"
+	case STATE_EXPECT_GAP_SPACE:
+		return "STATE_EXPECT_GAP_SPACE"
+	default:
+		return "[?? Invalid state]"
+	}
+}
@@ -0,0 +1,130 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package sharp_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/sharp"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	FRAME_PERIOD = 40000 // Each frame starts 40ms after the previous frame
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sharp.Codec{})
+	defer codec.Close()
+
+	tests := []struct {
+		device, scancode uint32
+		repeats          uint
+	}{
+		{0x00, 0x00, 0},
+		{0x1F, 0xFF, 0},
+		{0x11, 0x5A, 0},
+		{0x11, 0x5A, 1},
+		{0x11, 0x5A, 3},
+	}
+	for _, test := range tests {
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("device=0x%X scancode=0x%X: %v", test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, remotes.CODEC_SHARP, remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sharp.Codec{})
+	defer codec.Close()
+
+	tests := []struct {
+		device, scancode uint32
+	}{
+		{0x20, 0x00},
+		{0x00, 0x100},
+	}
+	for _, test := range tests {
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.device, test.scancode, err)
+		}
+	}
+}
+
+func TestEncodePulses(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sharp.Codec{})
+	defer codec.Close()
+
+	// Each frame is the 5 device bits, 8 scancode bits, the expansion bit
+	// and the check bit, least significant bit first, followed by a trail
+	// pulse. The second frame of each pair has the scancode, expansion and
+	// check bits inverted, and each frame starts 40ms after the previous frame
+	frame := framePulses(0x11, 0x5A, 1, 0)
+	inverted := framePulses(0x11, 0x5A^0xFF, 0, 1)
+	expected := remotestest.Frames(FRAME_PERIOD, frame, inverted, frame, inverted)
+	if pulses, err := codec.Encode(0x11, 0x5A, 1); err != nil {
+		t.Fatal(err)
+	} else if remotestest.Equals(pulses, expected) == false {
+		t.Errorf("Expected %v, got %v", expected, pulses)
+	}
+}
+
+func TestInvertedFrame(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sharp.Codec{})
+	defer codec.Close()
+
+	// A frame is only decoded when followed by the inverted frame
+	frame := framePulses(0x11, 0x5A, 1, 0)
+	tests := [][]uint32{
+		frame,
+		remotestest.Frames(FRAME_PERIOD, frame, frame),
+		remotestest.Frames(FRAME_PERIOD, frame, framePulses(0x11, 0x5B^0xFF, 0, 1)),
+		remotestest.Frames(FRAME_PERIOD, frame, framePulses(0x12, 0x5A^0xFF, 0, 1)),
+		remotestest.Frames(FRAME_PERIOD, frame, framePulses(0x11, 0x5A^0xFF, 1, 1)),
+	}
+	for _, pulses := range tests {
+		if evts := remotestest.Decode(t, codec, pulses); len(evts) != 0 {
+			t.Errorf("Expected no events, got %v", evts)
+		}
+	}
+	remotestest.CheckEvents(t, remotes.CODEC_SHARP, remotestest.Decode(t, codec, remotestest.Frames(FRAME_PERIOD, frame, framePulses(0x11, 0x5A^0xFF, 0, 1))), 0x11, 0x5A, 0)
+}
+
+func TestCarrier(t *testing.T) {
+	codec := remotestest.OpenCodec(t, sharp.Codec{})
+	defer codec.Close()
+
+	if carrier := codec.Carrier(0); carrier.Frequency != 38000 || carrier.DutyCycle != 33 {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// framePulses returns the pulses for a frame, with a 320us pulse for each
+// bit, least significant bit first, followed by a 680us space for a zero or
+// a 1680us space for a one, and a trail pulse
+func framePulses(device, scancode, expansion, check uint32) []uint32 {
+	value := device | scancode<<5 | expansion<<13 | check<<14
+	bits := remotestest.PulseDistance{Pulse: 320, Zero: 680, One: 1680}
+	return append(bits.Pulses(uint64(value), 15), 320)
+}
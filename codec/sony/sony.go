@@ -25,13 +25,16 @@ import (
 
 // Sony Configuration - for 12, 15 and 20
 type Codec struct {
-	LIRC gopi.LIRC
-	Type remotes.CodecType
+	LIRC      gopi.LIRC
+	Type      remotes.CodecType
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
 }
 
 type codec struct {
 	log         gopi.Logger
 	lirc        gopi.LIRC
+	carriers    *remotes.Carriers
+	timing      *remotes.Timing
 	codec_type  remotes.CodecType
 	bit_length  uint
 	cancel      context.CancelFunc
@@ -43,6 +46,8 @@ type codec struct {
 	duration    uint32
 	length      uint
 	repeat      bool
+	decoding    bool
+	elapsed     time.Duration
 }
 
 type state uint32
@@ -61,8 +66,10 @@ const (
 )
 
 const (
-	TOLERANCE   = 35    // 35% tolerance on values
-	TX_DURATION = 45000 // 45ms between each transmission
+	TOLERANCE         = 35    // 35% tolerance on values
+	CARRIER_FREQUENCY = 40000 // 40kHz carrier
+	DUTY_CYCLE        = 33    // 33% duty cycle
+	TX_DURATION       = 45000 // 45ms between each transmission
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -87,16 +94,19 @@ var (
 func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 	log.Debug("<remotes.Codec.Sony.Open>{ lirc=%v type=%v }", config.LIRC, config.Type)
 
-	// Check for LIRC
-	if config.LIRC == nil {
-		return nil, gopi.ErrBadParameter
-	}
-
 	this := new(codec)
 
 	// Set log and lirc objects
 	this.log = log
 	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
 
 	// Set codec and bit length
 	if bit_length := bitLengthForCodec(config.Type); bit_length == 0 {
@@ -108,16 +118,18 @@ func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 
 	// Set up channels
 	this.done = make(chan struct{})
-	this.events = this.lirc.Subscribe()
 	this.subscribers = evt.NewPubSub(0)
 
 	// Reset
 	this.Reset()
 
-	// Create background routine
-	if ctx, cancel := context.WithCancel(context.Background()); ctx != nil {
-		this.cancel = cancel
-		go this.acceptEvents(ctx)
+	// Create background routine, when there is a LIRC driver
+	if this.lirc != nil {
+		this.events = this.lirc.Subscribe()
+		if ctx, cancel := context.WithCancel(context.Background()); ctx != nil {
+			this.cancel = cancel
+			go this.acceptEvents(ctx)
+		}
 	}
 
 	// Return success
@@ -127,12 +139,13 @@ func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 func (this *codec) Close() error {
 	this.log.Debug("<remotes.Codec.Sony.Close>{ type=%v }", this.codec_type)
 
-	// Unsubscribe from LIRC signals
-	this.lirc.Unsubscribe(this.events)
-
-	// Cancel background thread, wait for done signal
-	this.cancel()
-	_ = <-this.done
+	// Unsubscribe from LIRC signals, cancel background thread
+	// and wait for done signal
+	if this.lirc != nil {
+		this.lirc.Unsubscribe(this.events)
+		this.cancel()
+		_ = <-this.done
+	}
 
 	// Remove subscribers to this codec
 	this.subscribers.Close()
@@ -161,12 +174,29 @@ func (this *codec) Type() remotes.CodecType {
 	return this.codec_type
 }
 
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
 func (this *codec) Reset() {
 	this.state = STATE_EXPECT_HEADER_PULSE
 	this.value = 0
 	this.length = 0
 	this.duration = 0
 	this.repeat = false
+	this.timing.Reset()
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -177,7 +207,10 @@ func (this *codec) Subscribe() <-chan gopi.Event {
 }
 
 func (this *codec) Unsubscribe(subscriber <-chan gopi.Event) {
-	this.subscribers.Unsubscribe(subscriber)
+	// Subscribers are removed when the codec is closed
+	if this.subscribers != nil {
+		this.subscribers.Unsubscribe(subscriber)
+	}
 }
 
 func (this *codec) Emit(value uint32, repeat bool) {
@@ -185,8 +218,8 @@ func (this *codec) Emit(value uint32, repeat bool) {
 		if err != gopi.ErrBadParameter {
 			this.log.Warn("Emit: %v", err)
 		}
-	} else {
-		this.subscribers.Emit(remotes.NewRemoteEvent(this, time.Since(timestamp), scancode, device, repeat))
+	} else if this.timing.Accepts(device) {
+		this.subscribers.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, repeat))
 	}
 }
 
@@ -213,37 +246,41 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 	this.log.Debug2("<remotes.Codec.Sony.Receive>{ type=%v evt=%v }", this.codec_type, evt)
 	switch this.state {
 	case STATE_EXPECT_HEADER_PULSE:
-		if HEADER_PULSE.Matches(evt) {
+		if this.timing.Matches(HEADER_PULSE, evt) {
 			this.state = STATE_EXPECT_SPACE
 			this.duration += evt.Value()
 		} else {
 			this.Reset()
 		}
 	case STATE_EXPECT_SPACE:
-		if ONEZERO_SPACE.Matches(evt) {
+		if this.timing.Matches(ONEZERO_SPACE, evt) {
 			this.value <<= 1
 			this.state = STATE_EXPECT_BIT
 			this.duration += evt.Value()
 		} else {
 			REPEAT_SPACE.Set(TX_DURATION-this.duration, TOLERANCE)
-			if REPEAT_SPACE.Matches(evt) && this.length == this.bit_length {
+			if this.timing.Matches(REPEAT_SPACE, evt) && this.length == this.bit_length {
 				this.Emit(this.value, this.repeat)
 				this.value = 0
 				this.length = 0
 				this.duration = 0
 				this.repeat = true
 				this.state = STATE_EXPECT_HEADER_PULSE
+			} else if this.length == this.bit_length && (this.timing.GreaterThan(REPEAT_SPACE, evt) || evt.Type() == gopi.LIRC_TYPE_TIMEOUT) {
+				// The last frame ends with a long space or timeout
+				this.Emit(this.value, this.repeat)
+				this.Reset()
 			} else {
 				this.Reset()
 			}
 		}
 	case STATE_EXPECT_BIT:
-		if ONE_PULSE.Matches(evt) {
+		if this.timing.Matches(ONE_PULSE, evt) {
 			this.value |= 1
 			this.length += 1
 			this.state = STATE_EXPECT_SPACE
 			this.duration += evt.Value()
-		} else if ZERO_PULSE.Matches(evt) {
+		} else if this.timing.Matches(ZERO_PULSE, evt) {
 			this.value |= 0
 			this.length += 1
 			this.state = STATE_EXPECT_SPACE
@@ -256,18 +293,46 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), codec_type: this.codec_type, bit_length: this.bit_length, subscribers: evt.NewPubSub(0), decoding: true}
+	decoder.Reset()
+	defer decoder.subscribers.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SENDING
 
 func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 	this.log.Debug2("<remotes.Codec.Sony.SendSend{ codec_type=%v device=0x%08X scancode=0x%08X repeats=%v }", this.codec_type, device, scancode, repeats)
 
+	if this.lirc == nil {
+		this.log.Error("<remotes.Codec.Sony> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+// Encode returns the pulses for a scancode without sending them
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
 	// Array of pulses
 	pulses := make([]uint32, 0, 100)
 
 	// Make bits and pulses
 	if bits, err := bitsForCodec(this.codec_type, device, scancode); err != nil {
-		return err
+		return nil, err
 	} else {
 		for j := uint(0); j < (repeats + 1); j++ {
 			length := HEADER_PULSE.Value
@@ -279,7 +344,7 @@ func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 				length += ONEZERO_SPACE.Value
 				if bits[i] {
 					pulses = append(pulses, ONE_PULSE.Value)
-					length += ONEZERO_SPACE.Value
+					length += ONE_PULSE.Value
 				} else {
 					pulses = append(pulses, ZERO_PULSE.Value)
 					length += ZERO_PULSE.Value
@@ -293,13 +358,23 @@ func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 		}
 	}
 
-	// Perform the sending
-	return this.lirc.PulseSend(pulses)
+	// Return success
+	return pulses, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
 func bitLengthForCodec(codec remotes.CodecType) uint {
 	switch codec {
 	case remotes.CODEC_SONY12:
@@ -331,22 +406,27 @@ func codeForCodec(codec remotes.CodecType, value uint32) (uint32, uint32, error)
 
 func bitsForCodec(codec remotes.CodecType, device uint32, scancode uint32) ([]bool, error) {
 	bits := make([]bool, 0, bitLengthForCodec(codec))
+	device_length := uint(0)
 	switch codec {
 	case remotes.CODEC_SONY12:
 		// 7 scancode bits and 5 device bits
-		bits = bitsAppend(bits, scancode, 7)
-		bits = bitsAppend(bits, device, 5)
+		device_length = 5
 	case remotes.CODEC_SONY15:
 		// 7 scancode bits and 8 device bits
-		bits = bitsAppend(bits, scancode, 7)
-		bits = bitsAppend(bits, device, 8)
+		device_length = 8
 	case remotes.CODEC_SONY20:
 		// 7 scancode bits and 13 device bits
-		bits = bitsAppend(bits, scancode, 7)
-		bits = bitsAppend(bits, device, 13)
+		device_length = 13
 	default:
 		return nil, gopi.ErrBadParameter
 	}
+
+	// Check scancode and device fit in the bits
+	if scancode&0x7F != scancode || device>>device_length != 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	bits = bitsAppend(bits, scancode, 7)
+	bits = bitsAppend(bits, device, device_length)
 	return bits, nil
 }
 
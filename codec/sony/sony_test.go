@@ -0,0 +1,73 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package sony_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/sony"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+		repeats          uint
+	}{
+		{remotes.CODEC_SONY12, 0x00, 0x00, 0},
+		{remotes.CODEC_SONY12, 0x1F, 0x7F, 0},
+		{remotes.CODEC_SONY12, 0x01, 0x15, 1},
+		{remotes.CODEC_SONY12, 0x01, 0x15, 3},
+		{remotes.CODEC_SONY15, 0x00, 0x00, 0},
+		{remotes.CODEC_SONY15, 0xFF, 0x7F, 0},
+		{remotes.CODEC_SONY15, 0xA4, 0x15, 1},
+		{remotes.CODEC_SONY20, 0x0000, 0x00, 0},
+		{remotes.CODEC_SONY20, 0x1FFF, 0x7F, 0},
+		{remotes.CODEC_SONY20, 0x1A5A, 0x15, 1},
+		{remotes.CODEC_SONY20, 0x1A5A, 0x15, 3},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, sony.Codec{Type: test.codec_type})
+		if pulses, err := codec.Encode(test.device, test.scancode, test.repeats); err != nil {
+			t.Errorf("%v device=0x%X scancode=0x%X: %v", test.codec_type, test.device, test.scancode, err)
+		} else {
+			remotestest.CheckEvents(t, test.codec_type, remotestest.Decode(t, codec, pulses), test.device, test.scancode, test.repeats)
+		}
+		codec.Close()
+	}
+}
+
+func TestEncodeRange(t *testing.T) {
+	tests := []struct {
+		codec_type       remotes.CodecType
+		device, scancode uint32
+	}{
+		{remotes.CODEC_SONY12, 0x20, 0x00},
+		{remotes.CODEC_SONY12, 0x00, 0x80},
+		{remotes.CODEC_SONY15, 0x100, 0x00},
+		{remotes.CODEC_SONY15, 0x00, 0x80},
+		{remotes.CODEC_SONY20, 0x2000, 0x00},
+		{remotes.CODEC_SONY20, 0x00, 0x80},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, sony.Codec{Type: test.codec_type})
+		if _, err := codec.Encode(test.device, test.scancode, 0); err != gopi.ErrBadParameter {
+			t.Errorf("%v device=0x%X scancode=0x%X: Expected ErrBadParameter, got %v", test.codec_type, test.device, test.scancode, err)
+		}
+		codec.Close()
+	}
+}
@@ -0,0 +1,116 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements codec types for codecs which are defined by name
+	rather than by a constant, such as protocols read from data files. The
+	codec type is derived from the name so that it is the same each time
+	the name is registered, and can be stored in keymaps:
+
+	  t, err := remotes.NamedCodecType("mitsubishi") // t.String() is "CODEC_MITSUBISHI"
+*/
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+/////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Codec types from CODEC_NAMED are derived from a codec name
+	CODEC_NAMED      CodecType = 0x10000
+	CODEC_NAMED_MASK CodecType = 0xFFFF
+	CODEC_PREFIX               = "CODEC_"
+)
+
+/////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	codec_names      = make(map[CodecType]string)
+	codec_names_lock sync.RWMutex
+)
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NamedCodecType registers a codec name and returns its codec type. The
+// name is upper-cased and may include the CODEC_ prefix. It returns
+// ErrDuplicateCodec if the name is the same as a constant codec type or
+// another name has the same codec type
+func NamedCodecType(name string) (CodecType, error) {
+	name = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), CODEC_PREFIX)
+	if name == "" || strings.Trim(name, "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_") != "" {
+		return CODEC_NONE, gopi.ErrBadParameter
+	}
+	name = CODEC_PREFIX + name
+
+	// Check against codec types which are constants
+	for codec := CODEC_NONE; codec < CODEC_MAX; codec++ {
+		if codec.String() == name {
+			return CODEC_NONE, ErrDuplicateCodec
+		}
+	}
+
+	// Register the name
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	codec := CODEC_NAMED | CodecType(hash.Sum32())&CODEC_NAMED_MASK
+	codec_names_lock.Lock()
+	defer codec_names_lock.Unlock()
+	if other, exists := codec_names[codec]; exists && other != name {
+		return CODEC_NONE, ErrDuplicateCodec
+	} else {
+		codec_names[codec] = name
+	}
+
+	// Success
+	return codec, nil
+}
+
+// ModuleCodecs returns the codecs which have been created by modules. Codecs
+// have OTHER as module type and name starting with "remotes/", and a module
+// can create a single codec or a set of codecs
+func ModuleCodecs(app *gopi.AppInstance) []Codec {
+	codecs := make([]Codec, 0)
+	for _, module := range gopi.ModulesByType(gopi.MODULE_TYPE_OTHER) {
+		if strings.HasPrefix(module.Name, "remotes/") == false {
+			continue
+		}
+		switch instance := app.ModuleInstance(module.Name).(type) {
+		case Codec:
+			if instance != nil {
+				codecs = append(codecs, instance)
+			}
+		case CodecSet:
+			if instance != nil {
+				codecs = append(codecs, instance.Codecs()...)
+			}
+		}
+	}
+	return codecs
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// namedCodecType returns the name for a registered codec type
+func namedCodecType(codec CodecType) (string, bool) {
+	codec_names_lock.RLock()
+	defer codec_names_lock.RUnlock()
+	name, exists := codec_names[codec]
+	return name, exists
+}
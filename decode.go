@@ -0,0 +1,145 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements helpers for encoding and decoding without a LIRC
+	driver. Codecs implement Decode by creating a decoder with a fresh
+	state, and feeding the events into it using DecodeEvents
+*/
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+type lircevent struct {
+	t gopi.LIRCType
+	v uint32
+}
+
+/////////////////////////////////////////////////////////////////////
+// DECODE
+
+// DecodeEvents feeds LIRC events into a receive function and returns
+// the remote events which are emitted by the publisher as a result
+func DecodeEvents(publisher gopi.Publisher, receive func(gopi.LIRCEvent), events []gopi.LIRCEvent) []RemoteEvent {
+	subscriber := publisher.Subscribe()
+	done := make(chan struct{})
+	decoded := make([]RemoteEvent, 0)
+
+	// Emitting blocks until the event is received, so events are fed
+	// in the background
+	go func() {
+		for _, evt := range events {
+			if evt != nil {
+				receive(evt)
+			}
+		}
+		close(done)
+	}()
+
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-subscriber:
+			if remote_evt, ok := evt.(RemoteEvent); ok && remote_evt != nil {
+				decoded = append(decoded, remote_evt)
+			}
+		case <-done:
+			break FOR_LOOP
+		}
+	}
+
+	// Receive any events which were buffered by the publisher
+DRAIN_LOOP:
+	for {
+		select {
+		case evt := <-subscriber:
+			if remote_evt, ok := evt.(RemoteEvent); ok && remote_evt != nil {
+				decoded = append(decoded, remote_evt)
+			} else {
+				break DRAIN_LOOP
+			}
+		default:
+			break DRAIN_LOOP
+		}
+	}
+
+	// Unsubscribe and return the events
+	publisher.Unsubscribe(subscriber)
+	return decoded
+}
+
+// EventDuration returns the duration of a pulse or space event, or
+// zero for other events
+func EventDuration(evt gopi.LIRCEvent) time.Duration {
+	if evt.Type() == gopi.LIRC_TYPE_PULSE || evt.Type() == gopi.LIRC_TYPE_SPACE {
+		return time.Duration(evt.Value()) * time.Microsecond
+	} else {
+		return 0
+	}
+}
+
+// PulseEvents returns LIRC events for alternating pulses and spaces in
+// microseconds, starting with a pulse, as returned by Encode. When there
+// is an odd number of values, a trailing space or timeout is appended
+// to end the transmission
+func PulseEvents(pulses []uint32, timeout uint32) []gopi.LIRCEvent {
+	events := make([]gopi.LIRCEvent, 0, len(pulses)+1)
+	for i, value := range pulses {
+		if i%2 == 0 {
+			events = append(events, NewLIRCEvent(gopi.LIRC_TYPE_PULSE, value))
+		} else {
+			events = append(events, NewLIRCEvent(gopi.LIRC_TYPE_SPACE, value))
+		}
+	}
+	if len(pulses)%2 == 1 {
+		if timeout == 0 {
+			events = append(events, NewLIRCEvent(gopi.LIRC_TYPE_TIMEOUT, 0))
+		} else {
+			events = append(events, NewLIRCEvent(gopi.LIRC_TYPE_SPACE, timeout))
+		}
+	}
+	return events
+}
+
+// NewLIRCEvent returns a LIRC event which has no source driver
+func NewLIRCEvent(t gopi.LIRCType, value uint32) gopi.LIRCEvent {
+	return &lircevent{t, value}
+}
+
+/////////////////////////////////////////////////////////////////////
+// LIRCEvent Implementation
+
+func (*lircevent) Source() gopi.Driver {
+	return nil
+}
+
+func (*lircevent) Name() string {
+	return "LIRCEvent"
+}
+
+func (this *lircevent) Type() gopi.LIRCType {
+	return this.t
+}
+
+func (this *lircevent) Value() uint32 {
+	return this.v
+}
+
+func (this *lircevent) String() string {
+	return fmt.Sprintf("remotes.LIRCEvent{ type=%v value=%v }", this.t, this.v)
+}
@@ -0,0 +1,156 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package keymap
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Default number of backups kept for each keymap file
+	DEFAULT_BACKUPS = 3
+
+	// Extension for keymap files which could not be loaded
+	EXT_CORRUPT = ".corrupt"
+
+	// Default file mode for new keymap files
+	FILE_MODE = os.FileMode(0644)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// writeTempFile writes to a temporary file in the same folder as path and
+// syncs it to disk, returning the path of the temporary file
+func writeTempFile(path string, write func(fh *os.File) error) (string, error) {
+	fh, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmp := fh.Name()
+
+	// Write, sync and close, keeping the mode of any existing file
+	mode := FILE_MODE
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := write(fh); err != nil {
+		fh.Close()
+		os.Remove(tmp)
+		return "", err
+	} else if err := fh.Chmod(mode); err != nil {
+		fh.Close()
+		os.Remove(tmp)
+		return "", err
+	} else if err := fh.Sync(); err != nil {
+		fh.Close()
+		os.Remove(tmp)
+		return "", err
+	} else if err := fh.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	// Success
+	return tmp, nil
+}
+
+// replaceFile renames a file over path and syncs the folder, so that the
+// rename is on disk
+func replaceFile(tmp, path string) error {
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	// Syncing a folder isn't supported on all platforms, so ignore errors
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// backupFile keeps a copy of path as path.1, moving older copies to
+// path.2 and so on, and removing the oldest. It does nothing when there
+// are no backups or path does not exist
+func backupFile(path string, backups uint) error {
+	if backups == 0 {
+		return nil
+	} else if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Rotate existing backups
+	for i := backups; i > 1; i-- {
+		if err := os.Rename(backupPath(path, i-1), backupPath(path, i)); err != nil && os.IsNotExist(err) == false {
+			return err
+		}
+	}
+
+	// Link or copy the file to the first backup
+	backup := backupPath(path, 1)
+	if err := os.Remove(backup); err != nil && os.IsNotExist(err) == false {
+		return err
+	} else if err := os.Link(path, backup); err == nil {
+		return nil
+	} else {
+		return copyFile(path, backup)
+	}
+}
+
+// quarantineFile moves a file aside so that it is not loaded again, and
+// returns the new path
+func quarantineFile(path string) (string, error) {
+	quarantine := path + EXT_CORRUPT
+	for i := uint(1); ; i++ {
+		if _, err := os.Stat(quarantine); os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		quarantine = backupPath(path+EXT_CORRUPT, i)
+	}
+	if err := os.Rename(path, quarantine); err != nil {
+		return "", err
+	}
+	return quarantine, nil
+}
+
+func backupPath(path string, i uint) string {
+	return fmt.Sprintf("%v.%v", path, i)
+}
+
+func copyFile(src, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, FILE_MODE)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	} else if err := w.Sync(); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
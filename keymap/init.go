@@ -25,13 +25,16 @@ func init() {
 		Config: func(config *gopi.AppConfig) {
 			config.AppFlags.FlagString("keymap.db", "/var/local/remotes", "Key mapping database path")
 			config.AppFlags.FlagString("keymap.ext", "", "Key mapping file extension")
+			config.AppFlags.FlagUint("keymap.backups", DEFAULT_BACKUPS, "Number of backups kept for each key mapping file")
 		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
 			root, _ := app.AppFlags.GetString("keymap.db")
 			ext, _ := app.AppFlags.GetString("keymap.ext")
+			backups, _ := app.AppFlags.GetUint("keymap.backups")
 			return gopi.Open(Database{
-				Root: root,
-				Ext:  ext,
+				Root:    root,
+				Ext:     ext,
+				Backups: backups,
 			}, app.Logger)
 		},
 	})
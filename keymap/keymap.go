@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
@@ -30,9 +31,12 @@ type Database struct {
 
 	// Extension for keymap files
 	Ext string
+
+	// Number of backups kept when a keymap file is saved
+	Backups uint
 }
 
-// Driver
+// Driver, which is safe for concurrent readers and writers
 type db struct {
 	// Logger
 	log gopi.Logger
@@ -40,6 +44,9 @@ type db struct {
 	// Root path and file extension
 	root, ext string
 
+	// Number of backups for each keymap file
+	backups uint
+
 	// Keymap database
 	keymap map[remotes.CodecType]map[uint32]*tuple
 
@@ -50,6 +57,15 @@ type db struct {
 
 	// New keymap
 	empty *remotes.KeyMap
+
+	// Paths of deleted keymaps, removed when saved
+	deleted []string
+
+	// Files which could not be loaded
+	errors map[string]error
+
+	// Locking for the database and keymaps
+	sync.RWMutex
 }
 
 // (path,keymap tuple)
@@ -110,12 +126,13 @@ func (config Database) root() string {
 }
 
 func (config Database) Open(log gopi.Logger) (gopi.Driver, error) {
-	log.Debug("<keymap.db>Open{ root=\"%v\" ext=\"%v\"}", config.Root, config.ext())
+	log.Debug("<keymap.db>Open{ root=\"%v\" ext=\"%v\" backups=%v }", config.Root, config.ext(), config.Backups)
 
 	this := new(db)
 	this.log = log
 	this.root = config.root()
 	this.ext = config.ext()
+	this.backups = config.Backups
 
 	if this.root == "" {
 		log.Debug("keymap: Bad Parameter (root=%v ext=%v)", this.root, this.ext)
@@ -130,15 +147,18 @@ func (config Database) Open(log gopi.Logger) (gopi.Driver, error) {
 	this.bycodec = make(map[remotes.CodecType][]*etuple)
 	this.bydevice = make(map[uint32][]*etuple)
 	this.byscancode = make(map[uint32][]*etuple)
+	this.errors = make(map[string]error)
 
 	return this, nil
 }
 
 func (this *db) Close() error {
 	this.log.Debug("<keymap.db>Close{ root=\"%v\"}", this.root)
+	this.Lock()
+	defer this.Unlock()
 
 	// Warn on modified keymaps
-	if this.Modified() {
+	if this.modified() {
 		this.log.Warn("<keymap.db>Close: There are modified keymaps, invoke SaveModifiedKeyMaps before Close")
 	}
 
@@ -148,18 +168,26 @@ func (this *db) Close() error {
 	this.bydevice = nil
 	this.byscancode = nil
 	this.empty = nil
+	this.deleted = nil
+	this.errors = nil
 
 	// Return success
 	return nil
 }
 
 func (this *db) String() string {
+	this.RLock()
+	defer this.RUnlock()
 	return fmt.Sprintf("<keymap.db>{ root=%v empty=%v keymaps=%v }", this.root, this.empty, this.keymap)
 }
 
 func init() {
+	// Tokens are created here so that lookups don't modify them
 	allKeyCodes = getAllKeycodes()
 	keyCodeTokens = make(map[remotes.RemoteCode][]string, len(allKeyCodes))
+	for _, keycode := range allKeyCodes {
+		keyCodeTokens[keycode.Keycode] = getKeyCodeTokens(keycode)
+	}
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -174,6 +202,8 @@ func (this *db) NewKeyMap(name string) *remotes.KeyMap {
 	}
 
 	this.log.Debug2("<keymap.db>NewKeyMap{ name=\"%v\"}", name)
+	this.Lock()
+	defer this.Unlock()
 
 	// Create a new empty KeyMap file
 	keymap := new(remotes.KeyMap)
@@ -193,6 +223,78 @@ func (this *db) NewKeyMap(name string) *remotes.KeyMap {
 	return keymap
 }
 
+// Create a new KeyMap with codec and device and register it
+func (this *db) CreateKeyMap(name string, codec remotes.CodecType, device uint32) (*remotes.KeyMap, error) {
+	this.log.Debug2("<keymap.db>CreateKeyMap{ name=\"%v\" codec=%v device=0x%08X }", name, codec, device)
+	this.Lock()
+	defer this.Unlock()
+
+	// Check parameters
+	if name = strings.TrimSpace(name); name == "" {
+		return nil, gopi.ErrBadParameter
+	} else if codec == remotes.CODEC_NONE || device == remotes.DEVICE_UNKNOWN {
+		return nil, gopi.ErrBadParameter
+	} else if this.nameExists(nil, name) {
+		return nil, remotes.ErrDuplicateName
+	}
+
+	// Create the keymap
+	keymap := new(remotes.KeyMap)
+	keymap.Type = codec
+	keymap.Device = device
+	keymap.Name = name
+	keymap.Repeats = 0
+	keymap.Map = make([]*remotes.KeyMapEntry, 0)
+
+	// Register the keymap, which is saved as a new file
+	if path := uniqueKeyMapPath(codec, device, this.root, this.ext); path == "" {
+		return nil, fmt.Errorf("Unable to create a file for %v", name)
+	} else if err := this.registerNewKeyMap(path, keymap, true); err != nil {
+		return nil, err
+	}
+
+	// Success
+	return copyKeyMap(keymap), nil
+}
+
+// Delete a KeyMap, the file is removed on save
+func (this *db) DeleteKeyMap(keymap *remotes.KeyMap) error {
+	// Check parameters
+	if keymap == nil {
+		return gopi.ErrBadParameter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	this.log.Debug2("<keymap.db>DeleteKeyMap{ name=\"%v\" }", keymap.Name)
+
+	// The 'new' keymap case
+	if keymap == this.empty {
+		this.empty = nil
+		return nil
+	}
+
+	// Get the tuple for the keymap, which may be a copy
+	tuple := this.getTuple(keymap.Type, keymap.Device)
+	if tuple == nil {
+		return gopi.ErrBadParameter
+	}
+
+	// Remove the entries from the indexes and the keymap from the database
+	for _, entry := range tuple.keymap.Map {
+		this.unindexKeyMapEntry(entry)
+	}
+	if delete(this.keymap[keymap.Type], keymap.Device); len(this.keymap[keymap.Type]) == 0 {
+		delete(this.keymap, keymap.Type)
+	}
+
+	// Remove the file when saved
+	this.deleted = append(this.deleted, tuple.path)
+
+	// Success
+	return nil
+}
+
 // Load keymaps from the root path
 func (this *db) LoadKeyMaps(callback remotes.LoadSaveCallbackFunc) error {
 	this.log.Debug2("<keymap.db>LoadKeyMaps{ path=\"%v\"}", this.root)
@@ -205,17 +307,28 @@ func (this *db) LoadKeyMaps(callback remotes.LoadSaveCallbackFunc) error {
 			return gopi.ErrBadParameter
 		}
 	}
-	// Walk path loading in files with the correct extension
+	// Reset the files which could not be loaded
+	this.Lock()
+	this.errors = make(map[string]error)
+	this.Unlock()
+
+	// Walk path loading in files with the correct extension. Files which
+	// can't be loaded are reported rather than failing, and corrupt files
+	// are moved aside
 	return filepath.Walk(this.root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			if path == this.root {
+				return err
+			}
+			this.loadError(path, false, err)
+			return nil
 		}
 		if info.Mode().IsDir() {
 			return nil
 		}
 		if info.Mode().IsRegular() && filepath.Ext(path) == this.ext {
-			if keymap, err := this.LoadKeyMap(path); err != nil {
-				return err
+			if keymap, corrupt, err := this.loadKeyMap(path); err != nil {
+				this.loadError(path, corrupt, err)
 			} else if callback != nil {
 				callback(path, keymap)
 			}
@@ -226,27 +339,85 @@ func (this *db) LoadKeyMaps(callback remotes.LoadSaveCallbackFunc) error {
 
 // Load a single keymap
 func (this *db) LoadKeyMap(path string) (*remotes.KeyMap, error) {
+	keymap, _, err := this.loadKeyMap(path)
+	return keymap, err
+}
+
+// Return files which could not be loaded, and the reason
+func (this *db) LoadErrors() map[string]error {
+	this.RLock()
+	defer this.RUnlock()
+	errors := make(map[string]error, len(this.errors))
+	for path, err := range this.errors {
+		errors[path] = err
+	}
+	return errors
+}
+
+// loadKeyMap loads and registers a keymap, and returns true as the
+// second argument if the file is corrupt
+func (this *db) loadKeyMap(path string) (*remotes.KeyMap, bool, error) {
 	this.log.Debug2("<keymap.db>LoadKeyMap{ path=\"%v\"}", path)
 
 	keymap := new(remotes.KeyMap)
 	fh, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer fh.Close()
 	dec := xml.NewDecoder(fh)
 	if err := dec.Decode(keymap); err != nil {
-		return nil, err
-	} else if err := this.registerNewKeyMap(path, keymap, false); err != nil {
-		return nil, err
+		return nil, true, err
+	}
+
+	// Clamp the carrier duty cycle to the values LIRC devices accept
+	if keymap.Carrier != nil && keymap.Carrier.DutyCycle > remotes.MAX_DUTY_CYCLE {
+		this.log.Warn("<keymap.db>LoadKeyMap: %v: Duty cycle %v%% reduced to %v%%", path, keymap.Carrier.DutyCycle, remotes.MAX_DUTY_CYCLE)
+		keymap.Carrier.DutyCycle = remotes.MAX_DUTY_CYCLE
+	}
+
+	// Register the keymap
+	this.Lock()
+	defer this.Unlock()
+	if err := this.registerNewKeyMap(path, keymap, false); err == gopi.ErrBadParameter {
+		return nil, true, fmt.Errorf("Invalid codec and/or device")
+	} else if err != nil {
+		return nil, false, err
 	}
 
 	// Success
-	return keymap, nil
+	return copyKeyMap(keymap), false, nil
+}
+
+// loadError records a file which could not be loaded, and moves
+// corrupt files aside so they are not loaded again
+func (this *db) loadError(path string, corrupt bool, err error) {
+	if corrupt {
+		if quarantine, err_ := quarantineFile(path); err_ != nil {
+			this.log.Warn("<keymap.db>LoadKeyMaps: %v: %v (unable to quarantine: %v)", path, err, err_)
+		} else {
+			this.log.Warn("<keymap.db>LoadKeyMaps: %v: %v (quarantined as %v)", path, err, filepath.Base(quarantine))
+		}
+	} else {
+		this.log.Warn("<keymap.db>LoadKeyMaps: %v: %v", path, err)
+	}
+	this.Lock()
+	defer this.Unlock()
+	this.errors[path] = err
 }
 
 // Return true if any keymaps were modified
 func (this *db) Modified() bool {
+	this.RLock()
+	defer this.RUnlock()
+	return this.modified()
+}
+
+func (this *db) modified() bool {
+	// Deleted keymaps need to be removed
+	if len(this.deleted) > 0 {
+		return true
+	}
 	// Iterate through all the existing keymaps
 	for codec := range this.keymap {
 		for device := range this.keymap[codec] {
@@ -266,9 +437,34 @@ func (this *db) Modified() bool {
 
 // Save all modified keymaps
 func (this *db) SaveModifiedKeyMaps(callback remotes.LoadSaveCallbackFunc) error {
-	this.log.Debug2("<keymap.db>SaveModifiedKeyMaps{ modified=%v }", this.Modified())
+	// Callback for saved keymaps without holding the lock, so that the
+	// callback can use the database
+	saved, err := this.saveModifiedKeyMaps()
+	if callback != nil {
+		for _, t := range saved {
+			callback(t.path, t.keymap)
+		}
+	}
+	return err
+}
+
+func (this *db) saveModifiedKeyMaps() ([]*tuple, error) {
+	this.Lock()
+	defer this.Unlock()
+	this.log.Debug2("<keymap.db>SaveModifiedKeyMaps{ modified=%v }", this.modified())
+
+	// Remove files for deleted keymaps, keeping backups
+	for len(this.deleted) > 0 {
+		if err := backupFile(this.deleted[0], this.backups); err != nil {
+			return nil, err
+		} else if err := os.Remove(this.deleted[0]); err != nil && os.IsNotExist(err) == false {
+			return nil, err
+		}
+		this.deleted = this.deleted[1:]
+	}
 
 	// Iterate through all the existing keymaps
+	saved := make([]*tuple, 0)
 	for codec := range this.keymap {
 		for device := range this.keymap[codec] {
 			// Ignore codecs with invalid codec/device combination
@@ -278,25 +474,28 @@ func (this *db) SaveModifiedKeyMaps(callback remotes.LoadSaveCallbackFunc) error
 			// Don't save unmodified keymaps
 			if t := this.keymap[codec][device]; t.modified == false {
 				continue
-			} else if err := this.SaveKeyMap(t.keymap, t.path); err != nil {
-				return err
+			} else if err := this.saveKeyMap(t.keymap, t.path); err != nil {
+				return saved, err
 			} else {
-				// Callback
-				if callback != nil {
-					callback(t.path, t.keymap)
-				}
 				// Set modified to no
+				saved = append(saved, &tuple{path: t.path, keymap: copyKeyMap(t.keymap)})
 				t.modified = false
 			}
 		}
 	}
 
 	// Success
-	return nil
+	return saved, nil
 }
 
 // Save keymap to file
 func (this *db) SaveKeyMap(keymap *remotes.KeyMap, path string) error {
+	this.RLock()
+	defer this.RUnlock()
+	return this.saveKeyMap(keymap, path)
+}
+
+func (this *db) saveKeyMap(keymap *remotes.KeyMap, path string) error {
 	this.log.Debug2("<keymap.db>SaveKeyMap{ path=%v keymap=%v }", path, keymap)
 
 	// Sanity check codec and device
@@ -305,18 +504,20 @@ func (this *db) SaveKeyMap(keymap *remotes.KeyMap, path string) error {
 		return gopi.ErrBadParameter
 	}
 
-	// Save
-	if fh, err := os.Create(path); err != nil {
-		return err
-	} else {
-		defer fh.Close()
-
-		// Encode XML
+	// Write to a temporary file, then keep backups and replace the
+	// existing file, so that the file is never partially written
+	if tmp, err := writeTempFile(path, func(fh *os.File) error {
 		enc := xml.NewEncoder(fh)
 		enc.Indent("", "  ")
-		if err := enc.Encode(keymap); err != nil {
-			return err
-		}
+		return enc.Encode(keymap)
+	}); err != nil {
+		return err
+	} else if err := backupFile(path, this.backups); err != nil {
+		os.Remove(tmp)
+		return err
+	} else if err := replaceFile(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
 	}
 
 	// Success
@@ -325,6 +526,8 @@ func (this *db) SaveKeyMap(keymap *remotes.KeyMap, path string) error {
 
 func (this *db) KeyMaps(codec remotes.CodecType, device uint32, name string) []*remotes.KeyMap {
 	this.log.Debug2("<keymap.db>KeyMaps{ codec=%v device=0x%08X name=%v }", codec, device, name)
+	this.RLock()
+	defer this.RUnlock()
 	keymaps := this.allKeyMaps(func(t *tuple) bool {
 		this.log.Debug("<keymap.db>KeyMaps{ tuple=%v }", t)
 		if codec != remotes.CODEC_NONE && codec != t.keymap.Type {
@@ -338,6 +541,10 @@ func (this *db) KeyMaps(codec remotes.CodecType, device uint32, name string) []*
 		}
 		return true
 	})
+	// Return copies, which can be read without the lock
+	for i, keymap := range keymaps {
+		keymaps[i] = copyKeyMap(keymap)
+	}
 	return keymaps
 }
 
@@ -378,6 +585,8 @@ func (this *db) LookupKeyCode(searchterms ...string) []*remotes.KeyMapEntry {
 }
 
 func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType, device uint32, keycode remotes.RemoteCode, scancode uint32) error {
+	this.Lock()
+	defer this.Unlock()
 	this.log.Debug2("<keymap.db>SetKeyMapEntry{ keymap=\"%v\" codec=%v device=0x%08X keycode=%v scancode=0x%08X }", keymap.Name, codec, device, keycode, scancode)
 
 	// Sanity check to make sure keymap codec and device are correct
@@ -386,6 +595,17 @@ func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 		return gopi.ErrBadParameter
 	}
 
+	// The keymap may be a copy, so modify the registered keymap unless
+	// this is the 'empty' keymap
+	if keymap != this.empty {
+		if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil {
+			this.log.Debug("SetKeyMapEntry: Invalid keymap file")
+			return gopi.ErrBadParameter
+		} else {
+			keymap = tuple.keymap
+		}
+	}
+
 	// Set the codec in the keymap if CODEC_NONE
 	// We generally check the codec and device to make sure they are
 	// the same as the keymap values, but if the keymap has MultiCodec
@@ -402,11 +622,11 @@ func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 		return fmt.Errorf("Different device (0x%08X) than expected (0x%08X)", device, keymap.Device)
 	}
 
-	// If this is the 'empty' keymap then register it, If there's a collision with an
-	// existing keymap file, then return an error
+	// If this is the 'empty' keymap then register a copy of it, If there's a
+	// collision with an existing keymap file, then return an error
 	if keymap == this.empty {
 		path := uniqueKeyMapPath(codec, device, this.root, this.ext)
-		if err := this.registerNewKeyMap(path, keymap, true); err == remotes.ErrDuplicateKeyMap {
+		if err := this.registerNewKeyMap(path, copyKeyMap(keymap), true); err == remotes.ErrDuplicateKeyMap {
 			return remotes.ErrDuplicateKeyMap
 		} else if err != nil {
 			return err
@@ -417,11 +637,12 @@ func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 	}
 
 	// Obtain the tuple for this keymap - it needs to exist
-	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil || tuple.keymap != keymap {
+	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil {
 		this.log.Debug("SetKeyMapEntry: Invalid keymap file")
 		return gopi.ErrBadParameter
 	} else {
 		// Set modified
+		keymap = tuple.keymap
 		tuple.modified = true
 	}
 
@@ -434,6 +655,7 @@ func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 			entry.Type = remotes.CODEC_NONE
 			entry.Device = 0
 			entry.Repeats = 0
+			entry.Pulses = nil
 			// Set name
 			if entry.Name == "" {
 				entry.Name = defaultKeyName(entry.Keycode)
@@ -445,8 +667,8 @@ func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 			if keymap.Device != device {
 				entry.Device = device
 			}
-			// Return success
-			return nil
+			// Reindex the entry, replacing the previous scancode
+			return this.indexKeyMapEntry(keymap, entry)
 		}
 	}
 
@@ -457,8 +679,8 @@ func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 		Name:     "",
 	})
 
-	// Reindex the keymap
-	if err := this.indexKeyMap(keymap); err != nil {
+	// Index the new entry
+	if err := this.indexKeyMapEntry(keymap, keymap.Map[len(keymap.Map)-1]); err != nil {
 		return err
 	}
 
@@ -467,8 +689,15 @@ func (this *db) SetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 }
 
 func (this *db) GetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType, device uint32, keycode remotes.RemoteCode, scancode uint32) []*remotes.KeyMapEntry {
+	this.RLock()
+	defer this.RUnlock()
 	this.log.Debug2("<keymap.db>GetKeyMapEntry{ keymap=\"%v\" codec=%v device=0x%08X keycode=%v scancode=0x%08X }", keymap.Name, codec, device, keycode, scancode)
 
+	// The keymap may be a copy, so search the registered keymap
+	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple != nil && keymap != this.empty {
+		keymap = tuple.keymap
+	}
+
 	// Search through the keymap and return the entries which match
 	entries := make([]*remotes.KeyMapEntry, 0, 1)
 	for _, entry := range keymap.Map {
@@ -512,34 +741,132 @@ func (this *db) GetKeyMapEntry(keymap *remotes.KeyMap, codec remotes.CodecType,
 
 func (this *db) LookupKeyMapEntry(codec remotes.CodecType, device uint32, scancode uint32) map[*remotes.KeyMapEntry]*remotes.KeyMap {
 	this.log.Debug2("<keymap.db>LookupKeyMapEntry{ codec=%v device=0x%08X scancode=0x%08X }", codec, device, scancode)
+	this.RLock()
+	defer this.RUnlock()
 
 	if tuples := this.lookupEntryTuples(codec, device, scancode); len(tuples) == 0 {
 		return nil
 	} else {
-		// Create entries from tuples
+		// Create entries from tuples, with a copy of each keymap
 		entries := make(map[*remotes.KeyMapEntry]*remotes.KeyMap, len(tuples))
+		keymaps := make(map[*remotes.KeyMap]*remotes.KeyMap, 1)
 		for _, tuple := range tuples {
+			keymap, exists := keymaps[tuple.keymap]
+			if exists == false {
+				keymap = copyKeyMap(tuple.keymap)
+				keymaps[tuple.keymap] = keymap
+			}
 			entry := newKeyMapEntry(tuple.keymap, tuple.entry, false)
-			entries[entry] = tuple.keymap
+			entries[entry] = keymap
 		}
 		return entries
 	}
 }
 
 func (this *db) DeleteKeyMapEntry(keymap *remotes.KeyMap, entry *remotes.KeyMapEntry) error {
-	this.log.Info("TODO: Delete %v from %v", entry, keymap.Name)
-	return nil
+	// Check parameters
+	if keymap == nil || entry == nil {
+		return gopi.ErrBadParameter
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	this.log.Debug2("<keymap.db>DeleteKeyMapEntry{ keymap=\"%v\" keycode=%v }", keymap.Name, entry.Keycode)
+
+	// Obtain the tuple for this keymap - it needs to exist
+	tuple := this.getTuple(keymap.Type, keymap.Device)
+	if tuple == nil {
+		this.log.Debug("DeleteKeyMapEntry: Invalid keymap file")
+		return gopi.ErrBadParameter
+	} else {
+		keymap = tuple.keymap
+	}
+
+	// Search through the entries for the one to delete. The entry
+	// may be a copy returned by GetKeyMapEntry so it is matched on
+	// keycode, as there is one keycode per map
+	for i, existing := range keymap.Map {
+		if existing == entry || existing.Keycode == entry.Keycode {
+			// Remove from the indexes and the keymap
+			this.unindexKeyMapEntry(existing)
+			keymap.Map = append(keymap.Map[:i], keymap.Map[i+1:]...)
+			// Set modified
+			tuple.modified = true
+			// Return success
+			return nil
+		}
+	}
+
+	// Keycode not found
+	return remotes.ErrNotFound
+}
+
+func (this *db) SetKeyMapPulses(keymap *remotes.KeyMap, keycode remotes.RemoteCode, pulses []uint32) error {
+	this.Lock()
+	defer this.Unlock()
+	this.log.Debug2("<keymap.db>SetKeyMapPulses{ keymap=\"%v\" keycode=%v pulses=%v }", keymap.Name, keycode, len(pulses))
+
+	// Obtain the tuple for this keymap - it needs to exist
+	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil {
+		this.log.Debug("SetKeyMapPulses: Invalid keymap file")
+		return gopi.ErrBadParameter
+	} else {
+		for _, entry := range tuple.keymap.Map {
+			if entry.Keycode == keycode {
+				entry.Pulses = remotes.Pulses(pulses)
+				tuple.modified = true
+				return nil
+			}
+		}
+	}
+
+	// Keycode not found
+	return remotes.ErrNotFound
 }
 
 /////////////////////////////////////////////////////////////////////
 // SET PARAMETERS
 
 func (this *db) SetName(keymap *remotes.KeyMap, name string) error {
-	// TODO: Not yet implemented
-	return gopi.ErrNotImplemented
+	this.Lock()
+	defer this.Unlock()
+
+	// Check parameters
+	if keymap == nil {
+		return gopi.ErrBadParameter
+	} else if name = strings.TrimSpace(name); name == "" {
+		return gopi.ErrBadParameter
+	}
+
+	// The 'new' keymap case
+	if keymap == this.empty {
+		if keymap.Name == name {
+			return nil
+		} else if this.nameExists(keymap, name) {
+			return remotes.ErrDuplicateName
+		}
+		keymap.Name = name
+		return nil
+	}
+
+	// Get the tuple for the keymap, which may be a copy, and modify the name
+	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil {
+		return gopi.ErrBadParameter
+	} else if tuple.keymap.Name == name {
+		return nil
+	} else if this.nameExists(tuple.keymap, name) {
+		return remotes.ErrDuplicateName
+	} else {
+		tuple.keymap.Name = name
+		tuple.modified = true
+		return nil
+	}
 }
 
 func (this *db) SetRepeats(keymap *remotes.KeyMap, repeats uint) error {
+	this.Lock()
+	defer this.Unlock()
+
 	// Check parameters
 	if keymap == nil {
 		return gopi.ErrBadParameter
@@ -551,11 +878,10 @@ func (this *db) SetRepeats(keymap *remotes.KeyMap, repeats uint) error {
 		return nil
 	}
 
-	// Get the tuple for the keymap and modify the repeats value
+	// Get the tuple for the keymap, which may be a copy, and modify the
+	// repeats value
 	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil {
 		return gopi.ErrBadParameter
-	} else if tuple.keymap != keymap {
-		return gopi.ErrBadParameter
 	} else if tuple.keymap.Repeats == repeats {
 		return nil
 	} else {
@@ -565,16 +891,44 @@ func (this *db) SetRepeats(keymap *remotes.KeyMap, repeats uint) error {
 	}
 }
 
-func (this *db) SetMultiCodec(keymap *remotes.KeyMap, flag bool) error {
+func (this *db) SetCalibration(keymap *remotes.KeyMap, calibration *remotes.Calibration) error {
+	this.Lock()
+	defer this.Unlock()
+
 	// Check parameters
 	if keymap == nil {
 		return gopi.ErrBadParameter
 	}
 
-	// Get the tuple for the keymap and modify the multicodec value
+	// The 'new' keymap case
+	if keymap == this.empty {
+		keymap.Calibration = calibration
+		return nil
+	}
+
+	// Get the tuple for the keymap, which may be a copy, and modify the
+	// calibration
 	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil {
 		return gopi.ErrBadParameter
-	} else if tuple.keymap != keymap {
+	} else {
+		tuple.keymap.Calibration = calibration
+		tuple.modified = true
+		return nil
+	}
+}
+
+func (this *db) SetMultiCodec(keymap *remotes.KeyMap, flag bool) error {
+	this.Lock()
+	defer this.Unlock()
+
+	// Check parameters
+	if keymap == nil {
+		return gopi.ErrBadParameter
+	}
+
+	// Get the tuple for the keymap, which may be a copy, and modify the
+	// multicodec value
+	if tuple := this.getTuple(keymap.Type, keymap.Device); tuple == nil {
 		return gopi.ErrBadParameter
 	} else if tuple.keymap.MultiCodec == flag {
 		return nil
@@ -596,6 +950,7 @@ func newKeyMapEntry(keymap *remotes.KeyMap, entry *remotes.KeyMapEntry, minimize
 		Device:   entry.Device,
 		Type:     entry.Type,
 		Repeats:  entry.Repeats,
+		Pulses:   entry.Pulses,
 	}
 
 	// Set the name field if empty
@@ -629,6 +984,30 @@ func newKeyMapEntry(keymap *remotes.KeyMap, entry *remotes.KeyMapEntry, minimize
 	return new_entry
 }
 
+// copyKeyMap returns a copy of a keymap and its entries, which can be
+// read without holding the lock
+func copyKeyMap(keymap *remotes.KeyMap) *remotes.KeyMap {
+	if keymap == nil {
+		return nil
+	}
+	new_keymap := *keymap
+	if keymap.Calibration != nil {
+		calibration := *keymap.Calibration
+		new_keymap.Calibration = &calibration
+	}
+	if keymap.Carrier != nil {
+		carrier := *keymap.Carrier
+		new_keymap.Carrier = &carrier
+	}
+	new_keymap.Map = make([]*remotes.KeyMapEntry, len(keymap.Map))
+	for i, entry := range keymap.Map {
+		new_entry := *entry
+		new_entry.Pulses = append(remotes.Pulses(nil), entry.Pulses...)
+		new_keymap.Map[i] = &new_entry
+	}
+	return &new_keymap
+}
+
 func appendKeyMapEntry(array []*remotes.KeyMapEntry, keymap *remotes.KeyMap, entry *remotes.KeyMapEntry) []*remotes.KeyMapEntry {
 	if array == nil {
 		array = keymap.Map
@@ -671,6 +1050,18 @@ func (this *db) registerNewKeyMap(path string, keymap *remotes.KeyMap, modified
 	return nil
 }
 
+// nameExists returns true if a keymap other than the one provided
+// has the name
+func (this *db) nameExists(keymap *remotes.KeyMap, name string) bool {
+	if this.empty != nil && this.empty != keymap && this.empty.Name == name {
+		return true
+	}
+	others := this.allKeyMaps(func(t *tuple) bool {
+		return t.keymap != keymap && t.keymap.Name == name
+	})
+	return len(others) > 0
+}
+
 func (this *db) allKeyMaps(callback allKeyMapsFunc) []*remotes.KeyMap {
 	keymaps := make([]*remotes.KeyMap, 0)
 	for _, devices := range this.keymap {
@@ -685,7 +1076,7 @@ func (this *db) allKeyMaps(callback allKeyMapsFunc) []*remotes.KeyMap {
 
 func (this *db) indexKeyMap(keymap *remotes.KeyMap) error {
 	// Check parameters
-	if keymap == nil || len(keymap.Map) == 0 {
+	if keymap == nil {
 		return gopi.ErrBadParameter
 	}
 
@@ -703,27 +1094,7 @@ func (this *db) indexKeyMap(keymap *remotes.KeyMap) error {
 func (this *db) indexKeyMapEntry(keymap *remotes.KeyMap, entry *remotes.KeyMapEntry) error {
 
 	// Remove any existing entries from all indexes
-	for codec, entries := range this.bycodec {
-		for _, etuple := range entries {
-			if entry == etuple.entry {
-				this.log.Warn("TODO: removeindexKeyMapEntry codec %v => %v", codec, entry)
-			}
-		}
-	}
-	for device, entries := range this.bydevice {
-		for _, etuple := range entries {
-			if entry == etuple.entry {
-				this.log.Warn("TODO: removeindexKeyMapEntry device %08X => %v", device, entry)
-			}
-		}
-	}
-	for scancode, entries := range this.byscancode {
-		for _, etuple := range entries {
-			if entry == etuple.entry {
-				this.log.Warn("TODO: removeindexKeyMapEntry scancode %08X => %v", scancode, entry)
-			}
-		}
-	}
+	this.unindexKeyMapEntry(entry)
 
 	// Set index parameters
 	codec := entry.Type
@@ -757,6 +1128,30 @@ func (this *db) indexKeyMapEntry(keymap *remotes.KeyMap, entry *remotes.KeyMapEn
 	return nil
 }
 
+func (this *db) unindexKeyMapEntry(entry *remotes.KeyMapEntry) {
+	for codec, entries := range this.bycodec {
+		if entries = removeEntryTuple(entries, entry); len(entries) == 0 {
+			delete(this.bycodec, codec)
+		} else {
+			this.bycodec[codec] = entries
+		}
+	}
+	for device, entries := range this.bydevice {
+		if entries = removeEntryTuple(entries, entry); len(entries) == 0 {
+			delete(this.bydevice, device)
+		} else {
+			this.bydevice[device] = entries
+		}
+	}
+	for scancode, entries := range this.byscancode {
+		if entries = removeEntryTuple(entries, entry); len(entries) == 0 {
+			delete(this.byscancode, scancode)
+		} else {
+			this.byscancode[scancode] = entries
+		}
+	}
+}
+
 func (this *db) lookupEntryTuples(codec remotes.CodecType, device uint32, scancode uint32) []*etuple {
 	// Create an etuple hash to count the number of occurences
 	counter := make(map[*etuple]uint, 100)
@@ -820,6 +1215,17 @@ func (this *db) getTuple(codec remotes.CodecType, device uint32) *tuple {
 	}
 }
 
+// removeEntryTuple returns the array without the tuples for an entry
+func removeEntryTuple(array []*etuple, entry *remotes.KeyMapEntry) []*etuple {
+	tuples := array[:0]
+	for _, tuple := range array {
+		if tuple.entry != entry {
+			tuples = append(tuples, tuple)
+		}
+	}
+	return tuples
+}
+
 func getAllKeycodes() []*remotes.KeyMapEntry {
 	keycodes := make([]*remotes.KeyMapEntry, 0, 100)
 	for c := remotes.KEYCODE_NONE; c < remotes.KEYCODE_MAX; c++ {
@@ -839,22 +1245,21 @@ func getAllKeycodes() []*remotes.KeyMapEntry {
 }
 
 func fuzzyKeyCodeMatch(keycode *remotes.KeyMapEntry, token string) bool {
-	if tokens, exists := keyCodeTokens[keycode.Keycode]; exists == false {
-		tokens = make([]string, 0, 1)
-		tokens = append(tokens, strings.Split(strings.ToUpper(keycode.Name), " ")...)
-		constant_name := strings.TrimPrefix(strings.ToUpper(fmt.Sprint(keycode.Keycode)), KEYCODE_PREFIX)
-		tokens = append(tokens, constant_name)
-		tokens = append(tokens, strings.Split(constant_name, "_")...)
-		keyCodeTokens[keycode.Keycode] = tokens
-		return fuzzyKeyCodeMatch(keycode, token)
-	} else {
-		for _, t := range tokens {
-			if token == t {
-				return true
-			}
+	for _, t := range keyCodeTokens[keycode.Keycode] {
+		if token == t {
+			return true
 		}
-		return false
 	}
+	return false
+}
+
+func getKeyCodeTokens(keycode *remotes.KeyMapEntry) []string {
+	tokens := make([]string, 0, 1)
+	tokens = append(tokens, strings.Split(strings.ToUpper(keycode.Name), " ")...)
+	constant_name := strings.TrimPrefix(strings.ToUpper(fmt.Sprint(keycode.Keycode)), KEYCODE_PREFIX)
+	tokens = append(tokens, constant_name)
+	tokens = append(tokens, strings.Split(constant_name, "_")...)
+	return tokens
 }
 
 func uniqueKeyMapPath(codec remotes.CodecType, device uint32, root, ext string) string {
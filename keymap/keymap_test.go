@@ -0,0 +1,439 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package keymap_test
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/keymap"
+)
+
+// These tests are most useful with the race detector:
+//
+//   go test -race github.com/djthorpe/remotes/keymap
+
+const (
+	TEST_KEYMAPS    = 4
+	TEST_GOROUTINES = 8
+	TEST_ITERATIONS = 200
+	TEST_KEYCODES   = 16
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestConcurrentReadersAndWriters(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+
+	keymaps := createKeyMaps(t, db)
+	keycodes := db.LookupKeyCode()[:TEST_KEYCODES]
+
+	var wg sync.WaitGroup
+	run := func(f func(i, j int)) {
+		for i := 0; i < TEST_GOROUTINES; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < TEST_ITERATIONS; j++ {
+					f(i, j)
+				}
+			}(i)
+		}
+	}
+
+	// Writers set, re-learn and delete entries and change the keymaps
+	run(func(i, j int) {
+		km := keymaps[(i+j)%len(keymaps)]
+		keycode := keycodes[j%len(keycodes)].Keycode
+		if err := db.SetKeyMapEntry(km, remotes.CODEC_NEC32, km.Device, keycode, uint32(i*j)); err != nil {
+			t.Error(err)
+		}
+		if j%3 == 0 {
+			if err := db.SetKeyMapPulses(km, keycode, []uint32{100, 200}); err != nil && err != remotes.ErrNotFound {
+				t.Error(err)
+			}
+		}
+		if j%5 == 0 {
+			entry := &remotes.KeyMapEntry{Keycode: keycode}
+			if err := db.DeleteKeyMapEntry(km, entry); err != nil && err != remotes.ErrNotFound {
+				t.Error(err)
+			}
+		}
+		if err := db.SetRepeats(km, uint(j%4)); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Keymaps are renamed, and created and deleted
+	run(func(i, j int) {
+		km := keymaps[i%len(keymaps)]
+		if err := db.SetName(km, fmt.Sprintf("keymap %v", i%len(keymaps))); err != nil {
+			t.Error(err)
+		}
+		name := fmt.Sprintf("goroutine %v", i)
+		if created, err := db.CreateKeyMap(name, remotes.CODEC_SONY12, uint32(i)); err != nil {
+			t.Error(err)
+		} else if err := db.SetKeyMapEntry(created, remotes.CODEC_SONY12, uint32(i), keycodes[0].Keycode, uint32(j)); err != nil {
+			t.Error(err)
+		} else if err := db.DeleteKeyMap(created); err != nil {
+			t.Error(err)
+		}
+	})
+
+	// Readers lookup entries and keymaps, and read what is returned
+	read := func(km *remotes.KeyMap) {
+		if km.Name == "" {
+			t.Error("Expected a keymap name")
+		}
+		for _, entry := range km.Map {
+			if entry.Keycode == remotes.KEYCODE_NONE || len(entry.Pulses) > 2 {
+				t.Error("Unexpected entry", entry)
+			}
+		}
+	}
+	run(func(i, j int) {
+		km := keymaps[(i+j)%len(keymaps)]
+		for _, entry := range db.GetKeyMapEntry(km, remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, remotes.KEYCODE_NONE, remotes.SCANCODE_UNKNOWN) {
+			if entry.Keycode == remotes.KEYCODE_NONE {
+				t.Error("Unexpected entry", entry)
+			}
+		}
+		for entry, km := range db.LookupKeyMapEntry(remotes.CODEC_NEC32, remotes.DEVICE_UNKNOWN, uint32(i*j)) {
+			if entry.Scancode != uint32(i*j) {
+				t.Error("Unexpected entry", entry)
+			}
+			read(km)
+		}
+		for _, km := range db.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, "") {
+			read(km)
+		}
+		db.LookupKeyCode("volume")
+		db.Modified()
+	})
+
+	// Modified keymaps are saved
+	run(func(i, j int) {
+		if j%20 == 0 {
+			if err := db.SaveModifiedKeyMaps(nil); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	wg.Wait()
+
+	// Check the indexes have no stale entries
+	for _, km := range keymaps {
+		checkIndex(t, db, km)
+	}
+}
+
+func TestKeyMapCopies(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+
+	keymaps := createKeyMaps(t, db)
+	keycode := db.LookupKeyCode()[0].Keycode
+
+	// Keymaps returned are copies, which are not changed by the database
+	km := keymaps[0]
+	if err := db.SetKeyMapEntry(km, km.Type, km.Device, keycode, 0x10); err != nil {
+		t.Fatal(err)
+	} else if err := db.SetName(km, "renamed"); err != nil {
+		t.Fatal(err)
+	} else if km.Name != "keymap 0" || len(km.Map) != 0 {
+		t.Error("Expected the keymap to be unchanged:", km)
+	}
+
+	// Modifying a copy does not change the database
+	copies := db.KeyMaps(km.Type, km.Device, "")
+	if len(copies) != 1 {
+		t.Fatal("Expected one keymap, got", copies)
+	} else if copies[0].Name != "renamed" || len(copies[0].Map) != 1 {
+		t.Error("Unexpected keymap:", copies[0])
+	}
+	copies[0].Name = "modified"
+	copies[0].Map[0].Scancode = 0x20
+	for entry, km := range db.LookupKeyMapEntry(km.Type, km.Device, remotes.SCANCODE_UNKNOWN) {
+		if km.Name != "renamed" || entry.Scancode != 0x10 || km.Map[0].Scancode != 0x10 {
+			t.Error("Unexpected entry:", km, entry)
+		}
+	}
+}
+
+func TestReLearnKey(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+
+	km := createKeyMaps(t, db)[0]
+	keycode := db.LookupKeyCode()[0].Keycode
+
+	// Learn the same key many times from many goroutines, and check only
+	// one entry is indexed
+	var wg sync.WaitGroup
+	for i := 0; i < TEST_GOROUTINES; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < TEST_ITERATIONS; j++ {
+				if err := db.SetKeyMapEntry(km, km.Type, km.Device, keycode, uint32(i*TEST_ITERATIONS+j)); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if entries := db.LookupKeyMapEntry(km.Type, km.Device, remotes.SCANCODE_UNKNOWN); len(entries) != 1 {
+		t.Errorf("Expected one entry, got %v", len(entries))
+	}
+	checkIndex(t, db, km)
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+
+	keymaps := createKeyMaps(t, db)
+	keycodes := db.LookupKeyCode()[:TEST_KEYCODES]
+
+	// Set entries concurrently and save
+	var wg sync.WaitGroup
+	for _, km := range keymaps {
+		wg.Add(1)
+		go func(km *remotes.KeyMap) {
+			defer wg.Done()
+			for i, keycode := range keycodes {
+				if err := db.SetKeyMapEntry(km, km.Type, km.Device, keycode.Keycode, uint32(i)); err != nil {
+					t.Error(err)
+				}
+			}
+		}(km)
+	}
+	wg.Wait()
+	if err := db.SaveModifiedKeyMaps(nil); err != nil {
+		t.Fatal(err)
+	} else if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Load the keymaps into a new database
+	driver, err := gopi.Open(keymap.Database{Root: root}, newLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = driver.(remotes.KeyMaps)
+	defer db.Close()
+	if err := db.LoadKeyMaps(nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, km := range db.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, "") {
+		if entries := db.LookupKeyMapEntry(km.Type, km.Device, remotes.SCANCODE_UNKNOWN); len(entries) != len(keycodes) {
+			t.Errorf("%v: Expected %v entries, got %v", km.Name, len(keycodes), len(entries))
+		}
+		checkIndex(t, db, km)
+	}
+}
+
+func TestBackups(t *testing.T) {
+	root, err := ioutil.TempDir("", "keymap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	driver, err := gopi.Open(keymap.Database{Root: root, Backups: 2}, newLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := driver.(remotes.KeyMaps)
+	defer db.Close()
+
+	// Save a keymap four times, with a different number of entries
+	km := createKeyMaps(t, db)[0]
+	keycodes := db.LookupKeyCode()[:TEST_KEYCODES]
+	var path string
+	for i := 0; i < 4; i++ {
+		if err := db.SetKeyMapEntry(km, km.Type, km.Device, keycodes[i].Keycode, uint32(i)); err != nil {
+			t.Fatal(err)
+		} else if err := db.SaveModifiedKeyMaps(func(filename string, _ *remotes.KeyMap) {
+			path = filename
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Expect the keymap and two backups, with one and two fewer entries
+	for i, filename := range []string{path, path + ".1", path + ".2"} {
+		if km, err := db.LoadKeyMap(filename); err == nil {
+			t.Errorf("%v: Expected duplicate keymap error", filename)
+		} else if err != remotes.ErrDuplicateKeyMap {
+			t.Errorf("%v: %v", filename, err)
+		} else if km != nil {
+			t.Errorf("%v: Unexpected keymap", filename)
+		} else if entries := countEntries(t, filename); entries != 4-i {
+			t.Errorf("%v: Expected %v entries, got %v", filename, 4-i, entries)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); os.IsNotExist(err) == false {
+		t.Errorf("Expected only two backups")
+	}
+	if files, err := filepath.Glob(filepath.Join(root, "*.tmp")); err != nil {
+		t.Error(err)
+	} else if len(files) > 0 {
+		t.Errorf("Unexpected temporary files: %v", files)
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	createKeyMaps(t, db)
+	if err := db.SaveModifiedKeyMaps(nil); err != nil {
+		t.Fatal(err)
+	} else if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write a truncated and an empty keymap file
+	truncated := filepath.Join(root, "truncated.keymap")
+	empty := filepath.Join(root, "empty.keymap")
+	if err := ioutil.WriteFile(truncated, []byte("<remote><name>truncated</name><ent"), 0644); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Load the keymaps into a new database, which skips the corrupt files
+	driver, err := gopi.Open(keymap.Database{Root: root}, newLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = driver.(remotes.KeyMaps)
+	defer db.Close()
+	if err := db.LoadKeyMaps(nil); err != nil {
+		t.Fatal(err)
+	} else if keymaps := db.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, ""); len(keymaps) != TEST_KEYMAPS {
+		t.Errorf("Expected %v keymaps, got %v", TEST_KEYMAPS, len(keymaps))
+	}
+
+	// Expect the corrupt files to be reported and renamed
+	errors := db.LoadErrors()
+	if len(errors) != 2 {
+		t.Errorf("Expected two load errors, got %v", errors)
+	}
+	for _, path := range []string{truncated, empty} {
+		if _, exists := errors[path]; exists == false {
+			t.Errorf("%v: Expected load error", path)
+		} else if _, err := os.Stat(path); os.IsNotExist(err) == false {
+			t.Errorf("%v: Expected file to be renamed", path)
+		} else if _, err := os.Stat(path + ".corrupt"); err != nil {
+			t.Errorf("%v: %v", path, err)
+		}
+	}
+}
+
+func TestLoadDutyCycle(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+
+	// Duty cycles over 99% are reduced when loaded
+	data := fmt.Sprintf("<remote id=\"256\"><codec>%d</codec><name>carrier</name><carrier frequency=\"38000\" duty=\"100\"></carrier></remote>", remotes.CODEC_NEC32)
+	if err := ioutil.WriteFile(filepath.Join(root, "carrier.keymap"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	} else if err := db.LoadKeyMaps(nil); err != nil {
+		t.Fatal(err)
+	} else if keymaps := db.KeyMaps(remotes.CODEC_NEC32, 0x100, ""); len(keymaps) != 1 {
+		t.Fatalf("Expected one keymap, got %v", keymaps)
+	} else if carrier := keymaps[0].Carrier; carrier == nil || carrier.Frequency != 38000 || carrier.DutyCycle != remotes.MAX_DUTY_CYCLE {
+		t.Errorf("Unexpected carrier %v", carrier)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func newLogger(t *testing.T) gopi.Logger {
+	if driver, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.Logger)
+	}
+}
+
+func openDatabase(t *testing.T) (string, remotes.KeyMaps) {
+	root, err := ioutil.TempDir("", "keymap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := gopi.Open(keymap.Database{Root: root}, newLogger(t))
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatal(err)
+	}
+	return root, driver.(remotes.KeyMaps)
+}
+
+func createKeyMaps(t *testing.T, db remotes.KeyMaps) []*remotes.KeyMap {
+	keymaps := make([]*remotes.KeyMap, TEST_KEYMAPS)
+	for i := range keymaps {
+		if km, err := db.CreateKeyMap(fmt.Sprintf("keymap %v", i), remotes.CODEC_NEC32, uint32(0x100+i)); err != nil {
+			t.Fatal(err)
+		} else {
+			keymaps[i] = km
+		}
+	}
+	return keymaps
+}
+
+// checkIndex checks each entry in a keymap can be looked up, and that
+// there are no other entries for the codec and device
+func checkIndex(t *testing.T, db remotes.KeyMaps, km *remotes.KeyMap) {
+	entries := db.GetKeyMapEntry(km, remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, remotes.KEYCODE_NONE, remotes.SCANCODE_UNKNOWN)
+	for _, entry := range entries {
+		found := false
+		for indexed, indexed_km := range db.LookupKeyMapEntry(entry.Type, entry.Device, entry.Scancode) {
+			if indexed_km.Type == km.Type && indexed_km.Device == km.Device && indexed.Keycode == entry.Keycode {
+				found = true
+			}
+		}
+		if found == false {
+			t.Errorf("%v: Entry not indexed: %v", km.Name, entry)
+		}
+	}
+	if indexed := db.LookupKeyMapEntry(km.Type, km.Device, remotes.SCANCODE_UNKNOWN); len(indexed) != len(entries) {
+		t.Errorf("%v: Expected %v indexed entries, got %v", km.Name, len(entries), len(indexed))
+	}
+}
+
+// countEntries returns the number of entries in a keymap file
+func countEntries(t *testing.T, path string) int {
+	keymap := new(remotes.KeyMap)
+	if data, err := ioutil.ReadFile(path); err != nil {
+		t.Fatal(err)
+	} else if err := xml.Unmarshal(data, keymap); err != nil {
+		t.Fatal(err)
+	}
+	return len(keymap.Map)
+}
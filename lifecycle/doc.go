@@ -0,0 +1,44 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lifecycle
+
+// The lifecycle module tracks the remote events emitted by the codecs for
+// each codec, device and scancode, and emits an event when a key is pressed,
+// when it repeats and when it is released. Codecs only emit events when a
+// transmission is received, so a key is released when there have been no
+// transmissions for the key within the repeat gap, which is set by the
+// -lifecycle.gap flag.
+//
+// Events emitted implement remotes.LifecycleEvent, which reports how long
+// the key has been held and how many times it has repeated:
+//
+//   INPUT_EVENT_KEYPRESS   when the key is first received
+//   INPUT_EVENT_KEYREPEAT  when the key is received again within the gap
+//   INPUT_EVENT_KEYRELEASE when the gap has expired since the key was last received
+//
+// Once a codec has reported a repeat, a key press from the codec releases
+// the held key and presses it again. Codecs which never report repeats send
+// the whole frame again whilst a key is held, so any transmission of a key
+// within the gap is a repeat. When a different key is received from the
+// same codec and device, the held key is released first.
+//
+// The codecs are tracked when the module is run, or can be added and
+// removed with Add and Remove:
+//
+//   import (
+//     _ "github.com/djthorpe/remotes/codec/nec"
+//     _ "github.com/djthorpe/remotes/lifecycle"
+//   )
+//
+//   config := gopi.NewAppConfig("remotes/nec32", "remotes/lifecycle")
+//
+// The timestamp of a release event is the time of the last transmission
+// plus the gap, or the time of the other key when a different key is
+// received.
@@ -0,0 +1,46 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lifecycle
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register remotes/lifecycle
+	gopi.RegisterModule(gopi.Module{
+		Name: "remotes/lifecycle",
+		Type: gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagDuration("lifecycle.gap", GAP, "Time after the last transmission before a key is released")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			gap, _ := app.AppFlags.GetDuration("lifecycle.gap")
+			return gopi.Open(Lifecycle{
+				Gap: gap,
+			}, app.Logger)
+		},
+		Run: func(app *gopi.AppInstance, driver gopi.Driver) error {
+			// Track codecs
+			for _, codec := range remotes.ModuleCodecs(app) {
+				if err := driver.(remotes.Lifecycle).Add(codec); err != nil {
+					return err
+				}
+			}
+			// Success
+			return nil
+		},
+	})
+}
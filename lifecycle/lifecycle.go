@@ -0,0 +1,310 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lifecycle
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Lifecycle Configuration
+type Lifecycle struct {
+	Gap time.Duration // Time after the last transmission before a key is released, or zero for the default
+}
+
+type lifecycle struct {
+	log       gopi.Logger
+	gap       time.Duration
+	codecs    map[remotes.Codec]<-chan gopi.Event
+	events    chan remotes.RemoteEvent
+	done      chan struct{}
+	keys      map[key]*state
+	repeating map[remotes.CodecType]bool // Codecs which report repeats
+
+	sync.Mutex
+	event.Publisher
+	event.Tasks
+}
+
+// key identifies a key on a remote
+type key struct {
+	codec    remotes.CodecType
+	device   uint32
+	scancode uint32
+}
+
+// state is a key which is being held
+type state struct {
+	evt     remotes.RemoteEvent // Event which pressed the key
+	last    time.Duration       // Timestamp of the last transmission
+	expires time.Time           // Time at which the key is released
+	repeats uint
+}
+
+type lifecycleevent struct {
+	remotes.RemoteEvent
+	evt_type gopi.InputEventType
+	ts       time.Duration
+	held     time.Duration
+	repeats  uint
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GAP = 200 * time.Millisecond // Default time before a key is released
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Lifecycle) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.Lifecycle>Open{ gap=%v }", config.Gap)
+
+	this := new(lifecycle)
+	this.log = log
+	this.gap = config.Gap
+	this.codecs = make(map[remotes.Codec]<-chan gopi.Event)
+	this.events = make(chan remotes.RemoteEvent)
+	this.done = make(chan struct{})
+	this.keys = make(map[key]*state)
+	this.repeating = make(map[remotes.CodecType]bool)
+
+	// Set default gap
+	if this.gap == 0 {
+		this.gap = GAP
+	} else if this.gap < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Start background task which tracks the keys
+	this.Tasks.Start(this.EventTask)
+
+	// Return success
+	return this, nil
+}
+
+func (this *lifecycle) Close() error {
+	this.log.Debug("<remotes.Lifecycle>Close{ gap=%v }", this.gap)
+
+	// Discard events from the codecs, which may already be closed, and
+	// unsubscribe from them
+	this.Lock()
+	close(this.done)
+	for codec, subscriber := range this.codecs {
+		codec.Unsubscribe(subscriber)
+	}
+	this.codecs = nil
+	this.Unlock()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Remove subscribers
+	this.Publisher.Close()
+
+	// Release resources
+	this.keys = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *lifecycle) String() string {
+	return fmt.Sprintf("<remotes.Lifecycle>{ gap=%v }", this.gap)
+}
+
+func (this *lifecycleevent) String() string {
+	return fmt.Sprintf("remotes.LifecycleEvent{ scancode=0x%X device=0x%X type=%v held=%v repeats=%v codec=%v ts=%v }", this.ScanCode(), this.Device(), this.evt_type, this.held, this.repeats, this.Codec(), this.ts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE INTERFACE
+
+func (this *lifecycle) Add(codec remotes.Codec) error {
+	this.log.Debug2("<remotes.Lifecycle>Add{ codec=%v }", codec)
+	this.Lock()
+	defer this.Unlock()
+
+	if codec == nil || this.codecs == nil {
+		return gopi.ErrBadParameter
+	} else if _, exists := this.codecs[codec]; exists {
+		return nil
+	}
+
+	// Forward events from the codec to the background task
+	subscriber := codec.Subscribe()
+	this.codecs[codec] = subscriber
+	go this.forward(subscriber)
+
+	return nil
+}
+
+func (this *lifecycle) Remove(codec remotes.Codec) error {
+	this.log.Debug2("<remotes.Lifecycle>Remove{ codec=%v }", codec)
+	this.Lock()
+	defer this.Unlock()
+
+	if codec == nil || this.codecs == nil {
+		return gopi.ErrBadParameter
+	} else if subscriber, exists := this.codecs[codec]; exists == false {
+		return gopi.ErrNotFound
+	} else {
+		codec.Unsubscribe(subscriber)
+		delete(this.codecs, codec)
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLEEVENT INTERFACE
+
+func (this *lifecycleevent) Name() string {
+	return "LifecycleEvent"
+}
+
+func (this *lifecycleevent) EventType() gopi.InputEventType {
+	return this.evt_type
+}
+
+func (this *lifecycleevent) Timestamp() time.Duration {
+	return this.ts
+}
+
+func (this *lifecycleevent) Held() time.Duration {
+	return this.held
+}
+
+func (this *lifecycleevent) Repeats() uint {
+	return this.repeats
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *lifecycle) EventTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		// Wake up when the next key is released
+		var expires <-chan time.Time
+		if next, exists := this.next(); exists {
+			expires = time.After(time.Until(next))
+		}
+		select {
+		case evt := <-this.events:
+			this.receive(evt, time.Now())
+		case now := <-expires:
+			this.release(now)
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *lifecycle) receive(evt remotes.RemoteEvent, now time.Time) {
+	this.log.Debug2("<remotes.Lifecycle>Receive{ evt=%v }", evt)
+
+	// A key press from a codec which reports repeats is a new press of the
+	// key. Other codecs resend the whole frame whilst a key is held, so any
+	// transmission of the key within the gap is a repeat
+	if evt.EventType() == gopi.INPUT_EVENT_KEYREPEAT {
+		this.repeating[evt.Codec()] = true
+	}
+	pressed := key{evt.Codec(), evt.Device(), evt.ScanCode()}
+	new_press := evt.EventType() == gopi.INPUT_EVENT_KEYPRESS && this.repeating[evt.Codec()]
+
+	// Release any other key which is held on the same remote, and the key
+	// itself when it is pressed again
+	for other, held := range this.keys {
+		if (other != pressed || new_press) && other.codec == pressed.codec && other.device == pressed.device {
+			this.emit(held, gopi.INPUT_EVENT_KEYRELEASE, evt.Timestamp())
+			delete(this.keys, other)
+		}
+	}
+
+	// Repeat the key when it is held, or else press it
+	if held, exists := this.keys[pressed]; exists {
+		held.repeats++
+		held.last = evt.Timestamp()
+		held.expires = now.Add(this.gap)
+		this.emit(held, gopi.INPUT_EVENT_KEYREPEAT, held.last)
+	} else {
+		held := &state{evt, evt.Timestamp(), now.Add(this.gap), 0}
+		this.keys[pressed] = held
+		this.emit(held, gopi.INPUT_EVENT_KEYPRESS, held.last)
+	}
+}
+
+// forward sends remote events from a codec to the background task until
+// the codec is removed. Events are discarded once closed, so that the
+// codec does not block
+func (this *lifecycle) forward(subscriber <-chan gopi.Event) {
+	for evt := range subscriber {
+		if remote_evt, ok := evt.(remotes.RemoteEvent); ok && remote_evt != nil {
+			select {
+			case this.events <- remote_evt:
+			case <-this.done:
+			}
+		}
+	}
+}
+
+// release emits release events for keys which have expired
+func (this *lifecycle) release(now time.Time) {
+	for pressed, held := range this.keys {
+		if now.Before(held.expires) == false {
+			this.emit(held, gopi.INPUT_EVENT_KEYRELEASE, held.last+this.gap)
+			delete(this.keys, pressed)
+		}
+	}
+}
+
+// next returns the earliest time at which a key is released
+func (this *lifecycle) next() (time.Time, bool) {
+	var next time.Time
+	for _, held := range this.keys {
+		if next.IsZero() || held.expires.Before(next) {
+			next = held.expires
+		}
+	}
+	return next, next.IsZero() == false
+}
+
+func (this *lifecycle) emit(held *state, evt_type gopi.InputEventType, ts time.Duration) {
+	this.Publisher.Emit(&lifecycleevent{
+		RemoteEvent: held.evt,
+		evt_type:    evt_type,
+		ts:          ts,
+		held:        ts - held.evt.Timestamp(),
+		repeats:     held.repeats,
+	})
+}
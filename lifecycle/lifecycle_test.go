@@ -0,0 +1,156 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package lifecycle_test
+
+import (
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/lifecycle"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GAP = 100 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestPressRepeatRelease(t *testing.T) {
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	module, events := openLifecycle(t, codec)
+	defer closeLifecycle(t, module, events)
+
+	// Repeats are counted from the key press, and the key is released
+	// after the gap
+	codec.Receive(0, 0x40, 0x12, false)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYPRESS, 0, 0)
+	codec.Receive(108*time.Millisecond, 0x40, 0x12, true)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYREPEAT, 108*time.Millisecond, 1)
+	codec.Receive(216*time.Millisecond, 0x40, 0x12, true)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYREPEAT, 216*time.Millisecond, 2)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYRELEASE, 216*time.Millisecond+GAP, 2)
+}
+
+func TestNewPress(t *testing.T) {
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	module, events := openLifecycle(t, codec)
+	defer closeLifecycle(t, module, events)
+
+	// A key press from a codec which reports repeats releases the key and
+	// presses it again, even within the gap
+	codec.Receive(0, 0x40, 0x12, false)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYPRESS, 0, 0)
+	codec.Receive(108*time.Millisecond, 0x40, 0x12, true)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYREPEAT, 108*time.Millisecond, 1)
+	codec.Receive(150*time.Millisecond, 0x40, 0x12, false)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYRELEASE, 150*time.Millisecond, 1)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYPRESS, 0, 0)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYRELEASE, GAP, 0)
+}
+
+func TestWholeFrames(t *testing.T) {
+	codec := remotestest.NewCodec(remotes.CODEC_SONY12)
+	module, events := openLifecycle(t, codec)
+	defer closeLifecycle(t, module, events)
+
+	// A codec which never reports repeats resends the whole frame whilst
+	// a key is held, so key presses within the gap are repeats
+	codec.Receive(0, 0x01, 0x12, false)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYPRESS, 0, 0)
+	codec.Receive(45*time.Millisecond, 0x01, 0x12, false)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYREPEAT, 45*time.Millisecond, 1)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYRELEASE, 45*time.Millisecond+GAP, 1)
+}
+
+func TestOtherKey(t *testing.T) {
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	module, events := openLifecycle(t, codec)
+	defer closeLifecycle(t, module, events)
+
+	// A different key on the same remote releases the held key
+	codec.Receive(0, 0x40, 0x12, false)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYPRESS, 0, 0)
+	codec.Receive(20*time.Millisecond, 0x40, 0x13, false)
+	checkEvent(t, events, 0x12, gopi.INPUT_EVENT_KEYRELEASE, 20*time.Millisecond, 0)
+	checkEvent(t, events, 0x13, gopi.INPUT_EVENT_KEYPRESS, 0, 0)
+	checkEvent(t, events, 0x13, gopi.INPUT_EVENT_KEYRELEASE, GAP, 0)
+}
+
+func TestClose(t *testing.T) {
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	module, events := openLifecycle(t, codec)
+
+	// The module unsubscribes from the codecs when closed
+	if codec.Subscribers() != 1 {
+		t.Errorf("Expected one subscriber, got %v", codec.Subscribers())
+	}
+	closeLifecycle(t, module, events)
+	if codec.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers, got %v", codec.Subscribers())
+	}
+	if err := module.Add(codec); err != gopi.ErrBadParameter {
+		t.Errorf("Expected ErrBadParameter, got %v", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openLifecycle(t *testing.T, codec remotes.Codec) (remotes.Lifecycle, <-chan gopi.Event) {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(lifecycle.Lifecycle{Gap: GAP}, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+	} else if err := driver.(remotes.Lifecycle).Add(codec); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(remotes.Lifecycle), driver.(remotes.Lifecycle).Subscribe()
+	}
+	return nil, nil
+}
+
+// closeLifecycle closes the module, discarding any events which are
+// emitted whilst it is closed
+func closeLifecycle(t *testing.T, module remotes.Lifecycle, events <-chan gopi.Event) {
+	t.Helper()
+	go func() {
+		for range events {
+		}
+	}()
+	if err := module.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// checkEvent checks the next event, including the time since the key was
+// pressed and the number of repeats
+func checkEvent(t *testing.T, events <-chan gopi.Event, scancode uint32, event_type gopi.InputEventType, held time.Duration, repeats uint) {
+	t.Helper()
+	select {
+	case evt := <-events:
+		if lifecycle_evt, ok := evt.(remotes.LifecycleEvent); ok == false {
+			t.Errorf("Expected a lifecycle event, got %v", evt)
+		} else if lifecycle_evt.ScanCode() != scancode || lifecycle_evt.EventType() != event_type || lifecycle_evt.Held() != held || lifecycle_evt.Repeats() != repeats {
+			t.Errorf("Expected scancode=0x%X type=%v held=%v repeats=%v, got %v", scancode, event_type, held, repeats, evt)
+		}
+	case <-time.After(GAP * 10):
+		t.Errorf("Expected scancode=0x%X type=%v, got no event", scancode, event_type)
+	}
+}
@@ -0,0 +1,34 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mapper
+
+// The mapper module resolves the remote events emitted by the codecs
+// through the keymap database, and emits an event for each keymap entry
+// which matches the codec, device and scancode. The events emitted
+// implement remotes.KeyMapEvent, so that KeyCode returns the keycode for
+// the entry, and KeyName and KeyMapName return the names of the key and
+// the keymap.
+//
+// When the lifecycle module is used, the mapper resolves the events from
+// the lifecycle instead of the codecs, so that key releases are mapped,
+// and the events emitted also implement remotes.LifecycleEvent:
+//
+//   import (
+//     _ "github.com/djthorpe/remotes/codec/nec"
+//     _ "github.com/djthorpe/remotes/keymap"
+//     _ "github.com/djthorpe/remotes/lifecycle"
+//     _ "github.com/djthorpe/remotes/mapper"
+//   )
+//
+//   config := gopi.NewAppConfig("remotes/nec32", "remotes/lifecycle", "remotes/mapper")
+//
+// Events which are not in any keymap are discarded, unless the
+// -mapper.unmapped flag is set, in which case they are emitted with
+// KEYCODE_NONE and empty names.
@@ -0,0 +1,52 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mapper
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register remotes/mapper
+	gopi.RegisterModule(gopi.Module{
+		Name:     "remotes/mapper",
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Requires: []string{"keymap"},
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagBool("mapper.unmapped", false, "Emit events which are not in any keymap")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			unmapped, _ := app.AppFlags.GetBool("mapper.unmapped")
+			return gopi.Open(Mapper{
+				KeyMaps:  app.ModuleInstance("keymap").(remotes.KeyMaps),
+				Unmapped: unmapped,
+			}, app.Logger)
+		},
+		Run: func(app *gopi.AppInstance, driver gopi.Driver) error {
+			// Resolve events from the lifecycle when it is used, or else
+			// from the codecs
+			if lifecycle, ok := app.ModuleInstance("remotes/lifecycle").(remotes.Lifecycle); ok && lifecycle != nil {
+				return driver.(remotes.Mapper).Add(lifecycle)
+			}
+			for _, codec := range remotes.ModuleCodecs(app) {
+				if err := driver.(remotes.Mapper).Add(codec); err != nil {
+					return err
+				}
+			}
+			// Success
+			return nil
+		},
+	})
+}
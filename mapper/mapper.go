@@ -0,0 +1,257 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mapper
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Mapper Configuration
+type Mapper struct {
+	KeyMaps  remotes.KeyMaps
+	Unmapped bool // Emit events which are not in any keymap
+}
+
+type mapper struct {
+	log        gopi.Logger
+	keymaps    remotes.KeyMaps
+	unmapped   bool
+	publishers map[gopi.Publisher]<-chan gopi.Event
+	events     chan remotes.RemoteEvent
+	done       chan struct{}
+
+	sync.Mutex
+	event.Publisher
+	event.Tasks
+}
+
+type keymapevent struct {
+	remotes.RemoteEvent
+	keymap *remotes.KeyMap
+	entry  *remotes.KeyMapEntry
+}
+
+// lifecycleevent is a keymap event for a key press, repeat or release
+type lifecycleevent struct {
+	*keymapevent
+	held    time.Duration
+	repeats uint
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Mapper) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.Mapper>Open{ keymaps=%v unmapped=%v }", config.KeyMaps, config.Unmapped)
+
+	if config.KeyMaps == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(mapper)
+	this.log = log
+	this.keymaps = config.KeyMaps
+	this.unmapped = config.Unmapped
+	this.publishers = make(map[gopi.Publisher]<-chan gopi.Event)
+	this.events = make(chan remotes.RemoteEvent)
+	this.done = make(chan struct{})
+
+	// Start background task which resolves the events
+	this.Tasks.Start(this.EventTask)
+
+	// Return success
+	return this, nil
+}
+
+func (this *mapper) Close() error {
+	this.log.Debug("<remotes.Mapper>Close{ unmapped=%v }", this.unmapped)
+
+	// Discard events from the publishers, which may already be closed, and
+	// unsubscribe from them
+	this.Lock()
+	close(this.done)
+	for publisher, subscriber := range this.publishers {
+		publisher.Unsubscribe(subscriber)
+	}
+	this.publishers = nil
+	this.Unlock()
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Remove subscribers
+	this.Publisher.Close()
+
+	// Release resources
+	this.keymaps = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *mapper) String() string {
+	return fmt.Sprintf("<remotes.Mapper>{ keymaps=%v unmapped=%v }", this.keymaps, this.unmapped)
+}
+
+func (this *keymapevent) String() string {
+	return fmt.Sprintf("remotes.KeyMapEvent{ keymap=%v name=%v keycode=%v scancode=0x%X device=0x%X type=%v codec=%v ts=%v }", this.KeyMapName(), this.KeyName(), this.KeyCode(), this.ScanCode(), this.Device(), this.EventType(), this.Codec(), this.Timestamp())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MAPPER INTERFACE
+
+func (this *mapper) Add(publisher gopi.Publisher) error {
+	this.log.Debug2("<remotes.Mapper>Add{ publisher=%v }", publisher)
+	this.Lock()
+	defer this.Unlock()
+
+	if publisher == nil || this.publishers == nil {
+		return gopi.ErrBadParameter
+	} else if _, exists := this.publishers[publisher]; exists {
+		return nil
+	}
+
+	// Forward events from the publisher to the background task
+	subscriber := publisher.Subscribe()
+	this.publishers[publisher] = subscriber
+	go this.forward(subscriber)
+
+	return nil
+}
+
+func (this *mapper) Remove(publisher gopi.Publisher) error {
+	this.log.Debug2("<remotes.Mapper>Remove{ publisher=%v }", publisher)
+	this.Lock()
+	defer this.Unlock()
+
+	if publisher == nil || this.publishers == nil {
+		return gopi.ErrBadParameter
+	} else if subscriber, exists := this.publishers[publisher]; exists == false {
+		return gopi.ErrNotFound
+	} else {
+		publisher.Unsubscribe(subscriber)
+		delete(this.publishers, publisher)
+	}
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// KEYMAPEVENT INTERFACE
+
+func (this *keymapevent) Name() string {
+	return "KeyMapEvent"
+}
+
+func (this *keymapevent) KeyCode() gopi.KeyCode {
+	if this.entry == nil {
+		return gopi.KEYCODE_NONE
+	} else {
+		return gopi.KeyCode(this.entry.Keycode)
+	}
+}
+
+func (this *keymapevent) KeyName() string {
+	if this.entry == nil {
+		return ""
+	} else {
+		return this.entry.Name
+	}
+}
+
+func (this *keymapevent) KeyMapName() string {
+	if this.keymap == nil {
+		return ""
+	} else {
+		return this.keymap.Name
+	}
+}
+
+func (this *lifecycleevent) Held() time.Duration {
+	return this.held
+}
+
+func (this *lifecycleevent) Repeats() uint {
+	return this.repeats
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *mapper) EventTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-this.events:
+			this.receive(evt)
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *mapper) receive(evt remotes.RemoteEvent) {
+	this.log.Debug2("<remotes.Mapper>Receive{ evt=%v }", evt)
+
+	// Emit an event for each entry which matches, or an unmapped event
+	if entries := this.keymaps.LookupKeyMapEntry(evt.Codec(), evt.Device(), evt.ScanCode()); len(entries) > 0 {
+		for entry, keymap := range entries {
+			this.Publisher.Emit(newKeyMapEvent(evt, keymap, entry))
+		}
+	} else if this.unmapped {
+		this.Publisher.Emit(newKeyMapEvent(evt, nil, nil))
+	}
+}
+
+// forward sends remote events from a publisher to the background task
+// until the publisher is removed. Events are discarded once closed, so
+// that the publisher does not block
+func (this *mapper) forward(subscriber <-chan gopi.Event) {
+	for evt := range subscriber {
+		if remote_evt, ok := evt.(remotes.RemoteEvent); ok && remote_evt != nil {
+			select {
+			case this.events <- remote_evt:
+			case <-this.done:
+			}
+		}
+	}
+}
+
+// newKeyMapEvent returns an event for a keymap entry, which is also a
+// lifecycle event when the remote event is
+func newKeyMapEvent(evt remotes.RemoteEvent, keymap *remotes.KeyMap, entry *remotes.KeyMapEntry) remotes.KeyMapEvent {
+	keymap_evt := &keymapevent{evt, keymap, entry}
+	if lifecycle_evt, ok := evt.(remotes.LifecycleEvent); ok {
+		return &lifecycleevent{keymap_evt, lifecycle_evt.Held(), lifecycle_evt.Repeats()}
+	} else {
+		return keymap_evt
+	}
+}
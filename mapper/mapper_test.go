@@ -0,0 +1,201 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package mapper_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/keymap"
+	"github.com/djthorpe/remotes/lifecycle"
+	"github.com/djthorpe/remotes/mapper"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	DEVICE   = 0x40
+	SCANCODE = 0x12
+	TIMEOUT  = time.Second
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestMapped(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	module, events := openMapper(t, db, false, codec)
+	defer closeMapper(t, module, events)
+
+	// Events which are not in a keymap are discarded
+	codec.Receive(0, DEVICE, SCANCODE+1, false)
+	codec.Receive(0, DEVICE, SCANCODE, false)
+	evt := nextEvent(t, events)
+	if evt == nil {
+		return
+	} else if evt.KeyCode() != gopi.KeyCode(remotes.KEYCODE_VOLUME_UP) || evt.ScanCode() != SCANCODE || evt.EventType() != gopi.INPUT_EVENT_KEYPRESS {
+		t.Errorf("Unexpected event %v", evt)
+	} else if evt.KeyName() == "" || evt.KeyMapName() != "TV" {
+		t.Errorf("Unexpected names in %v", evt)
+	} else if _, ok := evt.(remotes.LifecycleEvent); ok {
+		t.Errorf("Expected a codec event, got %v", evt)
+	}
+}
+
+func TestUnmapped(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	module, events := openMapper(t, db, true, codec)
+	defer closeMapper(t, module, events)
+
+	// Events which are not in a keymap are emitted without a keycode
+	codec.Receive(0, DEVICE, SCANCODE+1, true)
+	if evt := nextEvent(t, events); evt == nil {
+		return
+	} else if evt.KeyCode() != gopi.KEYCODE_NONE || evt.ScanCode() != SCANCODE+1 || evt.EventType() != gopi.INPUT_EVENT_KEYREPEAT {
+		t.Errorf("Unexpected event %v", evt)
+	} else if evt.KeyName() != "" || evt.KeyMapName() != "" {
+		t.Errorf("Unexpected names in %v", evt)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	driver, err := gopi.Open(lifecycle.Lifecycle{}, newLogger(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	if err := driver.(remotes.Lifecycle).Add(codec); err != nil {
+		t.Fatal(err)
+	}
+	module, events := openMapper(t, db, false, driver.(remotes.Lifecycle))
+	defer closeMapper(t, module, events)
+
+	// Events from the lifecycle are mapped, including the key release
+	codec.Receive(0, DEVICE, SCANCODE, false)
+	for _, event_type := range []gopi.InputEventType{gopi.INPUT_EVENT_KEYPRESS, gopi.INPUT_EVENT_KEYRELEASE} {
+		if evt := nextEvent(t, events); evt == nil {
+			return
+		} else if lifecycle_evt, ok := evt.(remotes.LifecycleEvent); ok == false {
+			t.Errorf("Expected a lifecycle event, got %v", evt)
+		} else if evt.KeyCode() != gopi.KeyCode(remotes.KEYCODE_VOLUME_UP) || evt.EventType() != event_type || lifecycle_evt.Repeats() != 0 {
+			t.Errorf("Unexpected event %v", evt)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	root, db := openDatabase(t)
+	defer os.RemoveAll(root)
+	defer db.Close()
+	codec := remotestest.NewCodec(remotes.CODEC_NEC32)
+	module, events := openMapper(t, db, false, codec)
+
+	// The module unsubscribes from the publishers when closed
+	if codec.Subscribers() != 1 {
+		t.Errorf("Expected one subscriber, got %v", codec.Subscribers())
+	}
+	closeMapper(t, module, events)
+	if codec.Subscribers() != 0 {
+		t.Errorf("Expected no subscribers, got %v", codec.Subscribers())
+	}
+	if err := module.Add(codec); err != gopi.ErrBadParameter {
+		t.Errorf("Expected ErrBadParameter, got %v", err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func newLogger(t *testing.T) gopi.Logger {
+	if driver, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.Logger)
+	}
+}
+
+// openDatabase returns a database with a keymap for the device
+func openDatabase(t *testing.T) (string, remotes.KeyMaps) {
+	root, err := ioutil.TempDir("", "mapper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := gopi.Open(keymap.Database{Root: root}, newLogger(t))
+	if err != nil {
+		os.RemoveAll(root)
+		t.Fatal(err)
+	}
+	db := driver.(remotes.KeyMaps)
+	if km, err := db.CreateKeyMap("TV", remotes.CODEC_NEC32, DEVICE); err != nil {
+		t.Fatal(err)
+	} else if err := db.SetKeyMapEntry(km, remotes.CODEC_NEC32, DEVICE, remotes.KEYCODE_VOLUME_UP, SCANCODE); err != nil {
+		t.Fatal(err)
+	}
+	return root, db
+}
+
+func openMapper(t *testing.T, db remotes.KeyMaps, unmapped bool, publisher gopi.Publisher) (remotes.Mapper, <-chan gopi.Event) {
+	t.Helper()
+	if driver, err := gopi.Open(mapper.Mapper{KeyMaps: db, Unmapped: unmapped}, newLogger(t)); err != nil {
+		t.Fatal(err)
+	} else if err := driver.(remotes.Mapper).Add(publisher); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(remotes.Mapper), driver.(remotes.Mapper).Subscribe()
+	}
+	return nil, nil
+}
+
+// closeMapper closes the module, discarding any events which are
+// emitted whilst it is closed
+func closeMapper(t *testing.T, module remotes.Mapper, events <-chan gopi.Event) {
+	t.Helper()
+	go func() {
+		for range events {
+		}
+	}()
+	if err := module.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func nextEvent(t *testing.T, events <-chan gopi.Event) remotes.KeyMapEvent {
+	t.Helper()
+	select {
+	case evt := <-events:
+		if keymap_evt, ok := evt.(remotes.KeyMapEvent); ok == false {
+			t.Errorf("Expected a keymap event, got %v", evt)
+		} else {
+			return keymap_evt
+		}
+	case <-time.After(TIMEOUT):
+		t.Error("Expected an event, got none")
+	}
+	return nil
+}
@@ -10,6 +10,8 @@ package remotes
 
 import (
 	"math"
+	"sort"
+	"sync"
 
 	"github.com/djthorpe/gopi"
 )
@@ -21,6 +23,24 @@ import (
 type MarkSpace struct {
 	Type            gopi.LIRCType
 	Value, Min, Max uint32
+	Tolerance       uint32
+}
+
+// Timing matches mark and space values with a tolerance which can be set
+// for each codec instance. Remotes which are slower or faster than the
+// nominal values are calibrated by device, and a frame which only matches
+// the calibrated values is accepted for that device alone
+type Timing struct {
+	calibrations   *calibrations
+	def, tolerance uint32
+	device         *Calibration    // Matches only the values for one calibrated device when not nil
+	nominal        bool            // False when the frame doesn't match the nominal values
+	excluded       map[uint32]bool // Calibrated devices which don't match the frame
+}
+
+type calibrations struct {
+	sync.RWMutex
+	devices map[uint32]*Calibration
 }
 
 func NewMarkSpace(t gopi.LIRCType, value, tolerance uint32) *MarkSpace {
@@ -34,6 +54,7 @@ func (m *MarkSpace) Set(value, tolerance uint32) {
 	m.Min = uint32(math.Max(0, float64(value)-delta))
 	m.Max = uint32(float64(value) + delta)
 	m.Value = value
+	m.Tolerance = tolerance
 }
 
 func (m *MarkSpace) Matches(evt gopi.LIRCEvent) bool {
@@ -68,3 +89,184 @@ func (m *MarkSpace) LessThan(evt gopi.LIRCEvent) bool {
 	}
 	return true
 }
+
+/////////////////////////////////////////////////////////////////////
+// Timing implementation
+
+// NewTiming returns timing for a codec instance, where def is the default
+// tolerance for the codec as a percentage and tolerance overrides it when
+// non-zero. Mark and space values with a tolerance other than the default
+// keep their own tolerance
+func NewTiming(def, tolerance uint32) (*Timing, error) {
+	if tolerance == 0 {
+		tolerance = def
+	}
+	if tolerance > 100 {
+		return nil, gopi.ErrBadParameter
+	}
+	return &Timing{
+		calibrations: &calibrations{devices: make(map[uint32]*Calibration)},
+		def:          def,
+		tolerance:    tolerance,
+		nominal:      true,
+	}, nil
+}
+
+// Copy returns timing with the same tolerance and calibrations, and
+// separate state for the frame being matched
+func (t *Timing) Copy() *Timing {
+	return &Timing{calibrations: t.calibrations, def: t.def, tolerance: t.tolerance, device: t.device, nominal: true}
+}
+
+// Device returns timing which only matches the calibrated values for
+// a device, or nil if the device is not calibrated
+func (t *Timing) Device(device uint32) *Timing {
+	t.calibrations.RLock()
+	defer t.calibrations.RUnlock()
+	if calibration, exists := t.calibrations.devices[device]; exists == false {
+		return nil
+	} else {
+		return &Timing{calibrations: t.calibrations, def: t.def, tolerance: t.tolerance, device: calibration, nominal: true}
+	}
+}
+
+// Tolerance returns the tolerance as a percentage
+func (t *Timing) Tolerance() uint32 {
+	return t.tolerance
+}
+
+// Calibrate sets the calibration for a remote device, so that frames from
+// the device are matched with slower or faster values than the nominal values
+func (t *Timing) Calibrate(device uint32, calibration *Calibration) error {
+	if calibration == nil || calibration.Pulse <= 0 || calibration.Space <= 0 {
+		return gopi.ErrBadParameter
+	}
+	t.calibrations.Lock()
+	defer t.calibrations.Unlock()
+	t.calibrations.devices[device] = calibration
+	return nil
+}
+
+// Devices returns the calibrated devices in ascending order
+func (t *Timing) Devices() []uint32 {
+	t.calibrations.RLock()
+	defer t.calibrations.RUnlock()
+	devices := make([]uint32, 0, len(t.calibrations.devices))
+	for device := range t.calibrations.devices {
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i] < devices[j] })
+	return devices
+}
+
+// Reset starts matching a new frame
+func (t *Timing) Reset() {
+	t.nominal = true
+	t.excluded = nil
+}
+
+// Accepts returns true if the frame matched so far matches the nominal
+// values, or the calibrated values for the device
+func (t *Timing) Accepts(device uint32) bool {
+	if t.device != nil || t.nominal {
+		return true
+	}
+	t.calibrations.RLock()
+	defer t.calibrations.RUnlock()
+	_, exists := t.calibrations.devices[device]
+	return exists && t.excluded[device] == false
+}
+
+// Window returns the minimum and maximum values which match a nominal
+// pulse or space value, or the calibrated value for a device timing
+func (t *Timing) Window(evt_type gopi.LIRCType, value uint32) (uint32, uint32) {
+	return window(evt_type, value, t.tolerance, t.device)
+}
+
+func (t *Timing) Matches(m *MarkSpace, evt gopi.LIRCEvent) bool {
+	if m.Type != evt.Type() {
+		return false
+	}
+	return t.match(m, func(min, max uint32) bool {
+		return evt.Value() >= min && evt.Value() <= max
+	})
+}
+
+func (t *Timing) GreaterThan(m *MarkSpace, evt gopi.LIRCEvent) bool {
+	if m.Type != evt.Type() {
+		return false
+	}
+	return t.match(m, func(min, _ uint32) bool {
+		return evt.Value() >= min
+	})
+}
+
+func (t *Timing) LessThan(m *MarkSpace, evt gopi.LIRCEvent) bool {
+	if m.Type != evt.Type() {
+		return false
+	}
+	return t.match(m, func(_, max uint32) bool {
+		return evt.Value() <= max
+	})
+}
+
+// match returns true if the nominal value, or the value for any calibrated
+// device which matched the frame so far, matches. Values which don't match
+// are then excluded from the rest of the frame
+func (t *Timing) match(m *MarkSpace, fn func(min, max uint32) bool) bool {
+	tolerance := t.toleranceFor(m)
+	if t.device != nil {
+		return fn(window(m.Type, m.Value, tolerance, t.device))
+	}
+
+	nominal := t.nominal && fn(window(m.Type, m.Value, tolerance, nil))
+	matched, excluded := nominal, make([]uint32, 0)
+	t.calibrations.RLock()
+	for device, calibration := range t.calibrations.devices {
+		if t.excluded[device] {
+			continue
+		} else if fn(window(m.Type, m.Value, tolerance, calibration)) {
+			matched = true
+		} else {
+			excluded = append(excluded, device)
+		}
+	}
+	t.calibrations.RUnlock()
+
+	// Only exclude values when something matched, as the codec may try
+	// another value instead
+	if matched == false {
+		return false
+	}
+	t.nominal = nominal
+	for _, device := range excluded {
+		if t.excluded == nil {
+			t.excluded = make(map[uint32]bool)
+		}
+		t.excluded[device] = true
+	}
+	return true
+}
+
+func (t *Timing) toleranceFor(m *MarkSpace) uint32 {
+	if m.Tolerance == t.def {
+		return t.tolerance
+	} else {
+		return m.Tolerance
+	}
+}
+
+// window returns the minimum and maximum values for a nominal value, which
+// are scaled by the calibration when not nil
+func window(evt_type gopi.LIRCType, value, tolerance uint32, calibration *Calibration) (uint32, uint32) {
+	ratio := 1.0
+	if calibration != nil && evt_type == gopi.LIRC_TYPE_PULSE {
+		ratio = calibration.Pulse
+	} else if calibration != nil {
+		ratio = calibration.Space
+	}
+	delta := float64(tolerance) / 100.0
+	min := math.Max(0, float64(value)*ratio*(1-delta))
+	max := float64(value) * ratio * (1 + delta)
+	return uint32(min), uint32(max)
+}
@@ -0,0 +1,213 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2018
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements encoding and decoding of Pronto hex codes, which
+	are published by most online IR code databases. Only learned codes
+	(format 0000) are supported. For example:
+
+	0000 006D 0022 0002 0157 00AC 0015 0016 ...
+
+	The second word is the carrier frequency, the third and fourth words
+	are the number of burst pairs in the intro and repeat sequences, and
+	the burst pairs follow in units of the carrier period
+*/
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ProntoCode is a learned code with a carrier frequency, and pulses and
+// spaces in microseconds for the intro sequence (which is sent once) and
+// the repeat sequence (which is sent while a key is held). Each
+// sequence ends with a space
+type ProntoCode struct {
+	Frequency uint32
+	Intro     []uint32
+	Repeat    []uint32
+}
+
+/////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	PRONTO_LEARNED    = 0x0000
+	PRONTO_CLOCK      = 0.241246 // Pronto clock period in microseconds
+	PRONTO_LEADOUT    = 100000   // Lead-out space when not known
+	DEFAULT_FREQUENCY = 38000    // Carrier frequency when not known
+)
+
+/////////////////////////////////////////////////////////////////////
+// PRONTO DECODE AND ENCODE
+
+// ParsePronto decodes Pronto hex into a learned code
+func ParsePronto(code string) (*ProntoCode, error) {
+	// Parse the hex words
+	fields := strings.Fields(code)
+	words := make([]uint16, len(fields))
+	for i, field := range fields {
+		if value, err := strconv.ParseUint(field, 16, 16); err != nil {
+			return nil, fmt.Errorf("Invalid Pronto word: %v", field)
+		} else {
+			words[i] = uint16(value)
+		}
+	}
+
+	// Check the preamble
+	if len(words) < 4 {
+		return nil, fmt.Errorf("Invalid Pronto code: missing preamble")
+	} else if words[0] != PRONTO_LEARNED {
+		return nil, fmt.Errorf("Unsupported Pronto format: %04X", words[0])
+	} else if words[1] == 0 {
+		return nil, fmt.Errorf("Invalid Pronto code: missing frequency")
+	}
+	intro, repeat := int(words[2])*2, int(words[3])*2
+	if len(words) != 4+intro+repeat {
+		return nil, fmt.Errorf("Invalid Pronto code: expected %v burst pairs", (intro+repeat)/2)
+	}
+
+	// Convert the burst pairs into microseconds
+	period := float64(words[1]) * PRONTO_CLOCK
+	pulses := make([]uint32, intro+repeat)
+	for i, word := range words[4:] {
+		pulses[i] = uint32(math.Round(float64(word) * period))
+	}
+
+	// Return the code
+	return &ProntoCode{
+		Frequency: uint32(math.Round(1e6 / period)),
+		Intro:     pulses[:intro],
+		Repeat:    pulses[intro:],
+	}, nil
+}
+
+// EncodePronto returns a learned code for a scancode. The intro sequence
+// is the first frame and the repeat sequence is the first repeat
+func EncodePronto(codec Codec, device, scancode uint32) (*ProntoCode, error) {
+	if codec == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Encode with no repeats, one repeat and two repeats, in order to determine
+	// the frame, the repeat and the spaces between them
+	frames := make([][]uint32, 3)
+	for repeats := range frames {
+		if pulses, err := codec.Encode(device, scancode, uint(repeats)); err != nil {
+			return nil, err
+		} else if len(pulses)%2 == 0 {
+			return nil, gopi.ErrBadParameter
+		} else {
+			frames[repeats] = pulses
+		}
+	}
+
+	// The intro is the first frame, and the repeat is the remainder of the
+	// second encoding
+	this := &ProntoCode{
+		Frequency: codec.Carrier(device).Frequency,
+	}
+	intro_leadout, repeat_leadout := uint32(PRONTO_LEADOUT), uint32(PRONTO_LEADOUT)
+	if hasPrefix(frames[1], frames[0]) && len(frames[1]) > len(frames[0]) {
+		intro_leadout = frames[1][len(frames[0])]
+		repeat_leadout = intro_leadout
+		this.Repeat = append(this.Repeat, frames[1][len(frames[0])+1:]...)
+		if hasPrefix(frames[2], frames[1]) && len(frames[2]) > len(frames[1]) {
+			repeat_leadout = frames[2][len(frames[1])]
+		}
+		this.Repeat = append(this.Repeat, repeat_leadout)
+	}
+	this.Intro = append(append(this.Intro, frames[0]...), intro_leadout)
+
+	// Return success
+	return this, nil
+}
+
+// Pulses returns the pulses and spaces to send the code, with the repeat
+// sequence sent the number of times indicated by repeats. Where there is no
+// intro sequence, the repeat sequence is always sent
+func (this *ProntoCode) Pulses(repeats uint) []uint32 {
+	pulses := make([]uint32, 0, len(this.Intro)+len(this.Repeat)*int(repeats+1))
+	pulses = append(pulses, this.Intro...)
+	if len(this.Intro) == 0 {
+		repeats++
+	}
+	if len(this.Repeat) > 0 {
+		for i := uint(0); i < repeats; i++ {
+			pulses = append(pulses, this.Repeat...)
+		}
+	}
+	// Remove the trailing space
+	if len(pulses) > 0 {
+		pulses = pulses[:len(pulses)-1]
+	}
+	return pulses
+}
+
+// SendPronto sets the carrier frequency and sends a learned code. Where
+// the device can't set the carrier frequency, the code is sent anyway
+func SendPronto(lirc gopi.LIRC, code *ProntoCode, repeats uint) error {
+	if lirc == nil || code == nil {
+		return gopi.ErrBadParameter
+	} else if pulses := code.Pulses(repeats); len(pulses) == 0 {
+		return gopi.ErrBadParameter
+	} else if err := lirc.SetSendCarrierHz(code.Frequency); err != nil && err != gopi.ErrNotImplemented {
+		return err
+	} else {
+		return lirc.PulseSend(pulses)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// String returns the code as Pronto hex
+func (this *ProntoCode) String() string {
+	frequency := this.Frequency
+	if frequency == 0 {
+		frequency = DEFAULT_FREQUENCY
+	}
+	code := uint16(math.Round(1e6 / (float64(frequency) * PRONTO_CLOCK)))
+	period := float64(code) * PRONTO_CLOCK
+	words := []string{
+		fmt.Sprintf("%04X", PRONTO_LEARNED),
+		fmt.Sprintf("%04X", code),
+		fmt.Sprintf("%04X", len(this.Intro)/2),
+		fmt.Sprintf("%04X", len(this.Repeat)/2),
+	}
+	for _, value := range append(append([]uint32{}, this.Intro...), this.Repeat...) {
+		count := math.Round(float64(value) / period)
+		words = append(words, fmt.Sprintf("%04X", uint16(math.Max(1, math.Min(count, math.MaxUint16)))))
+	}
+	return strings.Join(words, " ")
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func hasPrefix(pulses, prefix []uint32) bool {
+	if len(pulses) < len(prefix) {
+		return false
+	}
+	for i, value := range prefix {
+		if pulses[i] != value {
+			return false
+		}
+	}
+	return true
+}
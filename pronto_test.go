@@ -0,0 +1,154 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes_test
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/kaseikyo"
+	"github.com/djthorpe/remotes/codec/nec"
+	"github.com/djthorpe/remotes/codec/rc5"
+	"github.com/djthorpe/remotes/codec/sony"
+	"github.com/djthorpe/remotes/remotestest"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+// NEC_PRONTO is the published code for the LG TV power key, which is
+// NEC 0x20DF10EF, with a repeat sequence
+const NEC_PRONTO = "" +
+	"0000 006D 0022 0002 0157 00AC 0015 0016 0015 0016 0015 0041 0015 0016 0015 0016 " +
+	"0015 0016 0015 0016 0015 0016 0015 0041 0015 0041 0015 0016 0015 0041 0015 0041 " +
+	"0015 0041 0015 0041 0015 0041 0015 0016 0015 0016 0015 0016 0015 0041 0015 0016 " +
+	"0015 0016 0015 0016 0015 0016 0015 0041 0015 0041 0015 0041 0015 0016 0015 0041 " +
+	"0015 0041 0015 0041 0015 0041 0015 0689 0157 0056 0015 0E94"
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestParsePronto(t *testing.T) {
+	code, err := remotes.ParsePronto(NEC_PRONTO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The frequency is determined from the carrier period, and the burst
+	// pairs are converted to microseconds
+	if code.Frequency != 38029 {
+		t.Errorf("Unexpected frequency %v", code.Frequency)
+	}
+	if len(code.Intro) != 0x22*2 || len(code.Repeat) != 0x02*2 {
+		t.Errorf("Unexpected burst pairs intro=%v repeat=%v", len(code.Intro)/2, len(code.Repeat)/2)
+	} else if code.Intro[0] != 9019 || code.Intro[1] != 4523 || code.Intro[len(code.Intro)-1] != 43993 {
+		t.Errorf("Unexpected intro %v", code.Intro)
+	} else if code.Repeat[0] != 9019 || code.Repeat[1] != 2261 || code.Repeat[len(code.Repeat)-1] != 98136 {
+		t.Errorf("Unexpected repeat %v", code.Repeat)
+	}
+
+	// The code decodes as a key press followed by the repeats
+	codec := remotestest.OpenCodec(t, nec.Codec{Type: remotes.CODEC_NEC32})
+	defer codec.Close()
+	evts := remotestest.Decode(t, codec, code.Pulses(2))
+	remotestest.CheckEvents(t, remotes.CODEC_NEC32, evts, 0x20DF, 0x10, 2)
+}
+
+func TestProntoString(t *testing.T) {
+	// A parsed code is returned unchanged
+	if code, err := remotes.ParsePronto(NEC_PRONTO); err != nil {
+		t.Fatal(err)
+	} else if code.String() != NEC_PRONTO {
+		t.Errorf("Expected %v, got %v", NEC_PRONTO, code.String())
+	}
+
+	// The default frequency is used when not known, and values are limited
+	// to a single word
+	code := &remotes.ProntoCode{Intro: []uint32{0, 10000000}}
+	if code.String() != "0000 006D 0001 0000 0001 FFFF" {
+		t.Errorf("Unexpected code %v", code.String())
+	}
+}
+
+func TestParseProntoErrors(t *testing.T) {
+	for _, code := range []string{
+		"",
+		"0000 006D 0001",
+		"0000 006D 0001 0000 0157 00AC 0015",
+		"0000 006D 0001 0000 0157 00AC 0015 0016",
+		"0000 0000 0001 0000 0157 00AC",
+		"0100 006D 0001 0000 0157 00AC",
+		"0000 006D 0001 0000 0157 XXXX",
+		"0000 006D 0001 0000 0157 10000",
+	} {
+		if _, err := remotes.ParsePronto(code); err == nil {
+			t.Errorf("%v: Expected an error", code)
+		}
+	}
+}
+
+func TestEncodePronto(t *testing.T) {
+	tests := []struct {
+		config           gopi.Config
+		codec_type       remotes.CodecType
+		device, scancode uint32
+		frequency        uint32
+		intro, repeat    int
+		intro_leadout    uint32
+		repeat_leadout   uint32
+	}{
+		{nec.Codec{Type: remotes.CODEC_NEC32}, remotes.CODEC_NEC32, 0x04, 0x08, 38000, 34, 2, 35000, 96577},
+		{sony.Codec{Type: remotes.CODEC_SONY12}, remotes.CODEC_SONY12, 0x04, 0x08, 40000, 13, 13, 27550, 27550},
+		{kaseikyo.Codec{Type: remotes.CODEC_PANASONIC, Vendor: kaseikyo.VENDOR_PANASONIC}, remotes.CODEC_PANASONIC, 0x04, 0x08, 37000, 50, 50, 75000, 75000},
+		{rc5.Codec{Type: remotes.CODEC_RC5}, remotes.CODEC_RC5, 0x04, 0x08, 36000, 11, 11, 90678, 90678},
+	}
+	for _, test := range tests {
+		codec := remotestest.OpenCodec(t, test.config)
+		defer codec.Close()
+
+		// The intro is the frame followed by the space before the first
+		// repeat, and the repeat ends with the space before the next repeat
+		code, err := remotes.EncodePronto(codec, test.device, test.scancode)
+		if err != nil {
+			t.Error(test.codec_type, err)
+			continue
+		}
+		frame, err := codec.Encode(test.device, test.scancode, 0)
+		if err != nil {
+			t.Error(test.codec_type, err)
+			continue
+		}
+		if code.Frequency != test.frequency {
+			t.Errorf("%v: Unexpected frequency %v", test.codec_type, code.Frequency)
+		}
+		if len(code.Intro) != test.intro*2 || len(code.Repeat) != test.repeat*2 {
+			t.Errorf("%v: Unexpected burst pairs intro=%v repeat=%v", test.codec_type, len(code.Intro)/2, len(code.Repeat)/2)
+		} else if remotestest.Equals(code.Intro[:len(frame)], frame) == false {
+			t.Errorf("%v: Unexpected intro %v", test.codec_type, code.Intro)
+		} else if leadout := code.Intro[len(code.Intro)-1]; leadout != test.intro_leadout {
+			t.Errorf("%v: Unexpected intro lead-out %v", test.codec_type, leadout)
+		} else if leadout := code.Repeat[len(code.Repeat)-1]; leadout != test.repeat_leadout {
+			t.Errorf("%v: Unexpected repeat lead-out %v", test.codec_type, leadout)
+		}
+
+		// The Pronto hex decodes as a key press followed by the repeats
+		if parsed, err := remotes.ParsePronto(code.String()); err != nil {
+			t.Error(test.codec_type, err)
+		} else {
+			evts := remotestest.Decode(t, codec, parsed.Pulses(2))
+			remotestest.CheckEvents(t, test.codec_type, evts, test.device, test.scancode, 2)
+		}
+	}
+
+	if _, err := remotes.EncodePronto(nil, 0x04, 0x08); err != gopi.ErrBadParameter {
+		t.Errorf("Expected ErrBadParameter, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2018
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements the learned pulses which are stored in a
+	keymap entry for the raw codec
+*/
+
+import (
+	"strconv"
+	"strings"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Pulses are alternating pulse and space durations in microseconds,
+// starting and ending with a pulse
+type Pulses []uint32
+
+/////////////////////////////////////////////////////////////////////
+// MARSHAL AND UNMARSHAL
+
+// MarshalText returns the pulses as space-separated values
+func (p Pulses) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText sets the pulses from space-separated values
+func (p *Pulses) UnmarshalText(text []byte) error {
+	fields := strings.Fields(string(text))
+	pulses := make(Pulses, 0, len(fields))
+	for _, field := range fields {
+		if value, err := strconv.ParseUint(field, 10, 32); err != nil {
+			return err
+		} else {
+			pulses = append(pulses, uint32(value))
+		}
+	}
+	*p = pulses
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// SetRawPulses registers the learned pulses in the keymaps
+// with a raw codec, so they can be recognised and sent
+func SetRawPulses(codec RawCodec, keymaps KeyMaps) error {
+	for entry := range keymaps.LookupKeyMapEntry(codec.Type(), DEVICE_UNKNOWN, SCANCODE_UNKNOWN) {
+		if len(entry.Pulses) == 0 {
+			continue
+		} else if err := codec.SetPulses(entry.Device, entry.Scancode, entry.Pulses); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p Pulses) String() string {
+	values := make([]string, len(p))
+	for i, value := range p {
+		values[i] = strconv.FormatUint(uint64(value), 10)
+	}
+	return strings.Join(values, " ")
+}
@@ -0,0 +1,213 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+/*
+	This file implements recording LIRC events to a file, and reading them
+	back so they can be replayed. Each line of a recording is an event with
+	the time in seconds since the first event, the event type and the value:
+
+	  # LIRC recording
+	  0.000000 pulse 9024
+	  0.009024 space 4481
+	  0.013505 pulse 587
+	  ...
+	  0.120000 timeout 125000
+
+	Blank lines and lines starting with # are ignored
+*/
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RecordedEvent is a LIRC event with the time it was received, relative
+// to the first event in the recording
+type RecordedEvent struct {
+	gopi.LIRCEvent
+	Timestamp time.Duration
+}
+
+// Recording is a sequence of recorded events
+type Recording []*RecordedEvent
+
+// Recorder writes LIRC events to a recording
+type Recorder struct {
+	w     io.Writer
+	start time.Time
+	sync.Mutex
+}
+
+/////////////////////////////////////////////////////////////////////
+// RECORD
+
+// NewRecorder returns a recorder which writes to w, starting with a
+// comment line
+func NewRecorder(w io.Writer) (*Recorder, error) {
+	if w == nil {
+		return nil, gopi.ErrBadParameter
+	} else if _, err := fmt.Fprintln(w, "# LIRC recording"); err != nil {
+		return nil, err
+	} else {
+		return &Recorder{w: w}, nil
+	}
+}
+
+// Record writes an event with the time since the first recorded event
+func (this *Recorder) Record(evt gopi.LIRCEvent) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if evt == nil {
+		return gopi.ErrBadParameter
+	}
+	if this.start.IsZero() {
+		this.start = time.Now()
+	}
+	return this.write(time.Since(this.start), evt)
+}
+
+// WriteRecording writes recorded events with their own timestamps
+func WriteRecording(w io.Writer, recording Recording) error {
+	if recorder, err := NewRecorder(w); err != nil {
+		return err
+	} else {
+		for _, evt := range recording {
+			if err := recorder.write(evt.Timestamp, evt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////
+// READ
+
+// ReadRecording reads recorded events
+func ReadRecording(r io.Reader) (Recording, error) {
+	recording := make(Recording, 0, 100)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if evt, err := parseRecordedEvent(text); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		} else if len(recording) > 0 && evt.Timestamp < recording[len(recording)-1].Timestamp {
+			return nil, fmt.Errorf("Line %v: Timestamp is earlier than previous event", line)
+		} else {
+			recording = append(recording, evt)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+// Events returns the recorded events as LIRC events, which can be
+// decoded by a codec
+func (this Recording) Events() []gopi.LIRCEvent {
+	events := make([]gopi.LIRCEvent, len(this))
+	for i, evt := range this {
+		events[i] = evt.LIRCEvent
+	}
+	return events
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *RecordedEvent) String() string {
+	return fmt.Sprintf("remotes.RecordedEvent{ ts=%v type=%v value=%v }", this.Timestamp, this.Type(), this.Value())
+}
+
+/////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *Recorder) write(ts time.Duration, evt gopi.LIRCEvent) error {
+	if name := recordedEventName(evt.Type()); name == "" {
+		return gopi.ErrBadParameter
+	} else {
+		micros := ts.Nanoseconds() / 1000
+		_, err := fmt.Fprintf(this.w, "%d.%06d %s %d\n", micros/1000000, micros%1000000, name, evt.Value())
+		return err
+	}
+}
+
+func parseRecordedEvent(text string) (*RecordedEvent, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("Expected timestamp, type and value")
+	}
+
+	// Parse timestamp in seconds
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || seconds < 0 {
+		return nil, fmt.Errorf("Invalid timestamp: %v", fields[0])
+	}
+
+	// Parse type
+	var evt_type gopi.LIRCType
+	switch fields[1] {
+	case "pulse":
+		evt_type = gopi.LIRC_TYPE_PULSE
+	case "space":
+		evt_type = gopi.LIRC_TYPE_SPACE
+	case "frequency":
+		evt_type = gopi.LIRC_TYPE_FREQUENCY
+	case "timeout":
+		evt_type = gopi.LIRC_TYPE_TIMEOUT
+	default:
+		return nil, fmt.Errorf("Invalid event type: %v", fields[1])
+	}
+
+	// Parse value
+	value, err := strconv.ParseUint(fields[2], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid value: %v", fields[2])
+	}
+
+	// Return the event
+	return &RecordedEvent{
+		LIRCEvent: NewLIRCEvent(evt_type, uint32(value)),
+		Timestamp: time.Duration(seconds*1e6+0.5) * time.Microsecond,
+	}, nil
+}
+
+func recordedEventName(evt_type gopi.LIRCType) string {
+	switch evt_type {
+	case gopi.LIRC_TYPE_PULSE:
+		return "pulse"
+	case gopi.LIRC_TYPE_SPACE:
+		return "space"
+	case gopi.LIRC_TYPE_FREQUENCY:
+		return "frequency"
+	case gopi.LIRC_TYPE_TIMEOUT:
+		return "timeout"
+	default:
+		return ""
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"time"
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
@@ -34,17 +35,20 @@ type KeyMapEntry struct {
 	Device   uint32     `xml:"id,attr,omitempty"` // Overrides device if non-zero
 	Type     CodecType  `xml:"codec,omitempty"`   // Overrides codec if non-zero
 	Repeats  uint       `xml:"repeats,omitempty"` // Overrides repeats if non-zero
+	Pulses   Pulses     `xml:"pulses,omitempty"`  // Learned pulses for CODEC_RAW
 }
 
 // KeyMap maps one or more keys and scancodes
 type KeyMap struct {
-	XMLName    xml.Name       `xml:"remote"`
-	Type       CodecType      `xml:"codec"`
-	Device     uint32         `xml:"id,attr,omitempty"`
-	Name       string         `xml:"name"`
-	Repeats    uint           `xml:"repeats"`
-	MultiCodec bool           `xml:"multicodec,omitempty"` // Flag to indicate the device may record from multiple codecs
-	Map        []*KeyMapEntry `xml:"keymap"`
+	XMLName     xml.Name       `xml:"remote"`
+	Type        CodecType      `xml:"codec"`
+	Device      uint32         `xml:"id,attr,omitempty"`
+	Name        string         `xml:"name"`
+	Repeats     uint           `xml:"repeats"`
+	MultiCodec  bool           `xml:"multicodec,omitempty"`  // Flag to indicate the device may record from multiple codecs
+	Calibration *Calibration   `xml:"calibration,omitempty"` // Measured timings for the remote, or nil
+	Carrier     *Carrier       `xml:"carrier,omitempty"`     // Overrides the codec carrier if not nil
+	Map         []*KeyMapEntry `xml:"keymap"`
 }
 
 /////////////////////////////////////////////////////////////////////
@@ -71,6 +75,10 @@ const (
 	CODEC_SHARP
 	CODEC_APPLETV
 	CODEC_PANASONIC
+	CODEC_SAMSUNG32
+	CODEC_KASEIKYO
+	CODEC_RAW
+	CODEC_MAX
 )
 
 const (
@@ -90,27 +98,86 @@ type Codec interface {
 
 	// Send scancode
 	Send(device uint32, scancode uint32, repeats uint) error
+
+	// Return pulses and spaces for a scancode without sending them
+	Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error)
+
+	// Return remote events for a sequence of LIRC events, without
+	// changing the receive state
+	Decode(events []gopi.LIRCEvent) ([]RemoteEvent, error)
+
+	// Return the carrier for sending to a device
+	Carrier(device uint32) Carrier
+
+	// Override the carrier for sending to a device, or remove the
+	// override when nil
+	SetCarrier(device uint32, carrier *Carrier) error
+}
+
+// CodecSet is a module which creates several codecs, such as codecs
+// for protocols which are read from data files
+type CodecSet interface {
+	gopi.Driver
+
+	// Return the codecs
+	Codecs() []Codec
+}
+
+type RawCodec interface {
+	Codec
+
+	// Return learned pulses for a device and scancode, or nil
+	Pulses(device uint32, scancode uint32) []uint32
+
+	// Set learned pulses for a device and scancode
+	SetPulses(device uint32, scancode uint32, pulses []uint32) error
+
+	// Emit events for frames which have not been learned, so that they
+	// can be learned
+	SetLearning(flag bool)
+}
+
+type CalibratedCodec interface {
+	Codec
+
+	// Return the tolerance for pulses and spaces as a percentage
+	Tolerance() uint32
+
+	// Set the pulses and spaces recognised for a calibrated remote device
+	Calibrate(device uint32, calibration *Calibration) error
 }
 
 type KeyMaps interface {
 	gopi.Driver
 
+	// Methods are safe to call from many goroutines. A KeyMap is shared
+	// with the database, so it should only be modified through the methods
+
 	// Return properties
 	Modified() bool
 
 	// Create a new KeyMap with unknown codec and device
 	NewKeyMap(name string) *KeyMap
 
-	// LoadKeyMaps database functions and load individual keymap
+	// Create and register a new KeyMap with codec and device, and
+	// delete a KeyMap, which removes the file when saved
+	CreateKeyMap(name string, codec CodecType, device uint32) (*KeyMap, error)
+	DeleteKeyMap(keymap *KeyMap) error
+
+	// LoadKeyMaps database functions and load individual keymap. Files
+	// which can't be loaded are skipped, and returned by LoadErrors. Corrupt
+	// files are renamed so they are not loaded again
 	LoadKeyMaps(callback LoadSaveCallbackFunc) error
 	LoadKeyMap(path string) (*KeyMap, error)
+	LoadErrors() map[string]error
 
 	// Save modified KepMaps to files and save individual keymap
 	SaveModifiedKeyMaps(callback LoadSaveCallbackFunc) error
 	SaveKeyMap(keymap *KeyMap, path string) error
 
 	// Get a keymap from the database. Use DEVICE_UNKNOWN and
-	// CODEC_NONE to retrieve all keymaps
+	// CODEC_NONE to retrieve all keymaps. Keymaps returned are copies,
+	// which can be passed back to modify the keymap in the database
 	KeyMaps(codec CodecType, device uint32, name string) []*KeyMap
 
 	// Return keymapentry records matching a particular
@@ -123,12 +190,17 @@ type KeyMaps interface {
 	SetName(*KeyMap, string) error
 	SetMultiCodec(*KeyMap, bool) error
 	SetRepeats(*KeyMap, uint) error
+	SetCalibration(*KeyMap, *Calibration) error
 
-	// Set, get and lookup KeyMapEntry
+	// Set, get and lookup KeyMapEntry. Entries and keymaps returned
+	// are copies
 	SetKeyMapEntry(keymap *KeyMap, codec CodecType, device uint32, keycode RemoteCode, scancode uint32) error
 	GetKeyMapEntry(keymap *KeyMap, codec CodecType, device uint32, keycode RemoteCode, scancode uint32) []*KeyMapEntry
 	LookupKeyMapEntry(codec CodecType, device uint32, scancode uint32) map[*KeyMapEntry]*KeyMap
 	DeleteKeyMapEntry(keymap *KeyMap, entry *KeyMapEntry) error
+
+	// Set learned pulses for a keycode in a keymap
+	SetKeyMapPulses(keymap *KeyMap, keycode RemoteCode, pulses []uint32) error
 }
 
 type RemoteEvent interface {
@@ -138,14 +210,58 @@ type RemoteEvent interface {
 	Codec() CodecType
 }
 
+type Lifecycle interface {
+	gopi.Driver
+	gopi.Publisher
+
+	// Track key presses from a codec
+	Add(codec Codec) error
+
+	// Stop tracking key presses from a codec
+	Remove(codec Codec) error
+}
+
+type LifecycleEvent interface {
+	RemoteEvent
+
+	// Return the time since the key was pressed
+	Held() time.Duration
+
+	// Return the number of repeats since the key was pressed
+	Repeats() uint
+}
+
+type Mapper interface {
+	gopi.Driver
+	gopi.Publisher
+
+	// Resolve remote events from a codec or lifecycle through the keymaps
+	Add(publisher gopi.Publisher) error
+
+	// Stop resolving remote events from a codec or lifecycle
+	Remove(publisher gopi.Publisher) error
+}
+
+type KeyMapEvent interface {
+	RemoteEvent
+
+	// Return the name of the key, or empty string when the key is unmapped
+	KeyName() string
+
+	// Return the name of the keymap, or empty string when the key is unmapped
+	KeyMapName() string
+}
+
 /////////////////////////////////////////////////////////////////////
 // ERROR CODES
 
 var (
 	ErrInvalidKey      = errors.New("Invalid Key")
 	ErrDuplicateKeyMap = errors.New("Duplicate KeyMap")
+	ErrDuplicateName   = errors.New("Duplicate KeyMap Name")
 	ErrNotFound        = errors.New("Not Found")
 	ErrAmbiguous       = errors.New("Ambiguous Parameter")
+	ErrDuplicateCodec  = errors.New("Duplicate Codec")
 )
 
 /////////////////////////////////////////////////////////////////////
@@ -162,6 +278,9 @@ func (e *KeyMapEntry) String() string {
 	if e.Repeats != 0 {
 		params += fmt.Sprintf(" repeats=%v", e.Repeats)
 	}
+	if len(e.Pulses) != 0 {
+		params += fmt.Sprintf(" pulses=%v", len(e.Pulses))
+	}
 	return "<remotes.KeyMapEntry>{ " + params + " }"
 }
 
@@ -207,7 +326,16 @@ func (c CodecType) String() string {
 		return "CODEC_SHARP"
 	case CODEC_PANASONIC:
 		return "CODEC_PANASONIC"
+	case CODEC_SAMSUNG32:
+		return "CODEC_SAMSUNG32"
+	case CODEC_KASEIKYO:
+		return "CODEC_KASEIKYO"
+	case CODEC_RAW:
+		return "CODEC_RAW"
 	default:
+		if name, exists := namedCodecType(c); exists {
+			return name
+		}
 		return "[?? Invalid CodecType value]"
 	}
 }
@@ -0,0 +1,109 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotestest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+	"github.com/djthorpe/remotes"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Codec emits remote events without receiving transmissions, for
+// testing modules which subscribe to codecs
+type Codec struct {
+	codec_type  remotes.CodecType
+	subscribers int
+
+	sync.Mutex
+	event.Publisher
+}
+
+/////////////////////////////////////////////////////////////////////
+// NEW
+
+// NewCodec returns a codec which emits events for a codec type
+func NewCodec(codec_type remotes.CodecType) *Codec {
+	return &Codec{codec_type: codec_type}
+}
+
+/////////////////////////////////////////////////////////////////////
+// CODEC INTERFACE
+
+func (this *Codec) Close() error {
+	this.Publisher.Close()
+	return nil
+}
+
+func (this *Codec) Type() remotes.CodecType {
+	return this.codec_type
+}
+
+func (this *Codec) Send(device uint32, scancode uint32, repeats uint) error {
+	return gopi.ErrNotImplemented
+}
+
+func (this *Codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
+	return nil, gopi.ErrNotImplemented
+}
+
+func (this *Codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	return nil, gopi.ErrNotImplemented
+}
+
+func (this *Codec) Carrier(device uint32) remotes.Carrier {
+	return remotes.Carrier{}
+}
+
+func (this *Codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return gopi.ErrNotImplemented
+}
+
+/////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+func (this *Codec) Subscribe() <-chan gopi.Event {
+	this.Lock()
+	defer this.Unlock()
+	this.subscribers++
+	return this.Publisher.Subscribe()
+}
+
+func (this *Codec) Unsubscribe(subscriber <-chan gopi.Event) {
+	this.Lock()
+	defer this.Unlock()
+	this.subscribers--
+	this.Publisher.Unsubscribe(subscriber)
+}
+
+// Subscribers returns the number of subscribers to the codec
+func (this *Codec) Subscribers() int {
+	this.Lock()
+	defer this.Unlock()
+	return this.subscribers
+}
+
+// Receive emits a key press, or a repeat, for a device and scancode
+func (this *Codec) Receive(ts time.Duration, device, scancode uint32, repeat bool) {
+	this.Publisher.Emit(remotes.NewRemoteEvent(this, ts, scancode, device, repeat))
+}
+
+/////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *Codec) String() string {
+	return fmt.Sprintf("<remotestest.Codec>{ type=%v }", this.codec_type)
+}
@@ -0,0 +1,147 @@
+/*
+   Go Language Raspberry Pi Interface
+   (c) Copyright David Thorpe 2016-2019
+   All Rights Reserved
+   Documentation http://djthorpe.github.io/gopi/
+   For Licensing and Usage information, please see LICENSE.md
+*/
+
+// Package remotestest provides helpers for testing codecs, by opening
+// codecs, decoding pulses and checking the events emitted
+package remotestest
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/remotes"
+)
+
+/////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PulseDistance encodes each bit as a pulse followed by a space, which
+// is longer for a one than for a zero
+type PulseDistance struct {
+	Pulse, Zero, One uint32
+	MSB              bool // Most significant bit first
+}
+
+/////////////////////////////////////////////////////////////////////
+// CODECS
+
+// OpenCodec opens a codec, which only logs fatal errors as Encode logs
+// errors for values which are rejected
+func OpenCodec(t *testing.T, config gopi.Config) remotes.Codec {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{Level: logger.LOG_FATAL}, nil); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(config, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(remotes.Codec)
+	}
+	return nil
+}
+
+// Decode returns the events decoded from pulses and spaces, which
+// end with a timeout
+func Decode(t *testing.T, codec remotes.Codec, pulses []uint32) []remotes.RemoteEvent {
+	t.Helper()
+	if evts, err := codec.Decode(remotes.PulseEvents(pulses, 0)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return evts
+	}
+}
+
+// CheckEvents checks for a key press followed by the repeats
+func CheckEvents(t *testing.T, codec_type remotes.CodecType, evts []remotes.RemoteEvent, device, scancode uint32, repeats uint) {
+	t.Helper()
+	if uint(len(evts)) != repeats+1 {
+		t.Errorf("%v device=0x%X scancode=0x%X: Expected %v events, got %v", codec_type, device, scancode, repeats+1, evts)
+		return
+	}
+	for i, evt := range evts {
+		if evt.Codec() != codec_type || evt.Device() != device || evt.ScanCode() != scancode {
+			t.Errorf("%v device=0x%X scancode=0x%X: Unexpected event %v", codec_type, device, scancode, evt)
+		} else if i == 0 && evt.EventType() != gopi.INPUT_EVENT_KEYPRESS {
+			t.Errorf("Expected key press, got %v", evt)
+		} else if i > 0 && evt.EventType() != gopi.INPUT_EVENT_KEYREPEAT {
+			t.Errorf("Expected repeat, got %v", evt)
+		}
+	}
+}
+
+/////////////////////////////////////////////////////////////////////
+// PULSES
+
+// Pulses returns a pulse and space for each bit of a value
+func (this PulseDistance) Pulses(value uint64, length uint) []uint32 {
+	pulses := make([]uint32, 0, length*2)
+	for i := uint(0); i < length; i++ {
+		bit := i
+		if this.MSB {
+			bit = length - i - 1
+		}
+		if value&(1<<bit) == 0 {
+			pulses = append(pulses, this.Pulse, this.Zero)
+		} else {
+			pulses = append(pulses, this.Pulse, this.One)
+		}
+	}
+	return pulses
+}
+
+// Frames returns frames separated by spaces, so that each frame starts
+// the period in microseconds after the previous frame
+func Frames(period uint32, frames ...[]uint32) []uint32 {
+	pulses := make([]uint32, 0)
+	for i, frame := range frames {
+		if i > 0 {
+			pulses = append(pulses, period-Length(frames[i-1]))
+		}
+		pulses = append(pulses, frame...)
+	}
+	return pulses
+}
+
+// Join returns the pulses of two frames separated by a space
+func Join(a []uint32, space uint32, b []uint32) []uint32 {
+	return append(append(append([]uint32{}, a...), space), b...)
+}
+
+// Length returns the duration of pulses and spaces in microseconds
+func Length(pulses []uint32) uint32 {
+	length := uint32(0)
+	for _, value := range pulses {
+		length += value
+	}
+	return length
+}
+
+// Scale returns the pulses for a remote which is slower or faster than
+// the nominal values
+func Scale(pulses []uint32, ratio float64) []uint32 {
+	scaled := make([]uint32, len(pulses))
+	for i, value := range pulses {
+		scaled[i] = uint32(float64(value) * ratio)
+	}
+	return scaled
+}
+
+// Equals returns true if the pulses and spaces are the same
+func Equals(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
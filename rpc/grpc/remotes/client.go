@@ -55,6 +55,40 @@ type Event struct {
 	KeyMapInfo
 }
 
+type LearnEvent struct {
+	State LearnState
+	Key
+	KeyMapInfo
+	Index, Count uint
+}
+
+type (
+	LearnAction uint
+	LearnState  uint
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	LEARN_ACTION_NONE   LearnAction = LearnAction(pb.LearnAction_LEARN_ACTION_NONE)
+	LEARN_ACTION_SKIP   LearnAction = LearnAction(pb.LearnAction_LEARN_ACTION_SKIP)   // Skip the key being learnt
+	LEARN_ACTION_RETRY  LearnAction = LearnAction(pb.LearnAction_LEARN_ACTION_RETRY)  // Learn the last key again
+	LEARN_ACTION_COMMIT LearnAction = LearnAction(pb.LearnAction_LEARN_ACTION_COMMIT) // Save the learnt keys
+	LEARN_ACTION_CANCEL LearnAction = LearnAction(pb.LearnAction_LEARN_ACTION_CANCEL) // End without saving
+)
+
+const (
+	LEARN_STATE_NONE      LearnState = LearnState(pb.LearnState_LEARN_STATE_NONE)
+	LEARN_STATE_PROMPT    LearnState = LearnState(pb.LearnState_LEARN_STATE_PROMPT)
+	LEARN_STATE_VERIFY    LearnState = LearnState(pb.LearnState_LEARN_STATE_VERIFY)
+	LEARN_STATE_MISMATCH  LearnState = LearnState(pb.LearnState_LEARN_STATE_MISMATCH)
+	LEARN_STATE_LEARNT    LearnState = LearnState(pb.LearnState_LEARN_STATE_LEARNT)
+	LEARN_STATE_SKIPPED   LearnState = LearnState(pb.LearnState_LEARN_STATE_SKIPPED)
+	LEARN_STATE_DONE      LearnState = LearnState(pb.LearnState_LEARN_STATE_DONE)
+	LEARN_STATE_COMMITTED LearnState = LearnState(pb.LearnState_LEARN_STATE_COMMITTED)
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // NEW
 
@@ -307,6 +341,186 @@ func (this *Client) SendScancode(codec remotes.CodecType, device, scancode uint3
 	}
 }
 
+// Send a Pronto hex code
+func (this *Client) SendPronto(code string, repeats uint) error {
+	// One request per connection
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.RemotesClient.SendPronto(this.NewContext(), &pb.SendProntoRequest{
+		Code:    code,
+		Repeats: uint32(repeats),
+	}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+// Create a new keymap for a codec and device
+func (this *Client) CreateKeyMap(name string, codec remotes.CodecType, device uint32, repeats uint) (*KeyMapInfo, error) {
+	// One request per connection
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if reply, err := this.RemotesClient.CreateKeymap(this.NewContext(), &pb.CreateKeymapRequest{
+		Name:    name,
+		Codec:   pb.CodecType(codec),
+		Device:  device,
+		Repeats: uint32(repeats),
+	}); err != nil {
+		return nil, err
+	} else if len(reply.Keymap) != 1 {
+		return nil, gopi.ErrUnexpectedResponse
+	} else {
+		keymap := reply.Keymap[0]
+		return &KeyMapInfo{
+			remotes.KeyMap{
+				Name:    keymap.Name,
+				Type:    remotes.CodecType(keymap.Codec),
+				Device:  keymap.Device,
+				Repeats: uint(keymap.Repeats),
+			}, uint(keymap.Keys),
+		}, nil
+	}
+}
+
+// Delete a keymap
+func (this *Client) DeleteKeyMap(keymap string) error {
+	// One request per connection
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.RemotesClient.DeleteKeymap(this.NewContext(), &pb.DeleteKeymapRequest{
+		Keymap: keymap,
+	}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+// Set the name of a keymap
+func (this *Client) SetKeyMapName(keymap, name string) error {
+	// One request per connection
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.RemotesClient.SetKeymapName(this.NewContext(), &pb.SetKeymapNameRequest{
+		Keymap: keymap,
+		Name:   name,
+	}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+// Set a key in a keymap. The keymap codec and device are used
+// when codec is CODEC_NONE
+func (this *Client) SetKeyMapKey(keymap string, keycode remotes.RemoteCode, codec remotes.CodecType, device, scancode uint32) error {
+	// One request per connection
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	if _, err := this.RemotesClient.SetKeymapKey(this.NewContext(), &pb.SetKeymapKeyRequest{
+		Keymap:   keymap,
+		Keycode:  pb.RemoteCode(keycode),
+		Codec:    pb.CodecType(codec),
+		Device:   device,
+		Scancode: scancode,
+	}); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+// Learn keys for a keymap, which is created if it doesn't exist. Each key
+// is prompted for in turn, and pressed twice when verify is set. Actions
+// are sent to the server and events are received until the keys are
+// committed or cancelled, or the action channel is closed
+func (this *Client) Learn(ctx context.Context, keymap string, keycodes []remotes.RemoteCode, verify bool, action <-chan LearnAction, evt chan<- *LearnEvent) error {
+	// One request per connection
+	this.conn.Lock()
+	defer this.conn.Unlock()
+
+	// Start learning
+	stream, err := this.RemotesClient.Learn(ctx)
+	if err != nil {
+		return err
+	}
+	request := &pb.LearnRequest{
+		Action:  pb.LearnAction_LEARN_ACTION_START,
+		Keymap:  keymap,
+		Keycode: make([]pb.RemoteCode, len(keycodes)),
+		Verify:  verify,
+	}
+	for i, keycode := range keycodes {
+		request.Keycode[i] = pb.RemoteCode(keycode)
+	}
+	if err := stream.Send(request); err != nil {
+		return gopiError(err)
+	}
+
+	// Send actions in the background until the stream ends
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		for {
+			select {
+			case action_, ok := <-action:
+				if ok == false {
+					stream.CloseSend()
+					return
+				} else if err := stream.Send(&pb.LearnRequest{Action: pb.LearnAction(action_)}); err != nil {
+					return
+				}
+			case <-finished:
+				return
+			}
+		}
+	}()
+
+	// Receive events until the server ends the stream
+	for {
+		if msg, err := stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			return gopiError(err)
+		} else {
+			event := &LearnEvent{
+				State: LearnState(msg.State),
+				Index: uint(msg.Index),
+				Count: uint(msg.Count),
+			}
+			if msg.Key != nil {
+				event.Key = Key{
+					remotes.KeyMapEntry{
+						Name:     msg.Key.Name,
+						Type:     remotes.CodecType(msg.Key.Codec),
+						Device:   msg.Key.Device,
+						Scancode: msg.Key.Scancode,
+						Keycode:  remotes.RemoteCode(msg.Key.Keycode),
+					},
+				}
+			}
+			if msg.Keymap != nil {
+				event.KeyMapInfo = KeyMapInfo{
+					remotes.KeyMap{
+						Name:    msg.Keymap.Name,
+						Type:    remotes.CodecType(msg.Keymap.Codec),
+						Device:  msg.Keymap.Device,
+						Repeats: uint(msg.Keymap.Repeats),
+					}, uint(msg.Keymap.Keys),
+				}
+			}
+			evt <- event
+		}
+	}
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -318,6 +532,33 @@ func (this *Event) String() string {
 	return fmt.Sprintf("<grpc.client.remotes.Event>{ input_event=%v key=%v keymap=%v }", this.InputEvent, this.Key, this.KeyMapInfo)
 }
 
+func (this *LearnEvent) String() string {
+	return fmt.Sprintf("<grpc.client.remotes.LearnEvent>{ state=%v key=%v keymap=%v index=%v count=%v }", this.State, this.Key, this.KeyMapInfo, this.Index, this.Count)
+}
+
+func (s LearnState) String() string {
+	switch s {
+	case LEARN_STATE_NONE:
+		return "LEARN_STATE_NONE"
+	case LEARN_STATE_PROMPT:
+		return "LEARN_STATE_PROMPT"
+	case LEARN_STATE_VERIFY:
+		return "LEARN_STATE_VERIFY"
+	case LEARN_STATE_MISMATCH:
+		return "LEARN_STATE_MISMATCH"
+	case LEARN_STATE_LEARNT:
+		return "LEARN_STATE_LEARNT"
+	case LEARN_STATE_SKIPPED:
+		return "LEARN_STATE_SKIPPED"
+	case LEARN_STATE_DONE:
+		return "LEARN_STATE_DONE"
+	case LEARN_STATE_COMMITTED:
+		return "LEARN_STATE_COMMITTED"
+	default:
+		return "[?? Invalid LearnState value]"
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
@@ -10,8 +10,6 @@
 package remotes
 
 import (
-	"strings"
-
 	// Frameworks
 	gopi "github.com/djthorpe/gopi"
 	remotes "github.com/djthorpe/remotes"
@@ -25,22 +23,18 @@ func init() {
 	gopi.RegisterModule(gopi.Module{
 		Name:     "rpc/service/remotes:grpc",
 		Type:     gopi.MODULE_TYPE_SERVICE,
-		Requires: []string{"rpc/server", "keymap"},
+		Requires: []string{"rpc/server", "keymap", "lirc"},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
 			return gopi.Open(Service{
 				Server:  app.ModuleInstance("rpc/server").(gopi.RPCServer),
 				KeyMaps: app.ModuleInstance("keymap").(remotes.KeyMaps),
+				LIRC:    app.ModuleInstance("lirc").(gopi.LIRC),
 			}, app.Logger)
 		},
 		Run: func(app *gopi.AppInstance, driver gopi.Driver) error {
-			// Register codecs with driver. Codecs have OTHER as module type
-			// and name starting with "remotes/"
-			for _, module := range gopi.ModulesByType(gopi.MODULE_TYPE_OTHER) {
-				if strings.HasPrefix(module.Name, "remotes/") {
-					if codec, ok := app.ModuleInstance(module.Name).(remotes.Codec); ok && codec != nil {
-						driver.(*service).registerCodec(codec)
-					}
-				}
+			// Register codecs with driver
+			for _, codec := range remotes.ModuleCodecs(app) {
+				driver.(*service).registerCodec(codec)
 			}
 			// Load KeyMaps
 			if err := driver.(*service).loadKeyMaps(); err != nil {
@@ -0,0 +1,242 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2019
+	All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package remotes
+
+import (
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+
+	// Protocol Buffer definitions
+	pb "github.com/djthorpe/remotes/rpc/protobuf/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// learn is a session which learns keys for a keymap. Keys are learnt in
+// order and are only set in the keymap when committed
+type learn struct {
+	name    string                 // Keymap name
+	keymap  *remotes.KeyMap        // Existing keymap, or nil
+	verify  bool                   // Press each key twice
+	keys    []*remotes.KeyMapEntry // Keys to learn
+	learnt  []*remotes.KeyMapEntry // Keys learnt, or nil when skipped
+	pending *remotes.KeyMapEntry   // Key pressed once, when verifying
+	last    time.Time              // Time of the last remote event
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Time without remote events before a key press is recognised, so
+	// that repeats of a held key are ignored
+	LEARN_GAP = 500 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// NEW
+
+func (this *service) newLearn(in *pb.LearnRequest) (*learn, error) {
+	session := new(learn)
+	session.name = in.Keymap
+	session.verify = in.Verify
+
+	// Obtain an existing keymap, or else a keymap is created on commit
+	if in.Keymap == "" || len(in.Keycode) == 0 {
+		return nil, gopi.ErrBadParameter
+	} else if keymaps := this.keymaps.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, in.Keymap); len(keymaps) > 1 {
+		return nil, remotes.ErrAmbiguous
+	} else if len(keymaps) == 1 {
+		session.keymap = keymaps[0]
+	}
+
+	// Obtain the name for each keycode
+	names := make(map[remotes.RemoteCode]string)
+	for _, key := range this.keymaps.LookupKeyCode() {
+		names[key.Keycode] = key.Name
+	}
+	session.keys = make([]*remotes.KeyMapEntry, len(in.Keycode))
+	for i, keycode := range in.Keycode {
+		if name, exists := names[remotes.RemoteCode(keycode)]; exists == false {
+			return nil, remotes.ErrInvalidKey
+		} else {
+			session.keys[i] = &remotes.KeyMapEntry{
+				Keycode:  remotes.RemoteCode(keycode),
+				Name:     name,
+				Type:     remotes.CODEC_NONE,
+				Device:   remotes.DEVICE_UNKNOWN,
+				Scancode: remotes.SCANCODE_UNKNOWN,
+			}
+		}
+	}
+
+	// Return the session
+	return session, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ACTIONS
+
+// prompt returns the reply for the key being learnt, or done when
+// there are no more keys
+func (this *learn) prompt() *pb.LearnReply {
+	if len(this.learnt) >= len(this.keys) {
+		return this.reply(pb.LearnState_LEARN_STATE_DONE, nil)
+	} else if this.pending != nil {
+		return this.reply(pb.LearnState_LEARN_STATE_VERIFY, this.pending)
+	} else {
+		return this.reply(pb.LearnState_LEARN_STATE_PROMPT, this.keys[len(this.learnt)])
+	}
+}
+
+// press handles a remote event and returns the replies
+func (this *learn) press(evt remotes.RemoteEvent, now time.Time) []*pb.LearnReply {
+	// Ignore repeats, raw events and events after the last key
+	since := now.Sub(this.last)
+	this.last = now
+	if since < LEARN_GAP || evt.Codec() == remotes.CODEC_RAW {
+		return nil
+	} else if len(this.learnt) >= len(this.keys) {
+		return nil
+	}
+
+	// Key which was pressed
+	key := this.keys[len(this.learnt)]
+	pressed := &remotes.KeyMapEntry{
+		Keycode:  key.Keycode,
+		Name:     key.Name,
+		Type:     evt.Codec(),
+		Device:   evt.Device(),
+		Scancode: evt.Scancode(),
+	}
+
+	// Check the key against the keymap, and against the first press when
+	// verifying
+	if this.compatible(pressed) == false {
+		this.pending = nil
+		return []*pb.LearnReply{this.reply(pb.LearnState_LEARN_STATE_MISMATCH, pressed), this.prompt()}
+	} else if this.verify && this.pending == nil {
+		this.pending = pressed
+		return []*pb.LearnReply{this.prompt()}
+	} else if this.verify && equalKeys(this.pending, pressed) == false {
+		this.pending = nil
+		return []*pb.LearnReply{this.reply(pb.LearnState_LEARN_STATE_MISMATCH, pressed), this.prompt()}
+	}
+
+	// Key is learnt
+	learnt := this.reply(pb.LearnState_LEARN_STATE_LEARNT, pressed)
+	this.pending = nil
+	this.learnt = append(this.learnt, pressed)
+	return []*pb.LearnReply{learnt, this.prompt()}
+}
+
+// skip moves to the next key without learning it
+func (this *learn) skip() []*pb.LearnReply {
+	if len(this.learnt) >= len(this.keys) {
+		return []*pb.LearnReply{this.prompt()}
+	}
+	skipped := this.reply(pb.LearnState_LEARN_STATE_SKIPPED, this.keys[len(this.learnt)])
+	this.pending = nil
+	this.learnt = append(this.learnt, nil)
+	return []*pb.LearnReply{skipped, this.prompt()}
+}
+
+// retry discards the first press of the key being verified, or else
+// the last key learnt or skipped, and prompts for the key again
+func (this *learn) retry() []*pb.LearnReply {
+	if this.pending != nil {
+		this.pending = nil
+	} else if len(this.learnt) > 0 {
+		this.learnt = this.learnt[:len(this.learnt)-1]
+	}
+	return []*pb.LearnReply{this.prompt()}
+}
+
+// commitLearn sets the keys learnt in the keymap, creating the keymap if
+// necessary, and saves the keymaps
+func (this *service) commitLearn(session *learn) (*pb.LearnReply, error) {
+	keymap := session.keymap
+	for _, key := range session.learnt {
+		if key == nil {
+			continue
+		} else if keymap == nil {
+			if keymap_, err := this.keymaps.CreateKeyMap(session.name, key.Type, key.Device); err != nil {
+				return nil, err
+			} else {
+				keymap = keymap_
+			}
+		}
+		if err := this.keymaps.SetKeyMapEntry(keymap, key.Type, key.Device, key.Keycode, key.Scancode); err != nil {
+			return nil, err
+		}
+	}
+
+	// Save the keymaps
+	if err := this.saveKeyMaps(); err != nil {
+		return nil, err
+	}
+
+	// Return the keymap with the keys learnt, which is nil when no keys
+	// were learnt
+	reply := session.reply(pb.LearnState_LEARN_STATE_COMMITTED, nil)
+	if keymap != nil {
+		if keymaps := this.keymaps.KeyMaps(keymap.Type, keymap.Device, ""); len(keymaps) == 1 {
+			keymap = keymaps[0]
+		}
+	}
+	reply.Keymap = toProtobufKeyMapInfo(keymap)
+	return reply, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// compatible returns true if a key pressed has the same codec and device
+// as the keymap, or as the keys already learnt when there is no keymap
+func (this *learn) compatible(pressed *remotes.KeyMapEntry) bool {
+	if this.keymap != nil {
+		if this.keymap.MultiCodec || this.keymap.Type == remotes.CODEC_NONE {
+			return true
+		}
+		return this.keymap.Type == pressed.Type && this.keymap.Device == pressed.Device
+	}
+	for _, key := range this.learnt {
+		if key != nil {
+			return key.Type == pressed.Type && key.Device == pressed.Device
+		}
+	}
+	return true
+}
+
+func (this *learn) reply(state pb.LearnState, key *remotes.KeyMapEntry) *pb.LearnReply {
+	reply := &pb.LearnReply{
+		State: state,
+		Index: uint32(len(this.learnt)),
+		Count: uint32(len(this.keys)),
+	}
+	if key != nil {
+		reply.Key = &pb.Key{
+			Name:     key.Name,
+			Keycode:  pb.RemoteCode(key.Keycode),
+			Codec:    pb.CodecType(key.Type),
+			Device:   key.Device,
+			Scancode: key.Scancode,
+		}
+	}
+	return reply
+}
+
+func equalKeys(a, b *remotes.KeyMapEntry) bool {
+	return a.Type == b.Type && a.Device == b.Device && a.Scancode == b.Scancode
+}
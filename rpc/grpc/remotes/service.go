@@ -12,7 +12,9 @@ package remotes
 import (
 	"context"
 	"fmt"
+	"io"
 	"strings"
+	"time"
 
 	// Frameworks
 	gopi "github.com/djthorpe/gopi"
@@ -31,6 +33,7 @@ import (
 type Service struct {
 	Server  gopi.RPCServer
 	KeyMaps remotes.KeyMaps
+	LIRC    gopi.LIRC
 }
 
 type service struct {
@@ -39,6 +42,7 @@ type service struct {
 	merger  evt.EventMerger
 	codecs  map[remotes.CodecType]remotes.Codec
 	keymaps remotes.KeyMaps
+	lirc    gopi.LIRC
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -54,6 +58,7 @@ func (config Service) Open(log gopi.Logger) (gopi.Driver, error) {
 	this.codecs = make(map[remotes.CodecType]remotes.Codec, 10)
 	this.merger = evt.NewEventMerger()
 	this.keymaps = config.KeyMaps
+	this.lirc = config.LIRC
 
 	// Register service with GRPC server
 	pb.RegisterRemotesServer(config.Server.(grpc.GRPCServer).GRPCServer(), this)
@@ -94,9 +99,45 @@ func (this *service) registerCodec(codec remotes.Codec) {
 }
 
 func (this *service) loadKeyMaps() error {
-	return this.keymaps.LoadKeyMaps(func(filename string, keymap *remotes.KeyMap) {
+	if err := this.keymaps.LoadKeyMaps(func(filename string, keymap *remotes.KeyMap) {
 		this.log.Info("Loading: %v (%v)", filename, keymap.Name)
-	})
+	}); err != nil {
+		return err
+	}
+
+	// Register learned pulses with the raw codec
+	if raw, ok := this.codecs[remotes.CODEC_RAW].(remotes.RawCodec); ok {
+		if err := remotes.SetRawPulses(raw, this.keymaps); err != nil {
+			return err
+		}
+	}
+
+	// Register calibrated timings and carrier overrides with the codecs
+	for _, codec := range this.codecs {
+		if calibrated, ok := codec.(remotes.CalibratedCodec); ok {
+			if err := remotes.SetCalibrations(calibrated, this.keymaps); err != nil {
+				return err
+			}
+		}
+		if err := remotes.SetCarriers(codec, this.keymaps); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+func (this *service) keymapWithName(name string) (*remotes.KeyMap, error) {
+	if name == "" {
+		return nil, gopi.ErrBadParameter
+	} else if keymaps := this.keymaps.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, name); len(keymaps) == 0 {
+		return nil, remotes.ErrNotFound
+	} else if len(keymaps) > 1 {
+		return nil, remotes.ErrAmbiguous
+	} else {
+		return keymaps[0], nil
+	}
 }
 
 func (this *service) saveKeyMaps() error {
@@ -175,6 +216,99 @@ FOR_LOOP:
 	return nil
 }
 
+func (this *service) Learn(stream pb.Remotes_LearnServer) error {
+	// The first request starts learning
+	in, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else if in.Action != pb.LearnAction_LEARN_ACTION_START {
+		this.log.Warn("Learn: Bad request: Expected LEARN_ACTION_START")
+		return gopi.ErrBadParameter
+	}
+	session, err := this.newLearn(in)
+	if err != nil {
+		this.log.Warn("Learn: Bad request: %v", err)
+		return err
+	}
+
+	// Receive requests in the background until the stream is closed
+	requests := make(chan *pb.LearnRequest)
+	errs := make(chan error, 1)
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		for {
+			if in, err := stream.Recv(); err != nil {
+				errs <- err
+				return
+			} else {
+				select {
+				case requests <- in:
+				case <-finished:
+					return
+				}
+			}
+		}
+	}()
+
+	// Subscribe to the merger channel and the channel used for
+	// breaking the loop
+	input_events := this.merger.Subscribe()
+	cancel_requests := this.done.Subscribe()
+	defer this.merger.Unsubscribe(input_events)
+	defer this.done.Unsubscribe(cancel_requests)
+
+	// Prompt for the first key
+	if err := stream.Send(session.prompt()); err != nil {
+		return err
+	}
+
+	// Learn until committed or cancelled
+	for {
+		var replies []*pb.LearnReply
+		select {
+		case evt := <-input_events:
+			if remote_evt, ok := evt.(remotes.RemoteEvent); remote_evt != nil && ok {
+				replies = session.press(remote_evt, time.Now())
+			}
+		case in := <-requests:
+			switch in.Action {
+			case pb.LearnAction_LEARN_ACTION_SKIP:
+				replies = session.skip()
+			case pb.LearnAction_LEARN_ACTION_RETRY:
+				replies = session.retry()
+			case pb.LearnAction_LEARN_ACTION_COMMIT:
+				if reply, err := this.commitLearn(session); err != nil {
+					return err
+				} else {
+					return stream.Send(reply)
+				}
+			case pb.LearnAction_LEARN_ACTION_CANCEL:
+				return nil
+			default:
+				this.log.Warn("Learn: Bad request: Unexpected action %v", in.Action)
+				return gopi.ErrBadParameter
+			}
+		case err := <-errs:
+			// Closing the stream without committing discards the keys
+			if err == io.EOF {
+				return nil
+			} else {
+				return err
+			}
+		case <-cancel_requests:
+			return nil
+		}
+		for _, reply := range replies {
+			if err := stream.Send(reply); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (this *service) SendScancode(ctx context.Context, in *pb.SendScancodeRequest) (*pb.EmptyReply, error) {
 	if codec, exists := this.codecs[remotes.CodecType(in.Codec)]; exists == false {
 		this.log.Warn("SendScancode: Bad request: Invalid codec (%v)", remotes.CodecType(in.Codec))
@@ -230,6 +364,21 @@ func (this *service) SendKeycode(ctx context.Context, in *pb.SendKeycodeRequest)
 	return &pb.EmptyReply{}, nil
 }
 
+func (this *service) SendPronto(ctx context.Context, in *pb.SendProntoRequest) (*pb.EmptyReply, error) {
+	if this.lirc == nil {
+		this.log.Warn("SendPronto: Bad request: No LIRC device")
+		return nil, gopi.ErrAppError
+	} else if code, err := remotes.ParsePronto(in.Code); err != nil {
+		this.log.Warn("SendPronto: Bad request: %v", err)
+		return nil, gopi.ErrBadParameter
+	} else if err := remotes.SendPronto(this.lirc, code, uint(in.Repeats)); err != nil {
+		return nil, err
+	} else {
+		// Success
+		return &pb.EmptyReply{}, nil
+	}
+}
+
 func (this *service) Codecs(ctx context.Context, in *pb.EmptyRequest) (*pb.CodecsReply, error) {
 	return toProtobufCodecsReply(this.codecs), nil
 }
@@ -240,14 +389,10 @@ func (this *service) KeyMaps(ctx context.Context, in *pb.EmptyRequest) (*pb.KeyM
 
 func (this *service) Keys(ctx context.Context, in *pb.KeysRequest) (*pb.KeysReply, error) {
 	// Obtain the keymap
-	if in.Keymap == "" {
-		return nil, gopi.ErrBadParameter
-	} else if keymaps := this.keymaps.KeyMaps(remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, in.Keymap); len(keymaps) == 0 {
-		return nil, remotes.ErrNotFound
-	} else if len(keymaps) > 1 {
-		return nil, remotes.ErrAmbiguous
+	if keymap, err := this.keymapWithName(in.Keymap); err != nil {
+		return nil, err
 	} else {
-		return toProtobufKeysReply(this.keymaps.GetKeyMapEntry(keymaps[0], remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, remotes.KEYCODE_NONE, remotes.SCANCODE_UNKNOWN)), nil
+		return toProtobufKeysReply(this.keymaps.GetKeyMapEntry(keymap, remotes.CODEC_NONE, remotes.DEVICE_UNKNOWN, remotes.KEYCODE_NONE, remotes.SCANCODE_UNKNOWN)), nil
 	}
 }
 
@@ -255,6 +400,70 @@ func (this *service) LookupKeys(ctx context.Context, in *pb.LookupKeysRequest) (
 	return toProtobufKeysReply(this.keymaps.LookupKeyCode(in.Terms...)), nil
 }
 
+func (this *service) CreateKeymap(ctx context.Context, in *pb.CreateKeymapRequest) (*pb.KeyMapsReply, error) {
+	if keymap, err := this.keymaps.CreateKeyMap(in.Name, remotes.CodecType(in.Codec), in.Device); err != nil {
+		this.log.Warn("CreateKeymap: Bad request: %v", err)
+		return nil, err
+	} else if err := this.keymaps.SetRepeats(keymap, uint(in.Repeats)); err != nil {
+		return nil, err
+	} else if err := this.saveKeyMaps(); err != nil {
+		return nil, err
+	} else {
+		// Success, returning the keymap with the repeats set
+		return toProtobufKeyMapsReply(this.keymaps.KeyMaps(keymap.Type, keymap.Device, "")), nil
+	}
+}
+
+func (this *service) DeleteKeymap(ctx context.Context, in *pb.DeleteKeymapRequest) (*pb.EmptyReply, error) {
+	if keymap, err := this.keymapWithName(in.Keymap); err != nil {
+		return nil, err
+	} else if err := this.keymaps.DeleteKeyMap(keymap); err != nil {
+		return nil, err
+	} else if err := this.saveKeyMaps(); err != nil {
+		return nil, err
+	} else {
+		// Success
+		return &pb.EmptyReply{}, nil
+	}
+}
+
+func (this *service) SetKeymapName(ctx context.Context, in *pb.SetKeymapNameRequest) (*pb.EmptyReply, error) {
+	if keymap, err := this.keymapWithName(in.Keymap); err != nil {
+		return nil, err
+	} else if err := this.keymaps.SetName(keymap, in.Name); err != nil {
+		this.log.Warn("SetKeymapName: Bad request: %v", err)
+		return nil, err
+	} else if err := this.saveKeyMaps(); err != nil {
+		return nil, err
+	} else {
+		// Success
+		return &pb.EmptyReply{}, nil
+	}
+}
+
+func (this *service) SetKeymapKey(ctx context.Context, in *pb.SetKeymapKeyRequest) (*pb.EmptyReply, error) {
+	keymap, err := this.keymapWithName(in.Keymap)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the keymap codec and device when no codec is set
+	codec, device := remotes.CodecType(in.Codec), in.Device
+	if codec == remotes.CODEC_NONE {
+		codec, device = keymap.Type, keymap.Device
+	}
+
+	if err := this.keymaps.SetKeyMapEntry(keymap, codec, device, remotes.RemoteCode(in.Keycode), in.Scancode); err != nil {
+		this.log.Warn("SetKeymapKey: Bad request: %v", err)
+		return nil, err
+	} else if err := this.saveKeyMaps(); err != nil {
+		return nil, err
+	} else {
+		// Success
+		return &pb.EmptyReply{}, nil
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -0,0 +1,47 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package loopback
+
+// The loopback module implements gopi.LIRC in memory, so that codecs can be
+// used without any IR hardware. Pulses sent with PulseSend are received by
+// the subscribers as alternating pulse and space events, followed by a space
+// of the receive timeout (or a timeout event when timeout reports are
+// enabled), which is how the Linux LIRC driver reports the end of a
+// transmission.
+//
+// Recorded timings can be received with Inject (pulses and spaces) or
+// InjectEvent (any event type, including timeouts). All values received can
+// be jittered by a random amount up to the -loopback.jitter flag in
+// microseconds, in order to check the tolerance of the codecs.
+//
+// Recordings made with ir_rcv -record can be received with Replay, which
+// waits between events for the recorded time divided by the speed, or
+// replays as fast as possible when the speed is zero. When the
+// -loopback.replay flag is set to the path of a recording, it is replayed
+// when the module is opened at the speed set by the -loopback.speed flag.
+//
+// Events are emitted from a background task so that PulseSend does not block
+// on the subscribers. When the -loopback.realtime flag is set, the task waits
+// for the duration of each value so that timestamps are as they would be
+// when received from hardware.
+//
+// To use the loopback module instead of the Linux LIRC driver, import it
+// instead of github.com/djthorpe/gopi/sys/hw/linux and create the app with
+// the "lirc" module:
+//
+//   import (
+//     _ "github.com/djthorpe/remotes/codec/nec"
+//     _ "github.com/djthorpe/remotes/sys/loopback"
+//   )
+//
+//   config := gopi.NewAppConfig("lirc", "remotes/nec32")
+//
+// Or open it directly with gopi.Open(loopback.Loopback{}, logger) and pass it
+// to a codec as the LIRC parameter.
@@ -0,0 +1,44 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package loopback
+
+import (
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register loopback/lirc
+	gopi.RegisterModule(gopi.Module{
+		Name: "loopback/lirc",
+		Type: gopi.MODULE_TYPE_LIRC,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("loopback.jitter", 0, "Maximum jitter on received values in microseconds")
+			config.AppFlags.FlagBool("loopback.realtime", false, "Receive values in real time")
+			config.AppFlags.FlagString("loopback.replay", "", "Replay a recording when opened")
+			config.AppFlags.FlagFloat64("loopback.speed", 1.0, "Replay speed, or zero to replay as fast as possible")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			jitter, _ := app.AppFlags.GetUint("loopback.jitter")
+			realtime, _ := app.AppFlags.GetBool("loopback.realtime")
+			replay, _ := app.AppFlags.GetString("loopback.replay")
+			speed, _ := app.AppFlags.GetFloat64("loopback.speed")
+			return gopi.Open(Loopback{
+				Jitter:   uint32(jitter),
+				Realtime: realtime,
+				Replay:   replay,
+				Speed:    speed,
+			}, app.Logger)
+		},
+	})
+}
@@ -0,0 +1,577 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package loopback
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Loopback Configuration
+type Loopback struct {
+	Jitter   uint32  // Maximum jitter on received values in microseconds
+	Realtime bool    // Receive values in real time
+	Seed     int64   // Seed for jitter, or zero to seed from the clock
+	Replay   string  // Path to a recording to replay when opened
+	Speed    float64 // Replay speed, or zero to replay as fast as possible
+}
+
+// LIRC is the loopback driver interface, which can also receive
+// injected values
+type LIRC interface {
+	gopi.LIRC
+
+	// Inject receives alternating pulses and spaces, starting with a pulse,
+	// followed by the receive timeout
+	Inject(values []uint32) error
+
+	// InjectEvent receives a single event of any type
+	InjectEvent(evt_type gopi.LIRCType, value uint32) error
+
+	// Replay receives recorded events at the times they were recorded,
+	// divided by the speed, or as fast as possible when speed is zero.
+	// It returns when all the events have been received
+	Replay(recording remotes.Recording, speed float64) error
+}
+
+type loopback struct {
+	log      gopi.Logger
+	jitter   uint32
+	realtime bool
+	rand     *rand.Rand
+	queue    chan *lirc_event
+	replay   remotes.Recording
+	speed    float64
+	once     sync.Once
+	started  chan struct{}
+
+	// modes and parameters
+	rcv_mode, send_mode gopi.LIRCMode
+	timeout             uint32
+	timeout_reports     bool
+	rcv_carrier         uint32
+	rcv_carrier_min     uint32
+	rcv_carrier_max     uint32
+	send_carrier        uint32
+	send_duty_cycle     uint32
+
+	sync.Mutex
+	event.Publisher
+	event.Tasks
+}
+
+type lirc_event struct {
+	driver   gopi.Driver
+	lirctype gopi.LIRCType
+	value    uint32
+	done     chan struct{}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// LIRC_RESOLUTION is the receive resolution in microseconds
+	LIRC_RESOLUTION = 1
+	// LIRC_TIMEOUT is the default receive timeout in microseconds
+	LIRC_TIMEOUT = 125000
+	// LIRC_CARRIER_FREQUENCY is the default carrier frequency
+	LIRC_CARRIER_FREQUENCY = 38000
+	// LIRC_DUTY_CYCLE is the default duty cycle
+	LIRC_DUTY_CYCLE = 50
+	// LIRC_VALUE_MAX is the maximum value which can be received
+	LIRC_VALUE_MAX = 0x00FFFFFF
+	// QUEUE_SIZE is the number of events which can be queued
+	// before sending blocks
+	QUEUE_SIZE = 1024
+	// REPLAY_DELAY is the time to wait after the first subscriber
+	// before replaying, so that other modules can subscribe
+	REPLAY_DELAY = 100 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config Loopback) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sys.loopback.LIRC>Open{ jitter=%v realtime=%v replay=%v speed=%v }", config.Jitter, config.Realtime, config.Replay, config.Speed)
+
+	// Check parameters
+	if config.Speed < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(loopback)
+	this.log = log
+	this.jitter = config.Jitter
+	this.realtime = config.Realtime
+	this.queue = make(chan *lirc_event, QUEUE_SIZE)
+	this.started = make(chan struct{})
+	this.rcv_mode = gopi.LIRC_MODE_MODE2
+	this.send_mode = gopi.LIRC_MODE_PULSE
+	this.timeout = LIRC_TIMEOUT
+	this.rcv_carrier = LIRC_CARRIER_FREQUENCY
+	this.send_carrier = LIRC_CARRIER_FREQUENCY
+	this.send_duty_cycle = LIRC_DUTY_CYCLE
+
+	// Seed the jitter
+	if config.Seed == 0 {
+		this.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	} else {
+		this.rand = rand.New(rand.NewSource(config.Seed))
+	}
+
+	// Read the recording to replay
+	if config.Replay != "" {
+		if fh, err := os.Open(config.Replay); err != nil {
+			return nil, err
+		} else {
+			defer fh.Close()
+			if recording, err := remotes.ReadRecording(fh); err != nil {
+				return nil, fmt.Errorf("%v: %v", config.Replay, err)
+			} else {
+				this.replay, this.speed = recording, config.Speed
+			}
+		}
+	}
+
+	// Background tasks
+	this.Tasks.Start(this.ReceiveTask)
+	if this.replay != nil {
+		this.Tasks.Start(this.ReplayTask)
+	}
+
+	// Success
+	return this, nil
+}
+
+func (this *loopback) Close() error {
+	this.log.Debug("<sys.loopback.LIRC>Close{}")
+
+	// End tasks
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Remove subscribers
+	this.Publisher.Close()
+
+	// Release resources
+	this.queue = nil
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GET AND SET PROPERTIES
+
+func (this *loopback) RcvMode() gopi.LIRCMode {
+	this.Lock()
+	defer this.Unlock()
+	return this.rcv_mode
+}
+
+func (this *loopback) SendMode() gopi.LIRCMode {
+	this.Lock()
+	defer this.Unlock()
+	return this.send_mode
+}
+
+func (this *loopback) SetRcvMode(m gopi.LIRCMode) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetRcvMode>{ mode=%v }", m)
+	this.Lock()
+	defer this.Unlock()
+
+	// Only mode2 is supported for receiving
+	if m != gopi.LIRC_MODE_MODE2 {
+		return gopi.ErrNotImplemented
+	}
+	this.rcv_mode = m
+	return nil
+}
+
+func (this *loopback) SetSendMode(m gopi.LIRCMode) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetSendMode>{ mode=%v }", m)
+	this.Lock()
+	defer this.Unlock()
+
+	// Only pulse mode is supported for sending
+	if m != gopi.LIRC_MODE_PULSE {
+		return gopi.ErrNotImplemented
+	}
+	this.send_mode = m
+	return nil
+}
+
+func (this *loopback) GetRcvResolution() (uint32, error) {
+	return LIRC_RESOLUTION, nil
+}
+
+func (this *loopback) SetRcvTimeout(micros uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetRcvTimeout>{ micros=%v }", micros)
+	this.Lock()
+	defer this.Unlock()
+
+	if micros > LIRC_VALUE_MAX {
+		return gopi.ErrBadParameter
+	} else if micros == 0 {
+		this.timeout = LIRC_TIMEOUT
+	} else {
+		this.timeout = micros
+	}
+	return nil
+}
+
+func (this *loopback) SetRcvTimeoutReports(enable bool) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetRcvTimeoutReports>{ enable=%v }", enable)
+	this.Lock()
+	defer this.Unlock()
+
+	this.timeout_reports = enable
+	return nil
+}
+
+func (this *loopback) SetRcvCarrierHz(value uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetRcvCarrierHz>{ hz=%v }", value)
+	this.Lock()
+	defer this.Unlock()
+
+	if value == 0 {
+		return gopi.ErrBadParameter
+	}
+	this.rcv_carrier = value
+	return nil
+}
+
+func (this *loopback) SetRcvCarrierRangeHz(min uint32, max uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetRcvCarrierRangeHz>{ min=%v max=%v }", min, max)
+	this.Lock()
+	defer this.Unlock()
+
+	if min == 0 || max < min {
+		return gopi.ErrBadParameter
+	}
+	this.rcv_carrier_min, this.rcv_carrier_max = min, max
+	return nil
+}
+
+func (this *loopback) SetSendCarrierHz(value uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetSendCarrierHz>{ hz=%v }", value)
+	this.Lock()
+	defer this.Unlock()
+
+	if value == 0 {
+		return gopi.ErrBadParameter
+	}
+	this.send_carrier = value
+	return nil
+}
+
+func (this *loopback) SetSendDutyCycle(value uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.SetSendDutyCycle>{ value=%v }", value)
+	this.Lock()
+	defer this.Unlock()
+
+	if value == 0 || value > remotes.MAX_DUTY_CYCLE {
+		return gopi.ErrBadParameter
+	}
+	this.send_duty_cycle = value
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SEND AND INJECT
+
+// PulseSend receives the values on the subscribers, values are in
+// microseconds
+func (this *loopback) PulseSend(values []uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.PulseSend>{ values=%v }", values)
+
+	// Check for odd number of values
+	if len(values) == 0 || len(values)%2 == 0 {
+		this.log.Debug("sys.loopback.LIRC.PulseSend: Requires odd number of values")
+		return gopi.ErrBadParameter
+	}
+
+	// Receive the values
+	return this.Inject(values)
+}
+
+// Inject receives alternating pulses and spaces, followed by
+// the receive timeout
+func (this *loopback) Inject(values []uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.Inject>{ values=%v }", values)
+
+	// Check values
+	if len(values) == 0 {
+		return gopi.ErrBadParameter
+	}
+	for _, value := range values {
+		if value == 0 || value > LIRC_VALUE_MAX {
+			return gopi.ErrBadParameter
+		}
+	}
+
+	// Queue pulses and spaces
+	for i, value := range values {
+		if i%2 == 0 {
+			this.queue <- this.newEvent(gopi.LIRC_TYPE_PULSE, this.jitterValue(value))
+		} else {
+			this.queue <- this.newEvent(gopi.LIRC_TYPE_SPACE, this.jitterValue(value))
+		}
+	}
+
+	// End with the timeout, after a pulse
+	if len(values)%2 == 1 {
+		this.Lock()
+		timeout, timeout_reports := this.timeout, this.timeout_reports
+		this.Unlock()
+		if timeout_reports {
+			this.queue <- this.newEvent(gopi.LIRC_TYPE_TIMEOUT, timeout)
+		} else {
+			this.queue <- this.newEvent(gopi.LIRC_TYPE_SPACE, timeout)
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// InjectEvent receives a single event, and is used for replaying
+// recorded events
+func (this *loopback) InjectEvent(evt_type gopi.LIRCType, value uint32) error {
+	this.log.Debug2("<sys.loopback.LIRC.InjectEvent>{ type=%v value=%v }", evt_type, value)
+
+	if evt, err := this.injectedEvent(evt_type, value); err != nil {
+		return err
+	} else {
+		this.queue <- evt
+	}
+
+	// Success
+	return nil
+}
+
+// Replay receives recorded events at the times they were recorded
+func (this *loopback) Replay(recording remotes.Recording, speed float64) error {
+	this.log.Debug2("<sys.loopback.LIRC.Replay>{ events=%v speed=%v }", len(recording), speed)
+	return this.replayEvents(recording, speed, nil)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASKS
+
+func (this *loopback) ReceiveTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-this.queue:
+			// Signal when all events before this one have been received
+			if evt.done != nil {
+				close(evt.done)
+				continue
+			}
+			this.Emit(evt)
+			// Wait for pulses and spaces to complete
+			if this.realtime && (evt.lirctype == gopi.LIRC_TYPE_PULSE || evt.lirctype == gopi.LIRC_TYPE_SPACE) {
+				select {
+				case <-time.After(time.Duration(evt.value) * time.Microsecond):
+					break
+				case <-stop:
+					break FOR_LOOP
+				}
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	// Success
+	return nil
+}
+
+func (this *loopback) ReplayTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+
+	// Wait for the first subscriber, and then for other subscribers
+	select {
+	case <-this.started:
+		break
+	case <-stop:
+		return nil
+	}
+	select {
+	case <-time.After(REPLAY_DELAY):
+		break
+	case <-stop:
+		return nil
+	}
+
+	// Replay the events
+	if err := this.replayEvents(this.replay, this.speed, stop); err != nil {
+		this.log.Error("<sys.loopback.LIRC.ReplayTask> %v", err)
+	} else {
+		this.log.Debug("<sys.loopback.LIRC.ReplayTask> Replayed %v events", len(this.replay))
+	}
+
+	// Wait for stop signal
+	<-stop
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLISHER INTERFACE
+
+// Subscribe returns a channel for receiving events, and signals the replay
+// task that there is a subscriber
+func (this *loopback) Subscribe() <-chan gopi.Event {
+	defer this.once.Do(func() {
+		close(this.started)
+	})
+	return this.Publisher.Subscribe()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENTS INTERFACE
+
+func (this *lirc_event) Name() string {
+	return "LIRCEvent"
+}
+
+func (this *lirc_event) Source() gopi.Driver {
+	return this.driver
+}
+
+func (this *lirc_event) Type() gopi.LIRCType {
+	return this.lirctype
+}
+
+func (this *lirc_event) Value() uint32 {
+	return this.value
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *loopback) String() string {
+	this.Lock()
+	defer this.Unlock()
+	return fmt.Sprintf("<sys.loopback.LIRC>{ rcv_mode=%v send_mode=%v timeout=%v send_carrier=%v jitter=%v realtime=%v }", this.rcv_mode, this.send_mode, this.timeout, this.send_carrier, this.jitter, this.realtime)
+}
+
+func (this *lirc_event) String() string {
+	return fmt.Sprintf("<sys.loopback.LIRC.Event>{ type=%v value=%v }", this.Type(), this.Value())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *loopback) newEvent(evt_type gopi.LIRCType, value uint32) *lirc_event {
+	return &lirc_event{
+		driver:   this,
+		lirctype: evt_type,
+		value:    value,
+	}
+}
+
+// injectedEvent returns an event to be received, with jitter added to
+// pulses and spaces
+func (this *loopback) injectedEvent(evt_type gopi.LIRCType, value uint32) (*lirc_event, error) {
+	if evt_type > gopi.LIRC_TYPE_MAX || evt_type&LIRC_VALUE_MAX != 0 || value > LIRC_VALUE_MAX {
+		return nil, gopi.ErrBadParameter
+	} else if evt_type == gopi.LIRC_TYPE_PULSE || evt_type == gopi.LIRC_TYPE_SPACE {
+		return this.newEvent(evt_type, this.jitterValue(value)), nil
+	} else {
+		return this.newEvent(evt_type, value), nil
+	}
+}
+
+// replayEvents injects recorded events at the times they were recorded and
+// then waits for them to be received, or returns early when stopped
+func (this *loopback) replayEvents(recording remotes.Recording, speed float64, stop <-chan event.Signal) error {
+	if speed < 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// Inject the events
+	start := time.Now()
+	for _, evt := range recording {
+		if speed > 0 {
+			if wait := time.Duration(float64(evt.Timestamp)/speed) - time.Since(start); wait > 0 {
+				select {
+				case <-time.After(wait):
+					break
+				case <-stop:
+					return nil
+				}
+			}
+		}
+		if evt, err := this.injectedEvent(evt.Type(), evt.Value()); err != nil {
+			return err
+		} else {
+			select {
+			case this.queue <- evt:
+				break
+			case <-stop:
+				return nil
+			}
+		}
+	}
+
+	// Wait until the events have been received
+	done := make(chan struct{})
+	select {
+	case this.queue <- &lirc_event{done: done}:
+		break
+	case <-stop:
+		return nil
+	}
+	select {
+	case <-done:
+		break
+	case <-stop:
+		break
+	}
+
+	// Success
+	return nil
+}
+
+// jitterValue returns a value with a random deviation of up to the
+// jitter, which is always at least one microsecond
+func (this *loopback) jitterValue(value uint32) uint32 {
+	if this.jitter == 0 {
+		return value
+	}
+	this.Lock()
+	deviation := this.rand.Int63n(int64(this.jitter)*2+1) - int64(this.jitter)
+	this.Unlock()
+	if result := int64(value) + deviation; result < 1 {
+		return 1
+	} else if result > LIRC_VALUE_MAX {
+		return LIRC_VALUE_MAX
+	} else {
+		return uint32(result)
+	}
+}
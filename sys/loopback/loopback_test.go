@@ -0,0 +1,261 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package loopback_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/codec/irp"
+	"github.com/djthorpe/remotes/codec/jvc"
+	"github.com/djthorpe/remotes/codec/kaseikyo"
+	"github.com/djthorpe/remotes/codec/nec"
+	"github.com/djthorpe/remotes/codec/rc5"
+	"github.com/djthorpe/remotes/codec/rc6"
+	"github.com/djthorpe/remotes/codec/sanyo"
+	"github.com/djthorpe/remotes/codec/sharp"
+	"github.com/djthorpe/remotes/codec/sony"
+	"github.com/djthorpe/remotes/sys/loopback"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type codecTest struct {
+	name     string
+	config   func(lirc gopi.LIRC) gopi.Config
+	device   uint32
+	scancode uint32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Jitter in microseconds for the tests with jitter
+	TEST_JITTER = 60
+	// Time to wait for events
+	TEST_TIMEOUT = 2 * time.Second
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	codecTests = []codecTest{
+		{"NEC32", necConfig(remotes.CODEC_NEC32), 0x40BF, 0x12},
+		{"NECX", necConfig(remotes.CODEC_NECX), 0x40BF, 0x1234},
+		{"SAMSUNG32", necConfig(remotes.CODEC_SAMSUNG32), 0x0707, 0x02},
+		{"APPLETV", necConfig(remotes.CODEC_APPLETV), 0x5A, 0x0C},
+		{"RC5", rc5Config(remotes.CODEC_RC5), 0x14, 0x35},
+		{"RC5X_20", rc5Config(remotes.CODEC_RC5X_20), 0x14, 0x75},
+		{"RC6_0", rc6Config(remotes.CODEC_RC6_0), 0x00, 0x0C},
+		{"RC6_MCE", rc6Config(remotes.CODEC_RC6_MCE), 0x800F04, 0x0D},
+		{"SONY12", sonyConfig(remotes.CODEC_SONY12), 0x01, 0x15},
+		{"SONY15", sonyConfig(remotes.CODEC_SONY15), 0xA4, 0x15},
+		{"SONY20", sonyConfig(remotes.CODEC_SONY20), 0x1A5A, 0x15},
+		{"JVC", jvcConfig, 0x03, 0x17},
+		{"SANYO", sanyoConfig, 0x1234, 0x56},
+		{"SHARP", sharpConfig, 0x11, 0x5A},
+		{"PANASONIC", kaseikyoConfig(remotes.CODEC_PANASONIC, kaseikyo.VENDOR_PANASONIC), 0x0801, 0x3D},
+		{"KASEIKYO", kaseikyoConfig(remotes.CODEC_KASEIKYO, kaseikyo.VENDOR_ANY), kaseikyo.Device(kaseikyo.VENDOR_DENON, 0x05, 0x02), 0x44},
+		{"IRP", irpConfig, 0x2A, 0xC3},
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestSend(t *testing.T) {
+	for _, test := range codecTests {
+		t.Run(test.name, func(t *testing.T) {
+			sendReceive(t, test, 0)
+		})
+	}
+}
+
+func TestSendJitter(t *testing.T) {
+	for _, test := range codecTests {
+		t.Run(test.name, func(t *testing.T) {
+			sendReceive(t, test, TEST_JITTER)
+		})
+	}
+}
+
+func TestPulseSend(t *testing.T) {
+	lirc := openLoopback(t, 0)
+	defer lirc.Close()
+
+	// Values are received followed by the timeout
+	events := lirc.Subscribe()
+	defer lirc.Unsubscribe(events)
+	if err := lirc.PulseSend([]uint32{100, 200}); err != gopi.ErrBadParameter {
+		t.Errorf("Expected ErrBadParameter for even values, got %v", err)
+	}
+	if err := lirc.PulseSend([]uint32{100, 200, 300}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		evt_type gopi.LIRCType
+		value    uint32
+	}{
+		{gopi.LIRC_TYPE_PULSE, 100},
+		{gopi.LIRC_TYPE_SPACE, 200},
+		{gopi.LIRC_TYPE_PULSE, 300},
+		{gopi.LIRC_TYPE_SPACE, loopback.LIRC_TIMEOUT},
+	}
+	for _, e := range expected {
+		select {
+		case evt := <-events:
+			if evt_ := evt.(gopi.LIRCEvent); evt_.Type() != e.evt_type || evt_.Value() != e.value {
+				t.Errorf("Expected %v %v, got %v", e.evt_type, e.value, evt)
+			}
+		case <-time.After(TEST_TIMEOUT):
+			t.Fatal("Timeout waiting for events")
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// sendReceive sends a key press with one repeat, and checks the events
+// received are a key press followed by one or more repeats
+func sendReceive(t *testing.T, test codecTest, jitter uint32) {
+	t.Helper()
+	lirc := openLoopback(t, jitter)
+	codec := openCodec(t, test.config(lirc))
+	events := codec.Subscribe()
+	defer func() {
+		// Emitting blocks until received, so drain the events and close
+		// the loopback before the codec
+		go func() {
+			for range events {
+			}
+		}()
+		lirc.Close()
+		codec.Unsubscribe(events)
+		codec.Close()
+	}()
+
+	if err := codec.Send(test.device, test.scancode, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Receive the key press and the repeat
+	for i := 0; i < 2; i++ {
+		select {
+		case evt := <-events:
+			evt_ := evt.(remotes.RemoteEvent)
+			if evt_.Codec() != codec.Type() {
+				t.Errorf("Expected codec %v, got %v", codec.Type(), evt_.Codec())
+			}
+			if evt_.Device() != test.device || evt_.ScanCode() != test.scancode {
+				t.Errorf("Expected device 0x%X scancode 0x%X, got %v", test.device, test.scancode, evt_)
+			}
+			if i == 0 && evt_.EventType() != gopi.INPUT_EVENT_KEYPRESS {
+				t.Errorf("Expected key press, got %v", evt_)
+			} else if i > 0 && evt_.EventType() != gopi.INPUT_EVENT_KEYREPEAT {
+				t.Errorf("Expected repeat, got %v", evt_)
+			}
+		case <-time.After(TEST_TIMEOUT):
+			t.Fatalf("Timeout waiting for event %v", i)
+		}
+	}
+}
+
+func newLogger(t *testing.T) gopi.Logger {
+	t.Helper()
+	if driver, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(gopi.Logger)
+	}
+}
+
+func openLoopback(t *testing.T, jitter uint32) loopback.LIRC {
+	t.Helper()
+	if driver, err := gopi.Open(loopback.Loopback{Jitter: jitter, Seed: 1}, newLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(loopback.LIRC)
+	}
+}
+
+func openCodec(t *testing.T, config gopi.Config) remotes.Codec {
+	t.Helper()
+	if driver, err := gopi.Open(config, newLogger(t)); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		return driver.(remotes.Codec)
+	}
+}
+
+func necConfig(codec_type remotes.CodecType) func(gopi.LIRC) gopi.Config {
+	return func(lirc gopi.LIRC) gopi.Config {
+		return nec.Codec{LIRC: lirc, Type: codec_type}
+	}
+}
+
+func rc5Config(codec_type remotes.CodecType) func(gopi.LIRC) gopi.Config {
+	return func(lirc gopi.LIRC) gopi.Config {
+		return rc5.Codec{LIRC: lirc, Type: codec_type}
+	}
+}
+
+func rc6Config(codec_type remotes.CodecType) func(gopi.LIRC) gopi.Config {
+	return func(lirc gopi.LIRC) gopi.Config {
+		return rc6.Codec{LIRC: lirc, Type: codec_type}
+	}
+}
+
+func sonyConfig(codec_type remotes.CodecType) func(gopi.LIRC) gopi.Config {
+	return func(lirc gopi.LIRC) gopi.Config {
+		return sony.Codec{LIRC: lirc, Type: codec_type}
+	}
+}
+
+func kaseikyoConfig(codec_type remotes.CodecType, vendor uint16) func(gopi.LIRC) gopi.Config {
+	return func(lirc gopi.LIRC) gopi.Config {
+		return kaseikyo.Codec{LIRC: lirc, Type: codec_type, Vendor: vendor}
+	}
+}
+
+func jvcConfig(lirc gopi.LIRC) gopi.Config {
+	return jvc.Codec{LIRC: lirc}
+}
+
+func sanyoConfig(lirc gopi.LIRC) gopi.Config {
+	return sanyo.Codec{LIRC: lirc}
+}
+
+func sharpConfig(lirc gopi.LIRC) gopi.Config {
+	return sharp.Codec{LIRC: lirc}
+}
+
+func irpConfig(lirc gopi.LIRC) gopi.Config {
+	codec_type, err := remotes.NamedCodecType("loopback_test")
+	if err != nil {
+		panic(err)
+	}
+	protocol, err := irp.ParseProtocol("{38k,564}<1,-1|1,-3>(16,-8,D:8,F:8,1,^108m,(16,-4,1,^108m)*)[D:0..255,F:0..255]")
+	if err != nil {
+		panic(fmt.Errorf("%v: %v", codec_type, err))
+	}
+	return irp.Codec{LIRC: lirc, Type: codec_type, Protocol: protocol}
+}
@@ -24,10 +24,15 @@ func init() {
 		Name:     "remotes/sony12",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("sony12.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("sony12.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
-				Type: remotes.CODEC_SONY12,
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_SONY12,
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
@@ -37,10 +42,15 @@ func init() {
 		Name:     "remotes/sony15",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("sony15.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("sony15.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
-				Type: remotes.CODEC_SONY15,
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_SONY15,
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
@@ -50,10 +60,15 @@ func init() {
 		Name:     "remotes/sony20",
 		Requires: []string{"lirc"},
 		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("sony20.tolerance", TOLERANCE, "Tolerance on pulses and spaces as a percentage")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			tolerance, _ := app.AppFlags.GetUint("sony20.tolerance")
 			return gopi.Open(Codec{
-				LIRC: app.ModuleInstance("lirc").(gopi.LIRC),
-				Type: remotes.CODEC_SONY20,
+				LIRC:      app.ModuleInstance("lirc").(gopi.LIRC),
+				Type:      remotes.CODEC_SONY20,
+				Tolerance: uint32(tolerance),
 			}, app.Logger)
 		},
 	})
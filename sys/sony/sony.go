@@ -24,13 +24,16 @@ import (
 
 // Sony Configuration - for 12, 15 and 20
 type Codec struct {
-	LIRC gopi.LIRC
-	Type remotes.CodecType
+	LIRC      gopi.LIRC
+	Type      remotes.CodecType
+	Tolerance uint32 // Tolerance for pulses and spaces as a percentage, or zero for the default
 }
 
 type codec struct {
 	log        gopi.Logger
 	lirc       gopi.LIRC
+	carriers   *remotes.Carriers
+	timing     *remotes.Timing
 	codec_type remotes.CodecType
 	bit_length uint
 	state      state
@@ -38,6 +41,8 @@ type codec struct {
 	duration   uint32
 	length     uint
 	repeat     bool
+	decoding   bool
+	elapsed    time.Duration
 
 	event.Publisher
 	event.Tasks
@@ -59,8 +64,10 @@ const (
 )
 
 const (
-	TOLERANCE   = 35    // 35% tolerance on values
-	TX_DURATION = 45000 // 45ms between each transmission
+	TOLERANCE         = 35    // 35% tolerance on values
+	CARRIER_FREQUENCY = 40000 // 40kHz carrier
+	DUTY_CYCLE        = 33    // 33% duty cycle
+	TX_DURATION       = 45000 // 45ms between each transmission
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -85,14 +92,17 @@ var (
 func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 	log.Debug("<remotes.codec.sony>Open{ lirc=%v type=%v }", config.LIRC, config.Type)
 
-	// Check for LIRC
-	if config.LIRC == nil {
-		return nil, gopi.ErrBadParameter
-	}
-
 	this := new(codec)
 	this.log = log
 	this.lirc = config.LIRC
+	this.carriers = remotes.NewCarriers(CARRIER_FREQUENCY, DUTY_CYCLE)
+
+	// Set the tolerance for pulses and spaces
+	if timing, err := remotes.NewTiming(TOLERANCE, config.Tolerance); err != nil {
+		return nil, err
+	} else {
+		this.timing = timing
+	}
 
 	// Set codec and bit length
 	if bit_length := bitLengthForCodec(config.Type); bit_length == 0 {
@@ -105,8 +115,10 @@ func (config Codec) Open(log gopi.Logger) (gopi.Driver, error) {
 	// Reset state
 	this.Reset()
 
-	// Backround tasks
-	this.Tasks.Start(this.PulseTask)
+	// Background tasks, when there is a LIRC driver
+	if this.lirc != nil {
+		this.Tasks.Start(this.PulseTask)
+	}
 
 	// Return success
 	return this, nil
@@ -143,12 +155,29 @@ func (this *codec) Type() remotes.CodecType {
 	return this.codec_type
 }
 
+func (this *codec) Carrier(device uint32) remotes.Carrier {
+	return this.carriers.Carrier(device)
+}
+
+func (this *codec) SetCarrier(device uint32, carrier *remotes.Carrier) error {
+	return this.carriers.SetCarrier(device, carrier)
+}
+
+func (this *codec) Tolerance() uint32 {
+	return this.timing.Tolerance()
+}
+
+func (this *codec) Calibrate(device uint32, calibration *remotes.Calibration) error {
+	return this.timing.Calibrate(device, calibration)
+}
+
 func (this *codec) Reset() {
 	this.state = STATE_EXPECT_HEADER_PULSE
 	this.value = 0
 	this.length = 0
 	this.duration = 0
 	this.repeat = false
+	this.timing.Reset()
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -159,8 +188,8 @@ func (this *codec) Emit(value uint32, repeat bool) {
 		if err != gopi.ErrBadParameter {
 			this.log.Warn("Emit: %v", err)
 		}
-	} else {
-		this.Publisher.Emit(remotes.NewRemoteEvent(this, time.Since(timestamp), scancode, device, repeat))
+	} else if this.timing.Accepts(device) {
+		this.Publisher.Emit(remotes.NewRemoteEvent(this, this.now(), scancode, device, repeat))
 	}
 }
 
@@ -193,37 +222,41 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 	this.log.Debug2("<remotes.codec.sony>Receive{ type=%v evt=%v }", this.codec_type, evt)
 	switch this.state {
 	case STATE_EXPECT_HEADER_PULSE:
-		if HEADER_PULSE.Matches(evt) {
+		if this.timing.Matches(HEADER_PULSE, evt) {
 			this.state = STATE_EXPECT_SPACE
 			this.duration += evt.Value()
 		} else {
 			this.Reset()
 		}
 	case STATE_EXPECT_SPACE:
-		if ONEZERO_SPACE.Matches(evt) {
+		if this.timing.Matches(ONEZERO_SPACE, evt) {
 			this.value <<= 1
 			this.state = STATE_EXPECT_BIT
 			this.duration += evt.Value()
 		} else {
 			REPEAT_SPACE.Set(TX_DURATION-this.duration, TOLERANCE)
-			if REPEAT_SPACE.Matches(evt) && this.length == this.bit_length {
+			if this.timing.Matches(REPEAT_SPACE, evt) && this.length == this.bit_length {
 				this.Emit(this.value, this.repeat)
 				this.value = 0
 				this.length = 0
 				this.duration = 0
 				this.repeat = true
 				this.state = STATE_EXPECT_HEADER_PULSE
+			} else if this.length == this.bit_length && (this.timing.GreaterThan(REPEAT_SPACE, evt) || evt.Type() == gopi.LIRC_TYPE_TIMEOUT) {
+				// The last frame ends with a long space or timeout
+				this.Emit(this.value, this.repeat)
+				this.Reset()
 			} else {
 				this.Reset()
 			}
 		}
 	case STATE_EXPECT_BIT:
-		if ONE_PULSE.Matches(evt) {
+		if this.timing.Matches(ONE_PULSE, evt) {
 			this.value |= 1
 			this.length += 1
 			this.state = STATE_EXPECT_SPACE
 			this.duration += evt.Value()
-		} else if ZERO_PULSE.Matches(evt) {
+		} else if this.timing.Matches(ZERO_PULSE, evt) {
 			this.value |= 0
 			this.length += 1
 			this.state = STATE_EXPECT_SPACE
@@ -236,18 +269,45 @@ func (this *codec) receive(evt gopi.LIRCEvent) {
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// DECODING
+
+// Decode returns remote events for a sequence of LIRC events, using a
+// decoder with a fresh state
+func (this *codec) Decode(events []gopi.LIRCEvent) ([]remotes.RemoteEvent, error) {
+	decoder := &codec{log: this.log, timing: this.timing.Copy(), codec_type: this.codec_type, bit_length: this.bit_length, decoding: true}
+	decoder.Reset()
+	defer decoder.Publisher.Close()
+
+	return remotes.DecodeEvents(decoder, func(evt gopi.LIRCEvent) {
+		decoder.elapsed += remotes.EventDuration(evt)
+		decoder.receive(evt)
+	}, events), nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // SENDING
 
 func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 	this.log.Debug2("<remotes.codec.sony>Send{ codec_type=%v device=0x%08X scancode=0x%08X repeats=%v }", this.codec_type, device, scancode, repeats)
 
+	if this.lirc == nil {
+		this.log.Error("<remotes.codec.sony> Send: No LIRC driver")
+		return gopi.ErrAppError
+	} else if pulses, err := this.Encode(device, scancode, repeats); err != nil {
+		return err
+	} else {
+		return remotes.SendPulses(this.lirc, this.carriers.Carrier(device), pulses)
+	}
+}
+
+func (this *codec) Encode(device uint32, scancode uint32, repeats uint) ([]uint32, error) {
 	// Array of pulses
 	pulses := make([]uint32, 0, 100)
 
 	// Make bits and pulses
 	if bits, err := bitsForCodec(this.codec_type, device, scancode); err != nil {
-		return err
+		return nil, err
 	} else {
 		for j := uint(0); j < (repeats + 1); j++ {
 			length := HEADER_PULSE.Value
@@ -273,13 +333,23 @@ func (this *codec) Send(device uint32, scancode uint32, repeats uint) error {
 		}
 	}
 
-	// Perform the sending
-	return this.lirc.PulseSend(pulses)
+	// Return the pulses
+	return pulses, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// now returns the timestamp for an emitted event, which is the duration of
+// the events received when decoding
+func (this *codec) now() time.Duration {
+	if this.decoding {
+		return this.elapsed
+	} else {
+		return time.Since(timestamp)
+	}
+}
+
 func bitLengthForCodec(codec remotes.CodecType) uint {
 	switch codec {
 	case remotes.CODEC_SONY12:
@@ -0,0 +1,39 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package uinput
+
+// The uinput module creates a virtual keyboard with the Linux uinput
+// driver, so that applications such as Kodi, mpv and browsers can be
+// controlled by any remote which has a keymap. The keys from the mapper
+// module are injected as Linux input events when they are pressed,
+// repeated and released, so the lifecycle module is also used.
+//
+// Keycodes which are gopi keycodes (for example KEYCODE_VOLUME_UP and
+// KEYCODE_NAV_LEFT) are the same as the Linux KEY_ codes. Other keycodes
+// are translated using DEFAULT_KEYS, which also translates KEYCODE_NAV_SELECT
+// to KEY_ENTER and KEYCODE_NAV_BACK to KEY_BACK. Keys which have no
+// translation are ignored. The translation can be changed with a file of keycodes and
+// Linux KEY_ codes, set with the -uinput.keys flag. For example, to send
+// KEY_ESC for the back button and KEY_ENTER for select:
+//
+//   # Keycode        Linux KEY_ code
+//   KEYCODE_NAV_BACK    1
+//   KEYCODE_NAV_SELECT  0x1C
+//
+// The Linux KEY_ codes are defined in linux/input-event-codes.h. A keycode
+// with a code of zero is ignored.
+//
+// The device is created when the module is opened, with the name set by
+// the -uinput.name flag, and destroyed when closed. The user needs write
+// access to /dev/uinput. Instead of creating a device, input events can be
+// written to a file with the -uinput.file flag, or to any io.Writer with
+// the Writer parameter, which is useful for testing. Each event is the
+// Linux input_event structure, and each key is followed by a SYN_REPORT
+// event.
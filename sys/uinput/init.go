@@ -0,0 +1,67 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package uinput
+
+import (
+	"os"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register uinput/remotes
+	gopi.RegisterModule(gopi.Module{
+		Name:     "uinput/remotes",
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Requires: []string{"remotes/lifecycle", "remotes/mapper"},
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagString("uinput.device", DEVICE, "Path to the uinput device")
+			config.AppFlags.FlagString("uinput.name", NAME, "Name of the virtual keyboard")
+			config.AppFlags.FlagString("uinput.keys", "", "File of keycodes and Linux key codes")
+			config.AppFlags.FlagString("uinput.file", "", "Write input events to a file instead of the uinput device")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			device, _ := app.AppFlags.GetString("uinput.device")
+			name, _ := app.AppFlags.GetString("uinput.name")
+			config := UInput{
+				Mapper: app.ModuleInstance("remotes/mapper").(remotes.Mapper),
+				Device: device,
+				Name:   name,
+			}
+			// Read the keycode translations
+			if filename, _ := app.AppFlags.GetString("uinput.keys"); filename != "" {
+				if fh, err := os.Open(filename); err != nil {
+					return nil, err
+				} else {
+					defer fh.Close()
+					if keys, err := ReadKeys(fh); err != nil {
+						return nil, err
+					} else {
+						config.Keys = keys
+					}
+				}
+			}
+			// Write events to a file
+			if filename, _ := app.AppFlags.GetString("uinput.file"); filename != "" {
+				if fh, err := os.Create(filename); err != nil {
+					return nil, err
+				} else {
+					config.Writer = fh
+				}
+			}
+			return gopi.Open(config, app.Logger)
+		},
+	})
+}
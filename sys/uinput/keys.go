@@ -0,0 +1,125 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package uinput
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	// DEFAULT_KEYS are the Linux key codes for keycodes which are not
+	// gopi keycodes, or where the gopi keycode isn't understood by most
+	// applications
+	DEFAULT_KEYS = map[remotes.RemoteCode]uint16{
+		remotes.KEYCODE_EJECT:           161, // KEY_EJECTCD
+		remotes.KEYCODE_POWER_OFF:       142, // KEY_SLEEP
+		remotes.KEYCODE_POWER_ON:        143, // KEY_WAKEUP
+		remotes.KEYCODE_INPUT_PC:        376, // KEY_PC
+		remotes.KEYCODE_INPUT_VIDEO1:    393, // KEY_VIDEO
+		remotes.KEYCODE_INPUT_AUX1:      390, // KEY_AUX
+		remotes.KEYCODE_INPUT_CD:        383, // KEY_CD
+		remotes.KEYCODE_INPUT_DVD:       389, // KEY_DVD
+		remotes.KEYCODE_INPUT_TAPE1:     384, // KEY_TAPE
+		remotes.KEYCODE_INPUT_TUNER:     386, // KEY_TUNER
+		remotes.KEYCODE_INPUT_TEXT:      388, // KEY_TEXT
+		remotes.KEYCODE_INPUT_NEXT:      407, // KEY_NEXT
+		remotes.KEYCODE_INPUT_PREV:      412, // KEY_PREVIOUS
+		remotes.KEYCODE_VIDEO_ASPECT:    375, // KEY_SCREEN
+		remotes.KEYCODE_AUDIO_MONITOR:   392, // KEY_AUDIO
+		remotes.KEYCODE_CLEAR:           355, // KEY_CLEAR
+		remotes.KEYCODE_TIMER:           359, // KEY_TIME
+		remotes.KEYCODE_CHANNEL_PREV:    405, // KEY_LAST
+		remotes.KEYCODE_CHANNEL_GUIDE:   365, // KEY_EPG
+		remotes.KEYCODE_RECORD:          167, // KEY_RECORD
+		remotes.KEYCODE_PLAY_SPEED:      409, // KEY_SLOW
+		remotes.KEYCODE_PLAY_MODE:       373, // KEY_MODE
+		remotes.KEYCODE_REPLAY:          168, // KEY_REWIND
+		remotes.KEYCODE_DISPLAY:         431, // KEY_DISPLAYTOGGLE
+		remotes.KEYCODE_MENU:            139, // KEY_MENU
+		remotes.KEYCODE_INFO:            358, // KEY_INFO
+		remotes.KEYCODE_HOME:            172, // KEY_HOMEPAGE
+		remotes.KEYCODE_FAVOURITE:       364, // KEY_FAVORITES
+		remotes.KEYCODE_BUTTON_RED:      398, // KEY_RED
+		remotes.KEYCODE_BUTTON_GREEN:    399, // KEY_GREEN
+		remotes.KEYCODE_BUTTON_YELLOW:   400, // KEY_YELLOW
+		remotes.KEYCODE_BUTTON_BLUE:     401, // KEY_BLUE
+		remotes.KEYCODE_SEARCH_LEFT:     168, // KEY_REWIND
+		remotes.KEYCODE_SEARCH_RIGHT:    208, // KEY_FASTFORWARD
+		remotes.KEYCODE_CHAPTER_NEXT:    163, // KEY_NEXTSONG
+		remotes.KEYCODE_CHAPTER_PREV:    165, // KEY_PREVIOUSSONG
+		remotes.KEYCODE_NAV_SELECT:      28,  // KEY_ENTER
+		remotes.KEYCODE_NAV_BACK:        158, // KEY_BACK
+		remotes.KEYCODE_SUBTITLE_TOGGLE: 370, // KEY_SUBTITLE
+		remotes.KEYCODE_STOP:            166, // KEY_STOPCD
+		remotes.KEYCODE_PAUSE:           201, // KEY_PAUSECD
+		remotes.KEYCODE_SLEEP:           142, // KEY_SLEEP
+		remotes.KEYCODE_SHUFFLE:         410, // KEY_SHUFFLE
+		remotes.KEYCODE_REPEAT:          439, // KEY_MEDIA_REPEAT
+		remotes.KEYCODE_KEYPAD_10PLUS:   413, // KEY_DIGITS
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// ReadKeys reads lines of keycodes and Linux key codes. Keycodes are names
+// such as KEYCODE_NAV_BACK or numbers, and Linux key codes are decimal or
+// hexadecimal numbers. Blank lines and lines starting with # are ignored
+func ReadKeys(r io.Reader) (map[remotes.RemoteCode]uint16, error) {
+	keys := make(map[remotes.RemoteCode]uint16)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		if fields := strings.Fields(text); len(fields) != 2 {
+			return nil, fmt.Errorf("Line %v: Expected keycode and Linux key code", line)
+		} else if keycode, err := parseKeyCode(fields[0]); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		} else if code, err := strconv.ParseUint(fields[1], 0, 16); err != nil {
+			return nil, fmt.Errorf("Line %v: Invalid Linux key code: %v", line, fields[1])
+		} else {
+			keys[keycode] = uint16(code)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func parseKeyCode(value string) (remotes.RemoteCode, error) {
+	if keycode, err := strconv.ParseUint(value, 0, 32); err == nil {
+		return remotes.RemoteCode(keycode), nil
+	}
+	value = strings.ToUpper(value)
+	for keycode := remotes.KEYCODE_NONE; keycode < remotes.KEYCODE_MAX; keycode++ {
+		if fmt.Sprint(keycode) == value {
+			return keycode, nil
+		}
+	}
+	return remotes.KEYCODE_NONE, fmt.Errorf("Invalid keycode: %v", value)
+}
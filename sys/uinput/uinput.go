@@ -0,0 +1,269 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package uinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	event "github.com/djthorpe/gopi/util/event"
+	remotes "github.com/djthorpe/remotes"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// UInput Configuration
+type UInput struct {
+	Mapper remotes.Mapper
+	Device string                        // Path to the uinput device, or empty for the default
+	Name   string                        // Name of the virtual keyboard, or empty for the default
+	Keys   map[remotes.RemoteCode]uint16 // Linux key codes which override DEFAULT_KEYS
+	Writer io.Writer                     // Write input events instead of creating a device, closed with the module
+}
+
+type uinput struct {
+	log     gopi.Logger
+	mapper  remotes.Mapper
+	name    string
+	keys    map[remotes.RemoteCode]uint16
+	dev     *os.File
+	w       io.Writer
+	pressed map[uint16]bool
+
+	event.Tasks
+}
+
+// input_event is the Linux input_event structure
+type input_event struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	DEVICE = "/dev/uinput"
+	NAME   = "remotes"
+)
+
+const (
+	EV_SYN     = 0x00
+	EV_KEY     = 0x01
+	SYN_REPORT = 0x00
+)
+
+const (
+	// Values for EV_KEY events
+	KEY_RELEASE = 0
+	KEY_PRESS   = 1
+	KEY_REPEAT  = 2
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+func (config UInput) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<remotes.UInput>Open{ device=\"%v\" name=\"%v\" }", config.Device, config.Name)
+
+	if config.Mapper == nil {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this := new(uinput)
+	this.log = log
+	this.mapper = config.Mapper
+	this.name = config.Name
+	this.pressed = make(map[uint16]bool)
+
+	// Set default name
+	if this.name == "" {
+		this.name = NAME
+	}
+
+	// Translate keycodes with the defaults, overridden by the configuration
+	this.keys = make(map[remotes.RemoteCode]uint16, len(DEFAULT_KEYS)+len(config.Keys))
+	for keycode, code := range DEFAULT_KEYS {
+		this.keys[keycode] = code
+	}
+	for keycode, code := range config.Keys {
+		this.keys[keycode] = code
+	}
+
+	// Write to the writer, or else create the virtual keyboard
+	if config.Writer != nil {
+		this.w = config.Writer
+	} else {
+		device := config.Device
+		if device == "" {
+			device = DEVICE
+		}
+		if dev, err := createDevice(device, this.name, this.codes()); err != nil {
+			return nil, err
+		} else {
+			this.dev = dev
+			this.w = dev
+		}
+	}
+
+	// Start background task which injects the keys
+	this.Tasks.Start(this.EventTask)
+
+	// Return success
+	return this, nil
+}
+
+func (this *uinput) Close() error {
+	this.log.Debug("<remotes.UInput>Close{ name=\"%v\" }", this.name)
+
+	// End tasks, which releases any keys which are pressed
+	if err := this.Tasks.Close(); err != nil {
+		return err
+	}
+
+	// Destroy the device or close the writer
+	if this.dev != nil {
+		if err := destroyDevice(this.dev); err != nil {
+			return err
+		}
+	} else if closer, ok := this.w.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+
+	// Release resources
+	this.dev = nil
+	this.w = nil
+	this.mapper = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *uinput) String() string {
+	return fmt.Sprintf("<remotes.UInput>{ name=\"%v\" keys=%v }", this.name, len(this.keys))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BACKGROUND TASK
+
+func (this *uinput) EventTask(start chan<- event.Signal, stop <-chan event.Signal) error {
+	start <- gopi.DONE
+	events := this.mapper.Subscribe()
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-events:
+			if keymap_evt, ok := evt.(remotes.KeyMapEvent); ok && keymap_evt != nil {
+				if err := this.receive(keymap_evt); err != nil {
+					this.log.Warn("UInput: %v", err)
+				}
+			}
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+
+	this.mapper.Unsubscribe(events)
+
+	// Release keys which are still pressed
+	for code := range this.pressed {
+		if err := this.inject(code, KEY_RELEASE); err != nil {
+			this.log.Warn("UInput: %v", err)
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *uinput) receive(evt remotes.KeyMapEvent) error {
+	this.log.Debug2("<remotes.UInput>Receive{ evt=%v }", evt)
+
+	code := this.code(remotes.RemoteCode(evt.KeyCode()))
+	if code == 0 {
+		return nil
+	}
+
+	switch evt.EventType() {
+	case gopi.INPUT_EVENT_KEYPRESS:
+		this.pressed[code] = true
+		return this.inject(code, KEY_PRESS)
+	case gopi.INPUT_EVENT_KEYREPEAT:
+		// Press the key when the key press has not been received
+		if this.pressed[code] == false {
+			this.pressed[code] = true
+			return this.inject(code, KEY_PRESS)
+		}
+		return this.inject(code, KEY_REPEAT)
+	case gopi.INPUT_EVENT_KEYRELEASE:
+		delete(this.pressed, code)
+		return this.inject(code, KEY_RELEASE)
+	default:
+		return nil
+	}
+}
+
+// code returns the Linux key code for a keycode, or zero if there is
+// no translation
+func (this *uinput) code(keycode remotes.RemoteCode) uint16 {
+	if code, exists := this.keys[keycode]; exists {
+		return code
+	} else if keycode > remotes.KEYCODE_NONE && keycode < remotes.RemoteCode(gopi.KEYCODE_MAX) {
+		return uint16(keycode)
+	} else {
+		return 0
+	}
+}
+
+// codes returns all the Linux key codes which can be injected
+func (this *uinput) codes() []uint16 {
+	codes := make([]uint16, 0, gopi.KEYCODE_MAX)
+	for code := uint16(1); code < uint16(gopi.KEYCODE_MAX); code++ {
+		codes = append(codes, code)
+	}
+	for _, code := range this.keys {
+		if code >= uint16(gopi.KEYCODE_MAX) {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// inject writes a key event followed by a SYN_REPORT event
+func (this *uinput) inject(code uint16, value int32) error {
+	this.log.Debug2("<remotes.UInput>Inject{ code=0x%04X value=%v }", code, value)
+	ts := syscall.NsecToTimeval(time.Now().UnixNano())
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.LittleEndian, &input_event{ts, EV_KEY, code, value}); err != nil {
+		return err
+	} else if err := binary.Write(buf, binary.LittleEndian, &input_event{ts, EV_SYN, SYN_REPORT, 0}); err != nil {
+		return err
+	} else if _, err := this.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package uinput
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// uinput_setup is the Linux uinput_setup structure
+type uinput_setup struct {
+	Bustype      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	Name         [UINPUT_MAX_NAME_SIZE]byte
+	FFEffectsMax uint32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	UINPUT_MAX_NAME_SIZE = 80
+	BUS_VIRTUAL          = 0x06
+)
+
+const (
+	// ioctl requests, from linux/uinput.h
+	UI_DEV_CREATE  = 0x5501     // _IO('U', 1)
+	UI_DEV_DESTROY = 0x5502     // _IO('U', 2)
+	UI_DEV_SETUP   = 0x405C5503 // _IOW('U', 3, struct uinput_setup)
+	UI_SET_EVBIT   = 0x40045564 // _IOW('U', 100, int)
+	UI_SET_KEYBIT  = 0x40045565 // _IOW('U', 101, int)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE
+
+// createDevice opens the uinput device and creates a virtual keyboard
+// which can inject the key codes
+func createDevice(path, name string, codes []uint16) (*os.File, error) {
+	dev, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enable key events and the key codes
+	if err := uinput_ioctl(dev.Fd(), UI_SET_EVBIT, EV_KEY); err != nil {
+		dev.Close()
+		return nil, err
+	}
+	for _, code := range codes {
+		if err := uinput_ioctl(dev.Fd(), UI_SET_KEYBIT, uintptr(code)); err != nil {
+			dev.Close()
+			return nil, err
+		}
+	}
+
+	// Set up and create the device
+	setup := uinput_setup{Bustype: BUS_VIRTUAL}
+	copy(setup.Name[:UINPUT_MAX_NAME_SIZE-1], name)
+	if err := uinput_ioctl(dev.Fd(), UI_DEV_SETUP, uintptr(unsafe.Pointer(&setup))); err != nil {
+		dev.Close()
+		return nil, err
+	} else if err := uinput_ioctl(dev.Fd(), UI_DEV_CREATE, 0); err != nil {
+		dev.Close()
+		return nil, err
+	}
+
+	// Success
+	return dev, nil
+}
+
+// destroyDevice destroys the virtual keyboard and closes the uinput device
+func destroyDevice(dev *os.File) error {
+	if err := uinput_ioctl(dev.Fd(), UI_DEV_DESTROY, 0); err != nil {
+		dev.Close()
+		return err
+	}
+	return dev.Close()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Call ioctl
+func uinput_ioctl(fd uintptr, name uintptr, data uintptr) error {
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, fd, name, data); err != 0 {
+		return os.NewSyscallError("ioctl", err)
+	} else {
+		return nil
+	}
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+// +build !linux
+
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package uinput
+
+import (
+	"os"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE
+
+// createDevice returns an error, since uinput is only available on Linux.
+// Input events can still be written with the Writer parameter
+func createDevice(path, name string, codes []uint16) (*os.File, error) {
+	return nil, gopi.ErrNotImplemented
+}
+
+func destroyDevice(dev *os.File) error {
+	return gopi.ErrNotImplemented
+}
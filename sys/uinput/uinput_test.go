@@ -0,0 +1,254 @@
+/*
+	Go Language Raspberry Pi Interface
+    (c) Copyright David Thorpe 2016-2019
+    All Rights Reserved
+
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package uinput_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/sys/logger"
+	"github.com/djthorpe/gopi/util/event"
+	"github.com/djthorpe/remotes"
+	"github.com/djthorpe/remotes/sys/uinput"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// mapper emits the key map events, and signals when the module subscribes
+type mapper struct {
+	event.Publisher
+	subscribed chan struct{}
+}
+
+// keymapevent is a key map event with a keycode, which is the only
+// information used by the module
+type keymapevent struct {
+	remotes.KeyMapEvent
+	keycode    remotes.RemoteCode
+	event_type gopi.InputEventType
+}
+
+// input_event is the Linux input_event structure
+type input_event struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestPressRepeatRelease(t *testing.T) {
+	mapper, buf := newMapper(), new(bytes.Buffer)
+	module := openModule(t, mapper, buf, nil)
+
+	// A key press, repeat and release are each followed by a SYN_REPORT
+	mapper.Emit(newEvent(remotes.KEYCODE_VOLUME_UP, gopi.INPUT_EVENT_KEYPRESS))
+	mapper.Emit(newEvent(remotes.KEYCODE_VOLUME_UP, gopi.INPUT_EVENT_KEYREPEAT))
+	mapper.Emit(newEvent(remotes.KEYCODE_VOLUME_UP, gopi.INPUT_EVENT_KEYRELEASE))
+	closeModule(t, module, mapper)
+
+	code := uint16(gopi.KEYCODE_VOLUMEUP)
+	checkKeys(t, readEvents(t, buf), []string{
+		keyEvent(code, uinput.KEY_PRESS),
+		keyEvent(code, uinput.KEY_REPEAT),
+		keyEvent(code, uinput.KEY_RELEASE),
+	})
+}
+
+func TestRepeatWithoutPress(t *testing.T) {
+	mapper, buf := newMapper(), new(bytes.Buffer)
+	module := openModule(t, mapper, buf, nil)
+
+	// A repeat without a key press presses the key, and unknown keys
+	// are ignored
+	mapper.Emit(newEvent(remotes.KEYCODE_NONE, gopi.INPUT_EVENT_KEYPRESS))
+	mapper.Emit(newEvent(remotes.KEYCODE_VOLUME_UP, gopi.INPUT_EVENT_KEYREPEAT))
+	mapper.Emit(newEvent(remotes.KEYCODE_VOLUME_UP, gopi.INPUT_EVENT_KEYREPEAT))
+	mapper.Emit(newEvent(remotes.KEYCODE_VOLUME_UP, gopi.INPUT_EVENT_KEYRELEASE))
+	closeModule(t, module, mapper)
+
+	code := uint16(gopi.KEYCODE_VOLUMEUP)
+	checkKeys(t, readEvents(t, buf), []string{
+		keyEvent(code, uinput.KEY_PRESS),
+		keyEvent(code, uinput.KEY_REPEAT),
+		keyEvent(code, uinput.KEY_RELEASE),
+	})
+}
+
+func TestReleaseOnClose(t *testing.T) {
+	mapper, buf := newMapper(), new(bytes.Buffer)
+	module := openModule(t, mapper, buf, nil)
+
+	// Keys which are still pressed are released when the module is closed
+	mapper.Emit(newEvent(remotes.KEYCODE_VOLUME_UP, gopi.INPUT_EVENT_KEYPRESS))
+	closeModule(t, module, mapper)
+
+	code := uint16(gopi.KEYCODE_VOLUMEUP)
+	checkKeys(t, readEvents(t, buf), []string{
+		keyEvent(code, uinput.KEY_PRESS),
+		keyEvent(code, uinput.KEY_RELEASE),
+	})
+}
+
+func TestKeys(t *testing.T) {
+	// Keycodes are translated with DEFAULT_KEYS, overridden by the keys
+	// read from the -uinput.keys file
+	keys, err := uinput.ReadKeys(strings.NewReader("# Overrides\n\nKEYCODE_MENU 0x1A0\n113 114\n"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != 2 || keys[remotes.KEYCODE_MENU] != 0x1A0 || keys[remotes.RemoteCode(113)] != 114 {
+		t.Errorf("Unexpected keys %v", keys)
+	}
+	for _, line := range []string{"KEYCODE_MENU", "KEYCODE_INVALID 1", "KEYCODE_MENU 0x10000"} {
+		if _, err := uinput.ReadKeys(strings.NewReader(line)); err == nil {
+			t.Errorf("%v: Expected an error", line)
+		}
+	}
+
+	mapper, buf := newMapper(), new(bytes.Buffer)
+	module := openModule(t, mapper, buf, keys)
+	mapper.Emit(newEvent(remotes.KEYCODE_EJECT, gopi.INPUT_EVENT_KEYPRESS))
+	mapper.Emit(newEvent(remotes.KEYCODE_MENU, gopi.INPUT_EVENT_KEYPRESS))
+	mapper.Emit(newEvent(remotes.RemoteCode(113), gopi.INPUT_EVENT_KEYPRESS))
+	mapper.Emit(newEvent(remotes.KEYCODE_EJECT, gopi.INPUT_EVENT_KEYRELEASE))
+	mapper.Emit(newEvent(remotes.KEYCODE_MENU, gopi.INPUT_EVENT_KEYRELEASE))
+	mapper.Emit(newEvent(remotes.RemoteCode(113), gopi.INPUT_EVENT_KEYRELEASE))
+	closeModule(t, module, mapper)
+
+	eject := uinput.DEFAULT_KEYS[remotes.KEYCODE_EJECT]
+	checkKeys(t, readEvents(t, buf), []string{
+		keyEvent(eject, uinput.KEY_PRESS),
+		keyEvent(0x1A0, uinput.KEY_PRESS),
+		keyEvent(114, uinput.KEY_PRESS),
+		keyEvent(eject, uinput.KEY_RELEASE),
+		keyEvent(0x1A0, uinput.KEY_RELEASE),
+		keyEvent(114, uinput.KEY_RELEASE),
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// MAPPER
+
+func newMapper() *mapper {
+	return &mapper{subscribed: make(chan struct{})}
+}
+
+func (this *mapper) Subscribe() <-chan gopi.Event {
+	defer close(this.subscribed)
+	return this.Publisher.Subscribe()
+}
+
+func (this *mapper) Add(publisher gopi.Publisher) error {
+	return gopi.ErrNotImplemented
+}
+
+func (this *mapper) Remove(publisher gopi.Publisher) error {
+	return gopi.ErrNotImplemented
+}
+
+func (this *mapper) Close() error {
+	this.Publisher.Close()
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EVENTS
+
+func newEvent(keycode remotes.RemoteCode, event_type gopi.InputEventType) remotes.KeyMapEvent {
+	return &keymapevent{keycode: keycode, event_type: event_type}
+}
+
+func (this *keymapevent) KeyCode() gopi.KeyCode {
+	return gopi.KeyCode(this.keycode)
+}
+
+func (this *keymapevent) EventType() gopi.InputEventType {
+	return this.event_type
+}
+
+func (this *keymapevent) String() string {
+	return fmt.Sprintf("<keymapevent>{ keycode=%v type=%v }", this.keycode, this.event_type)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// openModule opens the module, and waits until it receives events
+func openModule(t *testing.T, mapper *mapper, w io.Writer, keys map[remotes.RemoteCode]uint16) gopi.Driver {
+	t.Helper()
+	if log, err := gopi.Open(logger.Config{Level: logger.LOG_WARN}, nil); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(uinput.UInput{Mapper: mapper, Writer: w, Keys: keys}, log.(gopi.Logger)); err != nil {
+		t.Fatal(err)
+	} else {
+		<-mapper.subscribed
+		return driver
+	}
+	return nil
+}
+
+// closeModule closes the module, after which the events can be read
+func closeModule(t *testing.T, module gopi.Driver, mapper *mapper) {
+	t.Helper()
+	if err := module.Close(); err != nil {
+		t.Error(err)
+	}
+	mapper.Close()
+}
+
+// readEvents returns the input_event records written
+func readEvents(t *testing.T, r io.Reader) []input_event {
+	t.Helper()
+	evts := make([]input_event, 0)
+	for {
+		var evt input_event
+		if err := binary.Read(r, binary.LittleEndian, &evt); err == io.EOF {
+			return evts
+		} else if err != nil {
+			t.Fatal(err)
+		} else {
+			evts = append(evts, evt)
+		}
+	}
+}
+
+// checkKeys checks each key event is followed by a SYN_REPORT
+func checkKeys(t *testing.T, evts []input_event, expected []string) {
+	t.Helper()
+	if len(evts) != len(expected)*2 {
+		t.Errorf("Expected %v events, got %v", len(expected)*2, evts)
+		return
+	}
+	for i, evt := range evts {
+		if i%2 == 1 {
+			if evt.Type != uinput.EV_SYN || evt.Code != uinput.SYN_REPORT || evt.Value != 0 {
+				t.Errorf("Expected SYN_REPORT, got %v", evt)
+			} else if evt.Time != evts[i-1].Time {
+				t.Errorf("Expected the time of the key event, got %v", evt)
+			}
+		} else if key := keyEvent(evt.Code, evt.Value); evt.Type != uinput.EV_KEY || key != expected[i/2] {
+			t.Errorf("Expected %v, got type=%v %v", expected[i/2], evt.Type, key)
+		}
+	}
+}
+
+func keyEvent(code uint16, value int32) string {
+	return fmt.Sprintf("code=%v value=%v", code, value)
+}